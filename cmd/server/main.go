@@ -2,22 +2,41 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"flag"
 	"fmt"
 	"log/slog"
 	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/olesia8novoselova/Subscriptions/internal/auth"
 	"github.com/olesia8novoselova/Subscriptions/internal/config"
 	"github.com/olesia8novoselova/Subscriptions/internal/controller"
+	"github.com/olesia8novoselova/Subscriptions/internal/graphqlapi"
+	"github.com/olesia8novoselova/Subscriptions/internal/grpcapi"
+	"github.com/olesia8novoselova/Subscriptions/internal/health"
+	"github.com/olesia8novoselova/Subscriptions/internal/idempotency"
+	"github.com/olesia8novoselova/Subscriptions/internal/metrics"
+	"github.com/olesia8novoselova/Subscriptions/internal/ratelimit"
+	"github.com/olesia8novoselova/Subscriptions/internal/repository/cache"
+	"github.com/olesia8novoselova/Subscriptions/internal/repository/memory"
 	"github.com/olesia8novoselova/Subscriptions/internal/repository/postgres"
+	"github.com/olesia8novoselova/Subscriptions/internal/repository/sqlite"
 	"github.com/olesia8novoselova/Subscriptions/internal/service"
+	"github.com/olesia8novoselova/Subscriptions/internal/tenant"
+	"github.com/olesia8novoselova/Subscriptions/internal/tracing"
+	"github.com/olesia8novoselova/Subscriptions/migrations"
 	"github.com/olesia8novoselova/Subscriptions/pkg/logging"
+	"github.com/redis/go-redis/v9"
 	httpSwagger "github.com/swaggo/http-swagger"
+	"google.golang.org/grpc"
 
 	_ "github.com/olesia8novoselova/Subscriptions/internal/docs"
-	gormpg "gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
@@ -28,90 +47,485 @@ import (
 // @BasePath /
 // @schemes http
 // @host localhost:8080
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description JWT (HS256/RS256) или API-ключ: "Bearer <token>"
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
 func main() {
+	migrateAction := flag.String("migrate", "", "выполнить встроенные миграции и выйти: up, down (одна миграция) или status")
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "YAML-файл конфигурации; переменные окружения имеют приоритет")
+	flag.Parse()
+
 	_ = godotenv.Load()
 
-	// Логгер
-	logger := logging.New()
+	// Логгер; уровень меняется по SIGHUP
+	logLevel := new(slog.LevelVar)
+	logger := logging.New(logLevel)
 	slog.SetDefault(logger)
 
 	// Конфиг
-	cfg, err := config.LoadConfig()
+	cfg, err := config.Load(*configPath)
 	if err != nil {
 		logger.Error("failed to load config", "error", err)
 		return
 	}
+	logLevel.Set(cfg.Log.SlogLevel())
 
-	// БД (GORM)
-	db, err := initDB(cfg)
+	// Трассировка OpenTelemetry; оставшиеся спаны отправляются при остановке
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TracesExporter)
 	if err != nil {
-		logger.Error("database initialization failed", "error", err)
+		logger.Error("tracing initialization failed", "error", err)
 		return
 	}
-	sqlDB, _ := db.DB()
 	defer func() {
-		_ = sqlDB.Close()
-		logger.Info("database connection closed")
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Error("tracing shutdown failed", "error", err)
+		}
 	}()
 
-	repo := postgres.New(db, logger)
-	svc := service.NewSubscriptionService(repo, logger)
-	h := controller.NewSubscriptionHandler(svc, logger)
+	// Метрики Prometheus (/metrics)
+	mtr := metrics.New()
+
+	// Готовность (/readyz): проверки зависимостей, при остановке всегда 503
+	probe := health.New(2 * time.Second)
+
+	// Хранилище подписок. Бюджеты, пользователи, участники, запланированные изменения, API-ключи
+	// и ключи идемпотентности есть только в PostgreSQL: с memory и sqlite эти функции отключены.
+	var (
+		db   *gorm.DB
+		repo service.SubscriptionRepository
+	)
+	switch cfg.Storage {
+	case config.StorageMemory:
+		repo = memory.New()
+	case config.StorageSQLite:
+		sdb, err := sqlite.Open(cfg.SQLitePath)
+		if err != nil {
+			logger.Error("sqlite initialization failed", "error", err)
+			return
+		}
+		if err := sdb.Use(tracing.GORM("sqlite")); err != nil {
+			logger.Error("gorm tracing initialization failed", "error", err)
+			return
+		}
+		sqlDB, _ := sdb.DB()
+		defer func() { _ = sqlDB.Close() }()
+		mtr.RegisterDB(sqlDB)
+		probe.Add("database", pingCheck(sqlDB))
+		repo = sqlite.New(sdb, logger)
+	default:
+		// БД (GORM)
+		db, err = postgres.Open(cfg.DSN(), postgres.Pool{
+			MaxOpenConns:    cfg.DB.MaxOpenConns,
+			MaxIdleConns:    cfg.DB.MaxIdleConns,
+			ConnMaxLifetime: cfg.DB.ConnMaxLifetime,
+			ConnMaxIdleTime: cfg.DB.ConnMaxIdleTime,
+		})
+		if err != nil {
+			logger.Error("database initialization failed", "error", err)
+			return
+		}
+		if err := db.Use(tracing.GORM("postgresql")); err != nil {
+			logger.Error("gorm tracing initialization failed", "error", err)
+			return
+		}
+		sqlDB, _ := db.DB()
+		defer func() {
+			_ = sqlDB.Close()
+			logger.Info("database connection closed")
+		}()
+		mtr.RegisterDB(sqlDB)
+		probe.Add("database", pingCheck(sqlDB))
+		pgRepo := postgres.New(db)
+		mtr.RegisterActiveSubscriptions(pgRepo)
+		repo = pgRepo
+	}
+	if db == nil {
+		logger.Warn("subscriptions-only storage, features backed by postgres are disabled", "storage", cfg.Storage)
+	}
+
+	// Миграции: --migrate выполняет действие и завершает процесс, DB_AUTO_MIGRATE применяет их перед стартом
+	if *migrateAction != "" || (cfg.DB.AutoMigrate && db != nil) {
+		if db == nil {
+			logger.Error("--migrate requires STORAGE=postgres")
+			return
+		}
+		action := *migrateAction
+		if action == "" {
+			action = "up"
+		}
+		if err := migrate(context.Background(), db, logger, action); err != nil {
+			logger.Error("migration failed", "error", err)
+			return
+		}
+		if *migrateAction != "" {
+			return
+		}
+	}
+	if db != nil {
+		check, err := migrationsCheck(db, logger)
+		if err != nil {
+			logger.Error("failed to load migrations", "error", err)
+			return
+		}
+		probe.Add("migrations", check)
+	}
+
+	var (
+		svcOpts    = []service.Option{service.WithMetrics(mtr)}
+		budgetRepo *postgres.BudgetRepo
+		changeRepo *postgres.ScheduledChangeRepo
+		userRepo   service.UserRepository
+		memberRepo service.MemberRepository
+	)
+	if db != nil {
+		budgetRepo = postgres.NewBudgetRepo(db, logger)
+		changeRepo = postgres.NewScheduledChangeRepo(db, logger)
+		userRepo = postgres.NewUserRepo(db, logger)
+		memberRepo = postgres.NewMemberRepo(db, logger)
+	}
+
+	// Кеш чтения подписок по ID и выборок для /total; сбрасывается при изменениях
+	if cfg.Cache.Backend != "" {
+		var store cache.Store
+		if cfg.Cache.Backend == config.CacheRedis {
+			rdb := redis.NewClient(&redis.Options{Addr: cfg.Cache.RedisAddr, Password: cfg.Cache.RedisPassword, DB: cfg.Cache.RedisDB})
+			defer func() { _ = rdb.Close() }()
+			store = cache.NewRedis(rdb, cache.RedisPrefix)
+		} else {
+			store = cache.NewLRU(cfg.Cache.Size)
+		}
+		cached := cache.New(repo, store, cfg.Cache.TTL, logger)
+		mtr.RegisterCache(cached)
+		svcOpts = append(svcOpts, service.WithUncachedReads(repo))
+		repo = cached
+		if memberRepo != nil {
+			memberRepo = cached.Members(memberRepo)
+			userRepo = cached.Users(userRepo)
+		}
+		logger.Info("subscription cache enabled", "backend", cfg.Cache.Backend, "ttl", cfg.Cache.TTL)
+	}
+
+	if db != nil {
+		svcOpts = append(svcOpts,
+			service.WithBudgets(budgetRepo),
+			service.WithUsers(userRepo),
+			service.WithMembers(memberRepo),
+			service.WithScheduledChanges(changeRepo),
+		)
+	}
+	svcOpts = append(svcOpts, service.WithPagination(service.Pagination{
+		DefaultLimit: cfg.Pagination.DefaultLimit,
+		MaxLimit:     cfg.Pagination.MaxLimit,
+	}))
+	svc := service.NewSubscriptionService(repo, svcOpts...)
+	var handlerOpts []controller.HandlerOption
+	if cfg.Features.RequireIfMatch {
+		handlerOpts = append(handlerOpts, controller.WithRequireIfMatch())
+	}
+	h := controller.NewSubscriptionHandler(svc, logger, handlerOpts...)
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("ok"))
-	})
+	mux.HandleFunc("GET /healthz", probe.Live)
+	mux.HandleFunc("GET /readyz", probe.Ready)
 
 	mux.HandleFunc("POST /api/subscriptions", h.CreateSubscription)
 	mux.HandleFunc("GET /api/subscriptions/", h.GetSubscription)
 	mux.HandleFunc("GET /api/subscriptions", h.ListSubscriptions)
 	mux.HandleFunc("DELETE /api/subscriptions/", h.DeleteSubscription)
 	mux.HandleFunc("PATCH /api/subscriptions/", h.PatchSubscription)
+	mux.HandleFunc("PUT /api/subscriptions/", h.ReplaceSubscription)
 	mux.HandleFunc("GET /api/subscriptions/total", h.GetTotalCost)
+	mux.HandleFunc("GET /api/subscriptions/forecast", h.GetForecast)
+	mux.HandleFunc("GET /api/users/{user_id}/duplicates", h.GetDuplicates)
+
+	if db != nil {
+		bh := controller.NewBudgetHandler(service.NewBudgetService(budgetRepo, repo, memberRepo, logger), logger)
+		userSvc := service.NewUserService(userRepo, repo, memberRepo, mtr, svc, logger)
+		uh := controller.NewUserHandler(userSvc, logger)
+		ch := controller.NewScheduledChangeHandler(service.NewScheduledChangeService(changeRepo, repo, logger), logger)
+		mh := controller.NewMemberHandler(service.NewMemberService(memberRepo, repo, mtr, logger), logger)
+
+		mux.HandleFunc("POST /api/subscriptions/{id}/changes", ch.CreateScheduledChange)
+		mux.HandleFunc("GET /api/subscriptions/{id}/changes", ch.ListScheduledChanges)
+		mux.HandleFunc("DELETE /api/subscriptions/{id}/changes/{change_id}", ch.DeleteScheduledChange)
+
+		mux.HandleFunc("POST /api/subscriptions/{id}/members", mh.AddMember)
+		mux.HandleFunc("GET /api/subscriptions/{id}/members", mh.ListMembers)
+		mux.HandleFunc("DELETE /api/subscriptions/{id}/members/{member_id}", mh.RemoveMember)
+
+		mux.HandleFunc("POST /api/users", uh.CreateUser)
+		mux.HandleFunc("GET /api/users", uh.ListUsers)
+		mux.HandleFunc("GET /api/users/{id}", uh.GetUser)
+		mux.HandleFunc("PATCH /api/users/{id}", uh.PatchUser)
+		mux.HandleFunc("DELETE /api/users/{id}", uh.DeleteUser)
+		mux.HandleFunc("GET /api/users/{user_id}/subscriptions", uh.ListUserSubscriptions)
+		mux.HandleFunc("GET /api/users/{user_id}/summary", uh.GetUserSummary)
+
+		mux.HandleFunc("POST /api/users/{user_id}/budgets", bh.CreateBudget)
+		mux.HandleFunc("GET /api/users/{user_id}/budgets", bh.ListBudgets)
+		mux.HandleFunc("GET /api/users/{user_id}/budgets/alerts", bh.GetBudgetAlerts)
+		mux.HandleFunc("DELETE /api/users/{user_id}/budgets/{id}", bh.DeleteBudget)
+
+		if cfg.Features.GraphQL {
+			gh, err := graphqlapi.NewHandler(svc, userSvc, logger, cfg.GraphQLMaxComplexity)
+			if err != nil {
+				logger.Error("graphql initialization failed", "error", err)
+				return
+			}
+			mux.Handle("/graphql", gh)
+		}
+	}
+
+	if cfg.Features.Swagger {
+		mux.Handle("GET /swagger/", httpSwagger.WrapHandler)
+	}
+	mux.Handle("GET /metrics", mtr.Handler())
+
+	// Повторы POST с Idempotency-Key получают сохранённый ответ; ключи разделены по арендатору и principal
+	var handler http.Handler = mux
+	if db != nil {
+		handler = idempotency.Middleware(logger, postgres.NewIdempotencyRepo(db, logger), cfg.IdempotencyTTL, handler)
+	}
+
+	// Арендатор запроса определяется после аутентификации
+	handler = tenant.Middleware(logger, handler)
 
-	mux.Handle("GET /swagger/", httpSwagger.WrapHandler)
+	// Аутентификация для /api/* и gRPC
+	var authenticator *auth.Authenticator
+	if cfg.Auth.Disabled {
+		logger.Warn("authentication is disabled")
+	} else {
+		verifier, err := auth.NewJWTVerifier(auth.JWTConfig{
+			HS256Secret:      cfg.Auth.JWTSecret,
+			RSAPublicKeyFile: cfg.Auth.JWTPublicKeyFile,
+			JWKSFile:         cfg.Auth.JWTJWKSFile,
+			Issuer:           cfg.Auth.JWTIssuer,
+			Audience:         cfg.Auth.JWTAudience,
+		})
+		if err != nil {
+			logger.Error("failed to load jwt keys", "error", err)
+			return
+		}
+		// без PostgreSQL API-ключей нет, принимаются только JWT
+		var keys auth.APIKeyStore
+		if db != nil {
+			keys = postgres.NewAPIKeyRepo(db, logger)
+		}
+		authenticator = auth.NewAuthenticator(verifier, keys)
+	}
+
+	// Ограничение частоты по API-ключу, пользователю или IP; ключ клиента известен после аутентификации.
+	// Лимит по IP стоит перед аутентификацией и ограничивает подбор ключей.
+	// Middleware стоят всегда: лимиты можно включить по SIGHUP
+	var rlStore ratelimit.Store = ratelimit.NewMemory()
+	if cfg.RateLimit.Store == config.RateLimitPostgres {
+		rlStore = postgres.NewRateLimitRepo(db, logger)
+	}
+	limiter := ratelimit.New(logger, rlStore, rateLimitConfig(cfg))
+	handler = limiter.Middleware(handler)
+	if cfg.RateLimit.Read.Enabled() || cfg.RateLimit.Write.Enabled() || cfg.RateLimit.IP.Enabled() {
+		logger.Info("rate limiting enabled", "read", cfg.RateLimit.Read.String(), "write", cfg.RateLimit.Write.String(),
+			"ip", cfg.RateLimit.IP.String(), "store", cfg.RateLimit.Store)
+	}
+	if authenticator != nil {
+		handler = auth.Middleware(logger, authenticator, handler)
+	}
+	handler = limiter.IPMiddleware(handler)
 
 	// Оборачиваем middleware логирования
-	handler := logging.HTTPMiddleware(logger, mux)
+	handler = logging.HTTPMiddleware(logger, handler)
+	// Спан запроса снаружи логирования, чтобы trace_id попадал в запись о запросе
+	handler = tracing.Middleware(mux, handler)
+	handler = mtr.Middleware(mux, handler)
 
 	// HTTP Server с таймаутами
 	srv := &http.Server{
-		Addr:              ":" + cfg.ServerPort,
+		Addr:              ":" + cfg.Server.Port,
 		Handler:           handler,
-		ReadHeaderTimeout: 5 * time.Second,
-		ReadTimeout:       10 * time.Second,
-		WriteTimeout:      15 * time.Second,
-		IdleTimeout:       60 * time.Second,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
 		BaseContext: func(_ net.Listener) context.Context {
 			return context.Background()
 		},
 	}
 
-	logger.Info("starting server", "port", cfg.ServerPort)
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	// gRPC API на отдельном порту
+	var gs *grpc.Server
+	if cfg.Server.GRPCPort != "" {
+		lis, err := net.Listen("tcp", ":"+cfg.Server.GRPCPort)
+		if err != nil {
+			logger.Error("grpc listen failed", "error", err)
+			return
+		}
+		gs = grpcapi.New(svc, logger, authenticator)
+		go func() {
+			logger.Info("starting grpc server", "port", cfg.Server.GRPCPort)
+			if err := gs.Serve(lis); err != nil {
+				logger.Error("grpc server failed", "error", err)
+			}
+		}()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// SIGHUP перечитывает конфигурацию и применяет настройки, которые меняются без перезапуска
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go func() {
+		current := cfg
+		for range hup {
+			next, err := config.Load(*configPath)
+			if err != nil {
+				logger.Error("config reload failed, keeping current settings", "error", err)
+				continue
+			}
+			logLevel.Set(next.Log.SlogLevel())
+			svc.SetPagination(service.Pagination{DefaultLimit: next.Pagination.DefaultLimit, MaxLimit: next.Pagination.MaxLimit})
+			h.SetRequireIfMatch(next.Features.RequireIfMatch)
+			limiter.SetConfig(rateLimitConfig(next))
+			if changed := config.RestartRequired(current, next); len(changed) > 0 {
+				logger.Warn("config changes require restart to take effect", "sections", changed)
+			}
+			current = next
+			logger.Info("config reloaded", "log_level", next.Log.Level, "pagination", next.Pagination,
+				"require_if_match", next.Features.RequireIfMatch,
+				"rate_limit_read", next.RateLimit.Read.String(), "rate_limit_write", next.RateLimit.Write.String(),
+				"rate_limit_ip", next.RateLimit.IP.String())
+		}
+	}()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		logger.Info("starting server", "port", cfg.Server.Port)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		if gs != nil {
+			gs.Stop()
+		}
 		logger.Error("server failed", "error", err)
 		return
+	case <-ctx.Done():
+	}
+	// повторный сигнал завершает процесс сразу
+	stop()
+
+	// Сначала /readyz отвечает 503, затем новые соединения перестают приниматься,
+	// а текущие запросы дорабатывают не дольше SHUTDOWN_TIMEOUT
+	probe.Shutdown()
+	logger.Info("shutting down", "delay", cfg.Server.ShutdownDelay, "timeout", cfg.Server.ShutdownTimeout)
+	time.Sleep(cfg.Server.ShutdownDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("http server shutdown timed out, closing connections", "error", err)
+		_ = srv.Close()
 	}
+	if gs != nil {
+		stopGRPC(shutdownCtx, gs)
+	}
+	logger.Info("server stopped")
 }
 
-func initDB(cfg *config.Config) (*gorm.DB, error) {
-	dsn := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=UTC",
-		cfg.DBHost, cfg.DBUser, cfg.DBPassword, cfg.DBName, cfg.DBPort,
-	)
+// rateLimitConfig — лимиты ratelimit из конфигурации
+func rateLimitConfig(cfg *config.Config) ratelimit.Config {
+	return ratelimit.Config{
+		Read:           cfg.RateLimit.Read,
+		Write:          cfg.RateLimit.Write,
+		IP:             cfg.RateLimit.IP,
+		TrustForwarded: cfg.RateLimit.TrustForwarded,
+	}
+}
+
+// stopGRPC — дожидается завершения текущих вызовов gRPC, по истечении ctx обрывает их
+func stopGRPC(ctx context.Context, gs *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		gs.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		gs.Stop()
+		<-done
+	}
+}
+
+// pingCheck — проверка готовности: соединение с БД отвечает
+func pingCheck(db *sql.DB) health.Check {
+	return func(ctx context.Context) (map[string]any, error) {
+		return nil, db.PingContext(ctx)
+	}
+}
 
-	db, err := gorm.Open(gormpg.Open(dsn), &gorm.Config{})
+// migrationsCheck — проверка готовности: версия схемы и число неприменённых миграций.
+// Неприменённые миграции не делают экземпляр неготовым (их может применять отдельный шаг деплоя),
+// а схема в состоянии dirty — делает.
+func migrationsCheck(db *gorm.DB, log *slog.Logger) (health.Check, error) {
+	list, err := postgres.LoadMigrations(migrations.FS)
 	if err != nil {
-		return nil, fmt.Errorf("gorm open: %w", err)
+		return nil, err
 	}
-	sqlDB, err := db.DB()
+	m := postgres.NewMigrator(db, list, log)
+	return func(ctx context.Context) (map[string]any, error) {
+		version, dirty, err := m.Version(ctx)
+		if err != nil {
+			return nil, err
+		}
+		pending, err := m.Pending(ctx)
+		if err != nil {
+			return nil, err
+		}
+		details := map[string]any{"version": version, "dirty": dirty, "pending": len(pending)}
+		if dirty {
+			return details, postgres.ErrDirty
+		}
+		return details, nil
+	}, nil
+}
+
+// migrate — выполняет действие со встроенными миграциями
+func migrate(ctx context.Context, db *gorm.DB, log *slog.Logger, action string) error {
+	list, err := postgres.LoadMigrations(migrations.FS)
 	if err != nil {
-		return nil, fmt.Errorf("get sql db: %w", err)
+		return err
 	}
-	if err := sqlDB.Ping(); err != nil {
-		return nil, fmt.Errorf("ping db: %w", err)
+	m := postgres.NewMigrator(db, list, log)
+
+	switch action {
+	case "up":
+		n, err := m.Up(ctx)
+		log.Info("migrations applied", "count", n)
+		return err
+	case "down":
+		n, err := m.Down(ctx, 1)
+		log.Info("migrations reverted", "count", n)
+		return err
+	case "status":
+		version, dirty, err := m.Version(ctx)
+		if err != nil {
+			return err
+		}
+		pending, err := m.Pending(ctx)
+		if err != nil {
+			return err
+		}
+		log.Info("migration status", "version", version, "dirty", dirty, "pending", len(pending))
+		return nil
 	}
-	return db, nil
+	return fmt.Errorf("unknown migrate action %q, expected up, down or status", action)
 }
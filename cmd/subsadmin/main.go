@@ -0,0 +1,473 @@
+// subsadmin — обслуживание базы данных без HTTP-сервера: миграции, отчёт о пересечениях,
+// пересчёт кешированных агрегатов, массовое завершение подписок сервиса, очистка устаревших записей
+// и выпуск API-ключей. Подключение к БД и кешу настраивается так же, как у сервера.
+// Все команды, кроме migrate, работают с данными одной организации (-tenant).
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+	"github.com/olesia8novoselova/Subscriptions/internal/auth"
+	"github.com/olesia8novoselova/Subscriptions/internal/config"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/olesia8novoselova/Subscriptions/internal/repository/cache"
+	"github.com/olesia8novoselova/Subscriptions/internal/repository/postgres"
+	"github.com/olesia8novoselova/Subscriptions/internal/tenant"
+	"github.com/olesia8novoselova/Subscriptions/migrations"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// env — подключение к БД и кешу и потоки вывода команды
+type env struct {
+	db       *gorm.DB
+	cacheCfg config.CacheConfig
+	// cache — общий кеш серверов (Redis); nil, если кеш выключен или у каждого сервера свой
+	cache  cache.Store
+	log    *slog.Logger
+	stdout io.Writer
+	stderr io.Writer
+}
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, e *env, args []string) error
+	// global — команда не относится к организации и не требует -tenant
+	global bool
+}
+
+var commands = map[string]command{
+	"migrate":     {"migrate [-dir DIR] up | down [N] | status", runMigrate, true},
+	"overlaps":    {"overlaps [-o table|json]", runOverlaps, false},
+	"recompute":   {"recompute", runRecompute, false},
+	"end-service": {"end-service -service NAME -end MM-YYYY [-dry-run]", runEndService, false},
+	"purge":       {"purge [-ended-before MM-YYYY] [-revoked-before DURATION] [-dry-run]", runPurge, false},
+	"api-key":     {"api-key create -name NAME [-role user|admin] [-user UUID] | revoke ID", runAPIKey, false},
+}
+
+// errUsage — неверные аргументы; код выхода 2
+var errUsage = errors.New("usage error")
+
+func main() {
+	_ = godotenv.Load()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("subsadmin", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	tenantFlag := fs.String("tenant", "", "UUID организации, с данными которой работает команда (обязателен, кроме migrate)")
+	fs.Usage = func() { usage(stderr, fs) }
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "subsadmin: unknown command %q\n", fs.Arg(0))
+		fs.Usage()
+		return 2
+	}
+	if !cmd.global {
+		if *tenantFlag == "" {
+			fmt.Fprintf(stderr, "subsadmin: %s requires -tenant\n", fs.Arg(0))
+			return 2
+		}
+		tenantID, err := uuid.Parse(*tenantFlag)
+		if err != nil {
+			fmt.Fprintln(stderr, "subsadmin: -tenant must be UUID")
+			return 2
+		}
+		ctx = tenant.WithTenant(ctx, tenantID)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(stderr, "subsadmin: load config: %v\n", err)
+		return 1
+	}
+	db, err := postgres.Open(cfg.DSN(), postgres.Pool{
+		MaxOpenConns:    cfg.DB.MaxOpenConns,
+		MaxIdleConns:    cfg.DB.MaxIdleConns,
+		ConnMaxLifetime: cfg.DB.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.DB.ConnMaxIdleTime,
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "subsadmin: %v\n", err)
+		return 1
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	e := &env{
+		db:       db,
+		cacheCfg: cfg.Cache,
+		log:      slog.New(slog.NewTextHandler(stderr, nil)),
+		stdout:   stdout,
+		stderr:   stderr,
+	}
+	if cfg.Cache.Backend == config.CacheRedis {
+		rdb := redis.NewClient(&redis.Options{Addr: cfg.Cache.RedisAddr, Password: cfg.Cache.RedisPassword, DB: cfg.Cache.RedisDB})
+		defer func() { _ = rdb.Close() }()
+		e.cache = cache.NewRedis(rdb, cache.RedisPrefix)
+	}
+	if err := cmd.run(ctx, e, fs.Args()[1:]); err != nil {
+		switch {
+		case errors.Is(err, flag.ErrHelp):
+			return 0
+		case errors.Is(err, errUsage):
+			fmt.Fprintf(stderr, "subsadmin: %v\nusage: subsadmin %s\n", err, cmd.usage)
+			return 2
+		}
+		fmt.Fprintf(stderr, "subsadmin: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func usage(w io.Writer, fs *flag.FlagSet) {
+	fmt.Fprintln(w, "usage: subsadmin [flags] <command> [args]")
+	fmt.Fprintln(w, "\ncommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s\n", commands[name].usage)
+	}
+	fmt.Fprintln(w, "\nflags:")
+	fs.PrintDefaults()
+}
+
+func newFlagSet(e *env, name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	return fs
+}
+
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	return nil
+}
+
+func runMigrate(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet(e, "migrate")
+	dir := fs.String("dir", "", "каталог с файлами миграций; по умолчанию — встроенные")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	var files iofs.FS = migrations.FS
+	if *dir != "" {
+		files = os.DirFS(*dir)
+	}
+	list, err := postgres.LoadMigrations(files)
+	if err != nil {
+		return err
+	}
+	m := postgres.NewMigrator(e.db, list, e.log)
+
+	switch fs.Arg(0) {
+	case "up":
+		n, err := m.Up(ctx)
+		fmt.Fprintf(e.stdout, "applied %d migrations\n", n)
+		return err
+	case "down":
+		steps := 1
+		if fs.NArg() > 1 {
+			if steps, err = strconv.Atoi(fs.Arg(1)); err != nil || steps <= 0 {
+				return fmt.Errorf("%w: N must be a positive number", errUsage)
+			}
+		}
+		n, err := m.Down(ctx, steps)
+		fmt.Fprintf(e.stdout, "reverted %d migrations\n", n)
+		return err
+	case "status":
+		version, dirty, err := m.Version(ctx)
+		if err != nil {
+			return err
+		}
+		pending, err := m.Pending(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(e.stdout, "version: %d", version)
+		if dirty {
+			fmt.Fprint(e.stdout, " (dirty)")
+		}
+		fmt.Fprintf(e.stdout, "\npending: %d\n", len(pending))
+		for _, mg := range pending {
+			fmt.Fprintf(e.stdout, "  %s\n", mg)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: expected up, down or status", errUsage)
+}
+
+func runOverlaps(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet(e, "overlaps")
+	out := fs.String("o", "table", "формат вывода: table или json")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	conflicts, err := postgres.New(e.db).FindOverlaps(ctx)
+	if err != nil {
+		return fmt.Errorf("db error: %w", err)
+	}
+
+	switch *out {
+	case "json":
+		return writeJSON(e.stdout, conflicts)
+	case "table":
+		tw := tabwriter.NewWriter(e.stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "USER\tSERVICE\tFIRST\tPERIOD\tSECOND\tPERIOD")
+		for _, c := range conflicts {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", c.UserID, c.ServiceName,
+				c.FirstID, period(c.FirstStart, c.FirstEnd), c.SecondID, period(c.SecondStart, c.SecondEnd))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintf(e.stdout, "%d overlapping pairs\n", len(conflicts))
+		return nil
+	}
+	return fmt.Errorf("%w: unknown output format %q", errUsage, *out)
+}
+
+// runRecompute — агрегаты для /total (выборки подписок за период) хранятся только в кеше и считаются
+// из БД. Команда сбрасывает их вместе с подписками организации в общем кеше, так что после правок
+// в обход сервера они пересчитываются при следующем запросе
+func runRecompute(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet(e, "recompute")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	switch e.cacheCfg.Backend {
+	case "":
+		fmt.Fprintln(e.stdout, "cache is disabled: aggregates are computed on every request")
+		return nil
+	case config.CacheMemory:
+		fmt.Fprintf(e.stdout, "cache is local to each server: aggregates are recomputed within %s (cache ttl)\n", e.cacheCfg.TTL)
+		return nil
+	}
+
+	// Limit -1 — без ограничения
+	subs, err := postgres.New(e.db).List(ctx, models.ListFilters{Limit: -1})
+	if err != nil {
+		return fmt.Errorf("db error: %w", err)
+	}
+	ids := make([]uuid.UUID, len(subs))
+	for i, s := range subs {
+		ids[i] = s.ID
+	}
+	if err := e.invalidate(ctx, ids); err != nil {
+		return err
+	}
+	fmt.Fprintf(e.stdout, "cached aggregates reset: %d subscriptions will be recomputed on next read\n", len(ids))
+	return nil
+}
+
+func runEndService(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet(e, "end-service")
+	service := fs.String("service", "", "название сервиса (без учёта регистра)")
+	endStr := fs.String("end", "", "последний оплачиваемый месяц, MM-YYYY")
+	dryRun := fs.Bool("dry-run", false, "только посчитать подписки, не изменяя их")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *service == "" {
+		return fmt.Errorf("%w: -service is required", errUsage)
+	}
+	end, err := time.Parse("01-2006", *endStr)
+	if err != nil {
+		return fmt.Errorf("%w: -end must be MM-YYYY", errUsage)
+	}
+
+	ids, err := postgres.New(e.db).EndService(ctx, *service, end, *dryRun)
+	if err != nil {
+		return fmt.Errorf("db error: %w", err)
+	}
+	fmt.Fprintf(e.stdout, "%s: %d subscriptions ended at %s%s\n", *service, len(ids), *endStr, dryRunNote(*dryRun))
+	if *dryRun {
+		return nil
+	}
+	return e.invalidate(ctx, ids)
+}
+
+func runPurge(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet(e, "purge")
+	endedBefore := fs.String("ended-before", "", "удалить подписки, завершившиеся раньше месяца MM-YYYY")
+	revokedBefore := fs.Duration("revoked-before", 0, "удалить API-ключи, отозванные больше указанного времени назад (например, 720h)")
+	dryRun := fs.Bool("dry-run", false, "только посчитать записи, не удаляя их")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *revokedBefore < 0 {
+		return fmt.Errorf("%w: -revoked-before must be positive", errUsage)
+	}
+	var before time.Time
+	if *endedBefore != "" {
+		var err error
+		if before, err = time.Parse("01-2006", *endedBefore); err != nil {
+			return fmt.Errorf("%w: -ended-before must be MM-YYYY", errUsage)
+		}
+	}
+
+	now := time.Now()
+	n, err := postgres.NewIdempotencyRepo(e.db, e.log).PurgeExpired(ctx, now, *dryRun)
+	if err != nil {
+		return fmt.Errorf("db error: %w", err)
+	}
+	fmt.Fprintf(e.stdout, "idempotency keys: %d expired removed%s\n", n, dryRunNote(*dryRun))
+
+	if *endedBefore != "" {
+		ids, err := postgres.New(e.db).PurgeEnded(ctx, before, *dryRun)
+		if err != nil {
+			return fmt.Errorf("db error: %w", err)
+		}
+		fmt.Fprintf(e.stdout, "subscriptions: %d ended before %s removed%s\n", len(ids), *endedBefore, dryRunNote(*dryRun))
+		if !*dryRun {
+			if err := e.invalidate(ctx, ids); err != nil {
+				return err
+			}
+		}
+	}
+
+	if *revokedBefore > 0 {
+		n, err := postgres.NewAPIKeyRepo(e.db, e.log).PurgeRevoked(ctx, now.Add(-*revokedBefore), *dryRun)
+		if err != nil {
+			return fmt.Errorf("db error: %w", err)
+		}
+		fmt.Fprintf(e.stdout, "api keys: %d revoked removed%s\n", n, dryRunNote(*dryRun))
+	}
+	return nil
+}
+
+func runAPIKey(ctx context.Context, e *env, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: expected create or revoke", errUsage)
+	}
+	repo := postgres.NewAPIKeyRepo(e.db, e.log)
+
+	switch args[0] {
+	case "create":
+		fs := newFlagSet(e, "api-key create")
+		name := fs.String("name", "", "назначение ключа, например название интеграции")
+		role := fs.String("role", models.RoleUser, "роль: user или admin")
+		userFlag := fs.String("user", "", "UUID пользователя, от имени которого действует ключ")
+		if err := parse(fs, args[1:]); err != nil {
+			return err
+		}
+		if *name == "" {
+			return fmt.Errorf("%w: -name is required", errUsage)
+		}
+		if *role != models.RoleUser && *role != models.RoleAdmin {
+			return fmt.Errorf("%w: -role must be user or admin", errUsage)
+		}
+		tenantID := tenant.FromContext(ctx)
+		k := &models.APIKey{ID: uuid.New(), Name: *name, Role: *role, TenantID: &tenantID}
+		if *userFlag != "" {
+			userID, err := uuid.Parse(*userFlag)
+			if err != nil {
+				return fmt.Errorf("%w: -user must be UUID", errUsage)
+			}
+			k.UserID = &userID
+		}
+
+		key, hash, err := auth.GenerateAPIKey()
+		if err != nil {
+			return fmt.Errorf("generate api key: %w", err)
+		}
+		k.KeyHash = hash
+		if err := repo.Create(ctx, k); err != nil {
+			return fmt.Errorf("db error: %w", err)
+		}
+		// ключ хранится только в виде хеша, поэтому показывается один раз
+		fmt.Fprintf(e.stdout, "id:  %s\nkey: %s\n", k.ID, key)
+		return nil
+	case "revoke":
+		if len(args) != 2 {
+			return fmt.Errorf("%w: expected key ID", errUsage)
+		}
+		id, err := uuid.Parse(args[1])
+		if err != nil {
+			return fmt.Errorf("%w: ID must be UUID", errUsage)
+		}
+		if err := repo.Revoke(ctx, id, time.Now()); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("api key %s not found or already revoked", id)
+			}
+			return fmt.Errorf("db error: %w", err)
+		}
+		fmt.Fprintf(e.stdout, "api key %s revoked\n", id)
+		return nil
+	}
+	return fmt.Errorf("%w: expected create or revoke", errUsage)
+}
+
+func period(start time.Time, end *time.Time) string {
+	if end == nil {
+		return start.Format("01-2006") + " — …"
+	}
+	return start.Format("01-2006") + " — " + end.Format("01-2006")
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// invalidate — сбрасывает общий кеш серверов после изменения подписок ids в обход сервера.
+// Кеш в памяти процесса недоступен отсюда и устаревает в пределах cache ttl.
+func (e *env) invalidate(ctx context.Context, ids []uuid.UUID) error {
+	if e.cache == nil {
+		return nil
+	}
+	if err := cache.Invalidate(ctx, e.cache, ids...); err != nil {
+		return fmt.Errorf("cache error: %w", err)
+	}
+	return nil
+}
+
+func dryRunNote(dryRun bool) string {
+	if dryRun {
+		return " (dry run)"
+	}
+	return ""
+}
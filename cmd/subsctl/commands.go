@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/olesia8novoselova/Subscriptions/internal/apiclient"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+)
+
+// exportPageSize — запрашиваемый размер страницы при выгрузке всех подписок; сервер может
+// вернуть меньше (pagination.max_limit), ListAll это учитывает
+const exportPageSize = 100
+
+func newFlagSet(e *env, name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	return fs
+}
+
+// parse — разбирает флаги команды и возвращает позиционные аргументы, допуская их перед флагами
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var pos []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if err == flag.ErrHelp {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: %v", errUsage, err)
+		}
+		if fs.NArg() == 0 {
+			return pos, nil
+		}
+		pos = append(pos, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+func outputFlag(fs *flag.FlagSet) *string {
+	return fs.String("o", "table", "формат вывода: table или json")
+}
+
+func runCreate(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet(e, "create")
+	var req models.CreateSubscriptionRequest
+	var end string
+	fs.StringVar(&req.ServiceName, "service", "", "название сервиса")
+	fs.IntVar(&req.Price, "price", 0, "цена в рублях")
+	fs.StringVar(&req.UserID, "user", "", "UUID пользователя")
+	fs.StringVar(&req.StartDate, "start", "", "месяц начала, MM-YYYY")
+	fs.StringVar(&end, "end", "", "месяц окончания, MM-YYYY")
+	fs.StringVar(&req.Category, "category", "", "категория")
+	key := fs.String("idempotency-key", "", "Idempotency-Key для безопасного повтора")
+	out := outputFlag(fs)
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+	if end != "" {
+		req.EndDate = &end
+	}
+
+	sub, err := e.client.Create(ctx, req, *key)
+	if err != nil {
+		return err
+	}
+	return printSubscriptions(e.stdout, *out, []models.SubscriptionResponse{*sub}, false)
+}
+
+func runGet(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet(e, "get")
+	out := outputFlag(fs)
+	pos, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return fmt.Errorf("%w: subscription ID is required", errUsage)
+	}
+
+	sub, err := e.client.Get(ctx, pos[0])
+	if err != nil {
+		return err
+	}
+	return printSubscriptions(e.stdout, *out, []models.SubscriptionResponse{*sub}, false)
+}
+
+func runList(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet(e, "list")
+	var p apiclient.ListParams
+	fs.StringVar(&p.UserID, "user", "", "фильтр по UUID пользователя")
+	fs.StringVar(&p.ServiceName, "service", "", "фильтр по названию сервиса")
+	fs.IntVar(&p.Limit, "limit", 20, "размер страницы (до 100)")
+	fs.IntVar(&p.Offset, "offset", 0, "смещение")
+	all := fs.Bool("all", false, "все страницы")
+	out := outputFlag(fs)
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+
+	var (
+		list []models.SubscriptionResponse
+		err  error
+	)
+	if *all {
+		list, err = e.client.ListAll(ctx, p, exportPageSize)
+	} else {
+		list, err = e.client.List(ctx, p)
+	}
+	if err != nil {
+		return err
+	}
+	return printSubscriptions(e.stdout, *out, list, true)
+}
+
+func runPatch(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet(e, "patch")
+	fs.String("service", "", "название сервиса")
+	fs.Int("price", 0, "цена в рублях")
+	fs.String("start", "", "месяц начала, MM-YYYY")
+	fs.String("end", "", `месяц окончания, MM-YYYY; "" — очистить`)
+	fs.String("category", "", "категория")
+	ifMatch := fs.Int("if-match", 0, "ожидаемая версия подписки (ETag)")
+	out := outputFlag(fs)
+	pos, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return fmt.Errorf("%w: subscription ID is required", errUsage)
+	}
+
+	// передаются только явно заданные флаги
+	var req models.UpdateSubscriptionRequest
+	var perr error
+	fs.Visit(func(f *flag.Flag) {
+		v := f.Value.String()
+		switch f.Name {
+		case "service":
+			req.ServiceName = &v
+		case "price":
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				perr = err
+			}
+			req.Price = &n
+		case "start":
+			req.StartDate = &v
+		case "end":
+			req.EndDate = &v
+		case "category":
+			req.Category = &v
+		}
+	})
+	if perr != nil {
+		return fmt.Errorf("%w: %v", errUsage, perr)
+	}
+	if req == (models.UpdateSubscriptionRequest{}) {
+		return fmt.Errorf("%w: nothing to update", errUsage)
+	}
+
+	sub, err := e.client.Patch(ctx, pos[0], req, *ifMatch)
+	if err != nil {
+		return err
+	}
+	return printSubscriptions(e.stdout, *out, []models.SubscriptionResponse{*sub}, false)
+}
+
+func runDelete(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet(e, "delete")
+	ifMatch := fs.Int("if-match", 0, "ожидаемая версия подписки (ETag)")
+	pos, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return fmt.Errorf("%w: subscription ID is required", errUsage)
+	}
+
+	if err := e.client.Delete(ctx, pos[0], *ifMatch); err != nil {
+		return err
+	}
+	fmt.Fprintf(e.stdout, "deleted %s\n", pos[0])
+	return nil
+}
+
+func runTotal(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet(e, "total")
+	from := fs.String("from", "", "начало периода, MM-YYYY")
+	to := fs.String("to", "", "конец периода, MM-YYYY")
+	user := fs.String("user", "", "фильтр по UUID пользователя")
+	service := fs.String("service", "", "фильтр по названию сервиса")
+	out := outputFlag(fs)
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+	if *from == "" || *to == "" {
+		return fmt.Errorf("%w: -from and -to are required", errUsage)
+	}
+
+	total, err := e.client.TotalCost(ctx, *from, *to, *user, *service)
+	if err != nil {
+		return err
+	}
+	if *out == "json" {
+		return writeJSON(e.stdout, models.TotalCostResponse{Total: total})
+	}
+	fmt.Fprintln(e.stdout, total)
+	return nil
+}
+
+// runImport — создаёт подписки из JSON-массива (формат export). Каждой записи соответствует
+// Idempotency-Key из её содержимого, поэтому повторный импорт того же файла не создаёт дубликатов.
+func runImport(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet(e, "import")
+	file := fs.String("f", "-", `файл с JSON-массивом подписок; "-" — stdin`)
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+
+	var r io.Reader = e.stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	var items []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&items); err != nil {
+		return fmt.Errorf("read import file: %w", err)
+	}
+
+	failed := 0
+	for i, raw := range items {
+		var req models.CreateSubscriptionRequest
+		if err := json.Unmarshal(raw, &req); err != nil {
+			fmt.Fprintf(e.stderr, "#%d: %v\n", i+1, err)
+			failed++
+			continue
+		}
+		sum := sha256.Sum256(raw)
+		if _, err := e.client.Create(ctx, req, "subsctl-import-"+hex.EncodeToString(sum[:16])); err != nil {
+			fmt.Fprintf(e.stderr, "#%d (%s): %v\n", i+1, req.ServiceName, err)
+			failed++
+		}
+	}
+	fmt.Fprintf(e.stdout, "imported %d, failed %d\n", len(items)-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d subscriptions were not imported", failed, len(items))
+	}
+	return nil
+}
+
+func runExport(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet(e, "export")
+	var p apiclient.ListParams
+	fs.StringVar(&p.UserID, "user", "", "фильтр по UUID пользователя")
+	fs.StringVar(&p.ServiceName, "service", "", "фильтр по названию сервиса")
+	file := fs.String("f", "-", `файл для выгрузки; "-" — stdout`)
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+
+	list, err := e.client.ListAll(ctx, p, exportPageSize)
+	if err != nil {
+		return err
+	}
+	if list == nil {
+		list = []models.SubscriptionResponse{}
+	}
+	if *file == "-" {
+		return writeJSON(e.stdout, list)
+	}
+	f, err := os.Create(*file)
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(f, list); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// printSubscriptions — подписки таблицей или JSON; asList — JSON-массив даже для одной записи
+func printSubscriptions(w io.Writer, format string, list []models.SubscriptionResponse, asList bool) error {
+	switch format {
+	case "json":
+		if !asList && len(list) == 1 {
+			return writeJSON(w, list[0])
+		}
+		if list == nil {
+			list = []models.SubscriptionResponse{}
+		}
+		return writeJSON(w, list)
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tSERVICE\tPRICE\tUSER\tSTART\tEND\tCATEGORY")
+		for _, s := range list {
+			end := "-"
+			if s.EndDate != nil {
+				end = *s.EndDate
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%s\t%s\n", s.ID, s.ServiceName, s.Price, s.UserID, s.StartDate, end, s.Category)
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("%w: unknown output format %q", errUsage, format)
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,119 @@
+// subsctl — консольный клиент REST API подписок.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/olesia8novoselova/Subscriptions/internal/apiclient"
+)
+
+// env — клиент API и потоки ввода-вывода команды
+type env struct {
+	client *apiclient.Client
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, e *env, args []string) error
+}
+
+var commands = map[string]command{
+	"create": {"create -service NAME -price N -start MM-YYYY [-user UUID] [-end MM-YYYY] [-category C]", runCreate},
+	"get":    {"get ID", runGet},
+	"list":   {"list [-user UUID] [-service NAME] [-limit N] [-offset N] [-all]", runList},
+	"patch":  {"patch ID [-service NAME] [-price N] [-start MM-YYYY] [-end MM-YYYY|\"\"] [-category C] [-if-match VERSION]", runPatch},
+	"delete": {"delete ID [-if-match VERSION]", runDelete},
+	"total":  {"total -from MM-YYYY -to MM-YYYY [-user UUID] [-service NAME]", runTotal},
+	"import": {"import [-f FILE]  (JSON-массив подписок, по умолчанию stdin)", runImport},
+	"export": {"export [-user UUID] [-service NAME] [-f FILE]", runExport},
+}
+
+// errUsage — неверные аргументы; код выхода 2
+var errUsage = errors.New("usage error")
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("subsctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	baseURL := fs.String("url", envOr("SUBSCTL_URL", "http://localhost:8080"), "базовый URL API (SUBSCTL_URL)")
+	token := fs.String("token", os.Getenv("SUBSCTL_TOKEN"), "JWT или API-ключ (SUBSCTL_TOKEN)")
+	tenant := fs.String("tenant", os.Getenv("SUBSCTL_TENANT"), "организация, X-Tenant-ID (SUBSCTL_TENANT)")
+	timeout := fs.Duration("timeout", 30*time.Second, "таймаут HTTP-запроса")
+	fs.Usage = func() { usage(stderr, fs) }
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "subsctl: unknown command %q\n", fs.Arg(0))
+		fs.Usage()
+		return 2
+	}
+
+	e := &env{
+		client: apiclient.New(*baseURL,
+			apiclient.WithToken(*token),
+			apiclient.WithTenant(*tenant),
+			apiclient.WithHTTPClient(&http.Client{Timeout: *timeout}),
+		),
+		stdin:  stdin,
+		stdout: stdout,
+		stderr: stderr,
+	}
+	if err := cmd.run(ctx, e, fs.Args()[1:]); err != nil {
+		switch {
+		case errors.Is(err, flag.ErrHelp):
+			return 0
+		case errors.Is(err, errUsage):
+			fmt.Fprintf(stderr, "subsctl: %v\nusage: subsctl %s\n", err, cmd.usage)
+			return 2
+		}
+		fmt.Fprintf(stderr, "subsctl: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func usage(w io.Writer, fs *flag.FlagSet) {
+	fmt.Fprintln(w, "usage: subsctl [flags] <command> [args]")
+	fmt.Fprintln(w, "\ncommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s\n", commands[name].usage)
+	}
+	fmt.Fprintln(w, "\nflags:")
+	fs.PrintDefaults()
+}
+
+func envOr(key, def string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/controller"
+	"github.com/olesia8novoselova/Subscriptions/internal/idempotency"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/olesia8novoselova/Subscriptions/internal/repository/memory"
+	"github.com/olesia8novoselova/Subscriptions/internal/service"
+)
+
+// newServer — httptest-сервер с настоящими обработчиками подписок поверх хранилища в памяти
+func newServer(t *testing.T, opts ...service.Option) *httptest.Server {
+	t.Helper()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := controller.NewSubscriptionHandler(service.NewSubscriptionService(memory.New(), opts...), log)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/subscriptions", h.CreateSubscription)
+	mux.HandleFunc("GET /api/subscriptions/", h.GetSubscription)
+	mux.HandleFunc("GET /api/subscriptions", h.ListSubscriptions)
+	mux.HandleFunc("DELETE /api/subscriptions/", h.DeleteSubscription)
+	mux.HandleFunc("PATCH /api/subscriptions/", h.PatchSubscription)
+	mux.HandleFunc("GET /api/subscriptions/total", h.GetTotalCost)
+
+	srv := httptest.NewServer(idempotency.Middleware(log, &idempotencyStore{recs: map[string]models.IdempotencyKey{}}, time.Hour, mux))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// idempotencyStore — хранилище ключей идемпотентности в памяти, как у сервера с PostgreSQL
+type idempotencyStore struct {
+	mu   sync.Mutex
+	recs map[string]models.IdempotencyKey
+}
+
+func (s *idempotencyStore) Reserve(_ context.Context, rec *models.IdempotencyKey) (*models.IdempotencyKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.recs[rec.Scope+"/"+rec.Key]; ok {
+		return &e, nil
+	}
+	s.recs[rec.Scope+"/"+rec.Key] = *rec
+	return nil, nil
+}
+
+func (s *idempotencyStore) Complete(_ context.Context, rec *models.IdempotencyKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recs[rec.Scope+"/"+rec.Key] = *rec
+	return nil
+}
+
+func (s *idempotencyStore) Release(_ context.Context, rec *models.IdempotencyKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.recs, rec.Scope+"/"+rec.Key)
+	return nil
+}
+
+// subsctl — запускает команду против сервера и возвращает код выхода, stdout и stderr
+func subsctl(t *testing.T, srv *httptest.Server, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), append([]string{"-url", srv.URL}, args...), strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// TestCRUD - тестирует создание, получение, изменение и удаление подписки через CLI
+func TestCRUD(t *testing.T) {
+	srv := newServer(t)
+	userID := uuid.NewString()
+
+	code, out, errOut := subsctl(t, srv, "", "create", "-service", "Yandex Plus", "-price", "400",
+		"-user", userID, "-start", "07-2025", "-o", "json")
+	if code != 0 {
+		t.Fatalf("create: code %d, stderr %q", code, errOut)
+	}
+	var created models.SubscriptionResponse
+	if err := json.Unmarshal([]byte(out), &created); err != nil {
+		t.Fatalf("create output: %v", err)
+	}
+
+	code, out, _ = subsctl(t, srv, "", "get", created.ID.String())
+	if code != 0 || !strings.Contains(out, "Yandex Plus") || !strings.Contains(out, "07-2025") {
+		t.Fatalf("get: code %d, output %q", code, out)
+	}
+
+	code, out, errOut = subsctl(t, srv, "", "patch", created.ID.String(), "-price", "500", "-end", "12-2025", "-if-match", "1", "-o", "json")
+	if code != 0 {
+		t.Fatalf("patch: code %d, stderr %q", code, errOut)
+	}
+	var patched models.SubscriptionResponse
+	if err := json.Unmarshal([]byte(out), &patched); err != nil {
+		t.Fatalf("patch output: %v", err)
+	}
+	if patched.Price != 500 || patched.EndDate == nil || *patched.EndDate != "12-2025" {
+		t.Errorf("patched = %+v", patched)
+	}
+
+	// устаревшая версия
+	code, _, errOut = subsctl(t, srv, "", "delete", created.ID.String(), "-if-match", "1")
+	if code != 1 || !strings.Contains(errOut, "412") {
+		t.Errorf("delete stale: code %d, stderr %q", code, errOut)
+	}
+
+	code, out, _ = subsctl(t, srv, "", "total", "-from", "07-2025", "-to", "09-2025", "-user", userID)
+	if code != 0 || strings.TrimSpace(out) != "1500" {
+		t.Errorf("total: code %d, output %q", code, out)
+	}
+
+	if code, _, errOut = subsctl(t, srv, "", "delete", created.ID.String()); code != 0 {
+		t.Fatalf("delete: code %d, stderr %q", code, errOut)
+	}
+	code, _, errOut = subsctl(t, srv, "", "get", created.ID.String())
+	if code != 1 || !strings.Contains(errOut, "404") {
+		t.Errorf("get deleted: code %d, stderr %q", code, errOut)
+	}
+}
+
+// TestExportImport - тестирует выгрузку подписок и повторный идемпотентный импорт
+func TestExportImport(t *testing.T) {
+	// страница сервера меньше запрашиваемой: выгрузка всё равно читает все подписки
+	src := newServer(t, service.WithPagination(service.Pagination{DefaultLimit: 2, MaxLimit: 2}))
+	userID := uuid.NewString()
+	for _, name := range []string{"Netflix", "Spotify", "Okko"} {
+		if code, _, errOut := subsctl(t, src, "", "create", "-service", name, "-price", "300", "-user", userID, "-start", "01-2025"); code != 0 {
+			t.Fatalf("create %s: %s", name, errOut)
+		}
+	}
+
+	file := filepath.Join(t.TempDir(), "subs.json")
+	if code, _, errOut := subsctl(t, src, "", "export", "-f", file); code != 0 {
+		t.Fatalf("export: %s", errOut)
+	}
+
+	dst := newServer(t)
+	code, out, errOut := subsctl(t, dst, "", "import", "-f", file)
+	if code != 0 || !strings.Contains(out, "imported 3, failed 0") {
+		t.Fatalf("import: code %d, output %q, stderr %q", code, out, errOut)
+	}
+
+	// повторный импорт получает сохранённые ответы и ничего не создаёт
+	code, out, errOut = subsctl(t, dst, "", "import", "-f", file)
+	if code != 0 || !strings.Contains(out, "imported 3, failed 0") {
+		t.Fatalf("second import: code %d, output %q, stderr %q", code, out, errOut)
+	}
+	if list := listJSON(t, dst); len(list) != 3 {
+		t.Errorf("after second import: %d subscriptions, want 3", len(list))
+	}
+
+	if list := listJSON(t, dst, "-service", "o"); len(list) != 2 {
+		t.Errorf("filtered list = %d subscriptions, want 2", len(list))
+	}
+
+	// stdin и запись с ошибкой
+	data, _ := os.ReadFile(file)
+	bad := strings.Replace(string(data), "[", `[{"service_name":"","price":1},`, 1)
+	code, out, errOut = subsctl(t, newServer(t), bad, "import")
+	if code != 1 || !strings.Contains(out, "imported 3, failed 1") || !strings.Contains(errOut, "#1") {
+		t.Errorf("import with error: code %d, output %q, stderr %q", code, out, errOut)
+	}
+}
+
+// listJSON — подписки, которые возвращает subsctl list
+func listJSON(t *testing.T, srv *httptest.Server, args ...string) []models.SubscriptionResponse {
+	t.Helper()
+	code, out, errOut := subsctl(t, srv, "", append([]string{"list", "-o", "json"}, args...)...)
+	if code != 0 {
+		t.Fatalf("list: code %d, stderr %q", code, errOut)
+	}
+	var list []models.SubscriptionResponse
+	if err := json.Unmarshal([]byte(out), &list); err != nil {
+		t.Fatalf("list output: %v", err)
+	}
+	return list
+}
+
+// TestUsage - тестирует коды выхода при неверных аргументах
+func TestUsage(t *testing.T) {
+	srv := newServer(t)
+	for _, args := range [][]string{
+		{},
+		{"unknown"},
+		{"get"},
+		{"patch", uuid.NewString()},
+		{"total", "-from", "01-2025"},
+		{"list", "-o", "yaml"},
+	} {
+		if code, _, _ := subsctl(t, srv, "", args...); code != 2 {
+			t.Errorf("subsctl %v: code %d, want 2", args, code)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+// Package apiclient — HTTP-клиент REST API подписок.
+package apiclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+)
+
+// Error — ответ API с кодом ошибки
+type Error struct {
+	StatusCode int
+	Message    string
+	// RequestID — ID запроса на сервере (X-Request-ID), по нему запрос ищется в логах
+	RequestID string
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+	if e.RequestID != "" {
+		msg += " (request " + e.RequestID + ")"
+	}
+	return msg
+}
+
+type Client struct {
+	baseURL string
+	token   string
+	tenant  string
+	http    *http.Client
+}
+
+// Option — дополнительная настройка Client
+type Option func(*Client)
+
+// WithToken — JWT или API-ключ (sk_...), передаётся в заголовке Authorization
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithTenant — организация для заголовка X-Tenant-ID
+func WithTenant(tenant string) Option {
+	return func(c *Client) {
+		c.tenant = tenant
+	}
+}
+
+// WithHTTPClient — свой http.Client (таймауты, транспорт)
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.http = hc
+	}
+}
+
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{baseURL: strings.TrimRight(baseURL, "/"), http: http.DefaultClient}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// ListParams — фильтры и пагинация списка подписок
+type ListParams struct {
+	UserID      string
+	ServiceName string
+	Limit       int
+	Offset      int
+}
+
+// Create — POST /api/subscriptions; idempotencyKey (может быть пустым) передаётся в Idempotency-Key
+func (c *Client) Create(ctx context.Context, req models.CreateSubscriptionRequest, idempotencyKey string) (*models.SubscriptionResponse, error) {
+	var res models.SubscriptionResponse
+	h := http.Header{}
+	if idempotencyKey != "" {
+		h.Set("Idempotency-Key", idempotencyKey)
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/subscriptions", nil, h, req, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (c *Client) Get(ctx context.Context, id string) (*models.SubscriptionResponse, error) {
+	var res models.SubscriptionResponse
+	if err := c.do(ctx, http.MethodGet, "/api/subscriptions/"+url.PathEscape(id), nil, nil, nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (c *Client) List(ctx context.Context, p ListParams) ([]models.SubscriptionResponse, error) {
+	q := url.Values{}
+	if p.UserID != "" {
+		q.Set("user_id", p.UserID)
+	}
+	if p.ServiceName != "" {
+		q.Set("service_name", p.ServiceName)
+	}
+	if p.Limit > 0 {
+		q.Set("limit", strconv.Itoa(p.Limit))
+	}
+	if p.Offset > 0 {
+		q.Set("offset", strconv.Itoa(p.Offset))
+	}
+	var res []models.SubscriptionResponse
+	if err := c.do(ctx, http.MethodGet, "/api/subscriptions", q, nil, nil, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ListAll — все подписки, подходящие под фильтры, постранично по pageSize. Сервер может урезать
+// страницу до своего максимума, поэтому выборка заканчивается на пустой странице
+func (c *Client) ListAll(ctx context.Context, p ListParams, pageSize int) ([]models.SubscriptionResponse, error) {
+	var all []models.SubscriptionResponse
+	p.Limit = pageSize
+	for p.Offset = 0; ; p.Offset = len(all) {
+		page, err := c.List(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		if len(page) == 0 {
+			return all, nil
+		}
+		all = append(all, page...)
+	}
+}
+
+// Patch — PATCH /api/subscriptions/{id}; ifMatch > 0 передаётся в If-Match
+func (c *Client) Patch(ctx context.Context, id string, req models.UpdateSubscriptionRequest, ifMatch int) (*models.SubscriptionResponse, error) {
+	var res models.SubscriptionResponse
+	if err := c.do(ctx, http.MethodPatch, "/api/subscriptions/"+url.PathEscape(id), nil, ifMatchHeader(ifMatch), req, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Delete — DELETE /api/subscriptions/{id}; ifMatch > 0 передаётся в If-Match
+func (c *Client) Delete(ctx context.Context, id string, ifMatch int) error {
+	return c.do(ctx, http.MethodDelete, "/api/subscriptions/"+url.PathEscape(id), nil, ifMatchHeader(ifMatch), nil, nil)
+}
+
+// TotalCost — GET /api/subscriptions/total
+func (c *Client) TotalCost(ctx context.Context, from, to, userID, serviceName string) (int, error) {
+	q := url.Values{"from": {from}, "to": {to}}
+	if userID != "" {
+		q.Set("user_id", userID)
+	}
+	if serviceName != "" {
+		q.Set("service_name", serviceName)
+	}
+	var res models.TotalCostResponse
+	if err := c.do(ctx, http.MethodGet, "/api/subscriptions/total", q, nil, nil, &res); err != nil {
+		return 0, err
+	}
+	return res.Total, nil
+}
+
+func ifMatchHeader(version int) http.Header {
+	if version <= 0 {
+		return nil
+	}
+	return http.Header{"If-Match": {`"` + strconv.Itoa(version) + `"`}}
+}
+
+func (c *Client) do(ctx context.Context, method, path string, q url.Values, h http.Header, body, out any) error {
+	u := c.baseURL + path
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
+	if err != nil {
+		return err
+	}
+	for k, v := range h {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.tenant != "" {
+		req.Header.Set("X-Tenant-ID", c.tenant)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var e struct {
+			Error     string `json:"error"`
+			RequestID string `json:"request_id"`
+		}
+		raw, _ := io.ReadAll(resp.Body)
+		if json.Unmarshal(raw, &e) != nil || e.Error == "" {
+			e.Error = strings.TrimSpace(string(raw))
+		}
+		if e.RequestID == "" {
+			e.RequestID = resp.Header.Get("X-Request-ID")
+		}
+		return &Error{StatusCode: resp.StatusCode, Message: e.Error, RequestID: e.RequestID}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
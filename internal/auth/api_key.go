@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+)
+
+// APIKeyPrefix — префикс, по которому API-ключ отличается от JWT в заголовке Authorization
+const APIKeyPrefix = "sk_"
+
+type APIKeyStore interface {
+	FindByHash(ctx context.Context, hash string) (*models.APIKey, error)
+}
+
+// HashAPIKey — SHA-256 (hex) ключа, в таком виде ключ хранится в БД
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateAPIKey — новый случайный ключ и его хеш
+func GenerateAPIKey() (key, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	key = APIKeyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	return key, HashAPIKey(key), nil
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+)
+
+// JWTConfig — источники ключей и ожидаемые значения iss/aud.
+// Пустые поля не используются.
+type JWTConfig struct {
+	HS256Secret      string
+	RSAPublicKeyFile string // PEM с публичным ключом RS256
+	JWKSFile         string // локальный JWKS с ключами RS256
+	Issuer           string
+	Audience         string
+}
+
+type claims struct {
+	jwt.RegisteredClaims
+	Role     string `json:"role,omitempty"`
+	TenantID string `json:"tenant_id,omitempty"`
+}
+
+// JWTVerifier — проверка подписи и стандартных claims токенов
+type JWTVerifier struct {
+	hsKey    []byte
+	rsaKeys  map[string]*rsa.PublicKey // kid -> ключ; "" — ключ без kid
+	issuer   string
+	audience string
+}
+
+// NewJWTVerifier — загружает ключи. Возвращает nil, если ни один ключ не настроен.
+func NewJWTVerifier(cfg JWTConfig) (*JWTVerifier, error) {
+	v := &JWTVerifier{
+		rsaKeys:  make(map[string]*rsa.PublicKey),
+		issuer:   cfg.Issuer,
+		audience: cfg.Audience,
+	}
+	if cfg.HS256Secret != "" {
+		v.hsKey = []byte(cfg.HS256Secret)
+	}
+	if cfg.RSAPublicKeyFile != "" {
+		data, err := os.ReadFile(cfg.RSAPublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read rsa public key: %w", err)
+		}
+		key, err := jwt.ParseRSAPublicKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("parse rsa public key: %w", err)
+		}
+		v.rsaKeys[""] = key
+	}
+	if cfg.JWKSFile != "" {
+		keys, err := LoadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		for kid, key := range keys {
+			v.rsaKeys[kid] = key
+		}
+	}
+	if v.hsKey == nil && len(v.rsaKeys) == 0 {
+		return nil, nil
+	}
+	return v, nil
+}
+
+// Verify — проверяет токен и возвращает principal
+func (v *JWTVerifier) Verify(tokenStr string) (*Principal, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg()}),
+		jwt.WithExpirationRequired(),
+	}
+	if v.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(v.issuer))
+	}
+	if v.audience != "" {
+		opts = append(opts, jwt.WithAudience(v.audience))
+	}
+
+	var c claims
+	if _, err := jwt.ParseWithClaims(tokenStr, &c, v.keyFunc, opts...); err != nil {
+		return nil, err
+	}
+	if c.Subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+
+	p := &Principal{Subject: c.Subject, Role: c.Role, Method: MethodJWT}
+	if p.Role == "" {
+		p.Role = models.RoleUser
+	}
+	if uid, err := uuid.Parse(c.Subject); err == nil {
+		p.UserID = uid
+	}
+	if c.TenantID != "" {
+		tid, err := uuid.Parse(c.TenantID)
+		if err != nil {
+			return nil, errors.New("tenant_id claim must be UUID")
+		}
+		p.TenantID = tid
+	}
+	return p, nil
+}
+
+func (v *JWTVerifier) keyFunc(t *jwt.Token) (any, error) {
+	switch t.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		if v.hsKey == nil {
+			return nil, errors.New("HS256 tokens are not accepted")
+		}
+		return v.hsKey, nil
+	case jwt.SigningMethodRS256.Alg():
+		kid, _ := t.Header["kid"].(string)
+		if key, ok := v.rsaKeys[kid]; ok {
+			return key, nil
+		}
+		// токен без kid при единственном ключе
+		if kid == "" && len(v.rsaKeys) == 1 {
+			for _, key := range v.rsaKeys {
+				return key, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/olesia8novoselova/Subscriptions/pkg/logging"
+	"gorm.io/gorm"
+)
+
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// Authenticator — проверяет учётные данные запроса: Bearer JWT, Bearer sk_... или X-API-Key
+type Authenticator struct {
+	jwt  *JWTVerifier
+	keys APIKeyStore
+}
+
+// NewAuthenticator — jwt может быть nil, тогда принимаются только API-ключи
+func NewAuthenticator(jwt *JWTVerifier, keys APIKeyStore) *Authenticator {
+	return &Authenticator{jwt: jwt, keys: keys}
+}
+
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, error) {
+	return a.AuthenticateCredentials(r.Context(), r.Header.Get("X-API-Key"), r.Header.Get("Authorization"))
+}
+
+// AuthenticateCredentials — проверяет значения X-API-Key и Authorization, полученные не из HTTP (например, из метаданных gRPC).
+// Неверные учётные данные дают ErrUnauthenticated, прочие ошибки (недоступна БД) возвращаются как есть
+func (a *Authenticator) AuthenticateCredentials(ctx context.Context, apiKey, authorization string) (*Principal, error) {
+	if apiKey != "" {
+		return a.apiKey(ctx, apiKey)
+	}
+
+	scheme, token, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, ErrUnauthenticated
+	}
+	if strings.HasPrefix(token, APIKeyPrefix) {
+		return a.apiKey(ctx, token)
+	}
+	if a.jwt == nil {
+		return nil, ErrUnauthenticated
+	}
+	p, err := a.jwt.Verify(token)
+	if err != nil {
+		return nil, errors.Join(ErrUnauthenticated, err)
+	}
+	return p, nil
+}
+
+func (a *Authenticator) apiKey(ctx context.Context, key string) (*Principal, error) {
+	if a.keys == nil {
+		return nil, ErrUnauthenticated
+	}
+	k, err := a.keys.FindByHash(ctx, HashAPIKey(key))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// неизвестный или отозванный ключ
+		return nil, ErrUnauthenticated
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find api key: %w", err)
+	}
+	p := &Principal{Subject: "api_key:" + k.ID.String(), Role: k.Role, Method: MethodAPIKey}
+	if p.Role == "" {
+		p.Role = models.RoleUser
+	}
+	if k.UserID != nil {
+		p.UserID = *k.UserID
+	}
+	if k.TenantID != nil {
+		p.TenantID = *k.TenantID
+	}
+	return p, nil
+}
+
+// Middleware — требует аутентификацию для /api/* и /graphql; остальные пути (healthz, readyz, swagger, metrics) открыты
+func Middleware(log *slog.Logger, a *Authenticator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") && r.URL.Path != "/graphql" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		p, err := a.Authenticate(r)
+		if err != nil && !errors.Is(err, ErrUnauthenticated) {
+			log.ErrorContext(r.Context(), "authentication failed", "method", r.Method, "path", r.URL.Path, "error", err)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = json.NewEncoder(w).Encode(logging.ErrorBody(w, "internal error"))
+			return
+		}
+		if err != nil {
+			log.WarnContext(r.Context(), "unauthenticated request", "method", r.Method, "path", r.URL.Path, "error", err)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(logging.ErrorBody(w, "authentication required"))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+	})
+}
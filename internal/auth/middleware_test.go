@@ -0,0 +1,193 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/auth"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+const testSecret = "test-secret"
+
+type fakeKeyStore map[string]*models.APIKey
+
+func (f fakeKeyStore) FindByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	if k, ok := f[hash]; ok {
+		return k, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+type failingKeyStore struct{}
+
+func (failingKeyStore) FindByHash(context.Context, string) (*models.APIKey, error) {
+	return nil, errors.New("connection refused")
+}
+
+func newTestLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func hsToken(t *testing.T, sub, role string, exp time.Time) string {
+	t.Helper()
+	tok := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": sub, "role": role, "exp": exp.Unix()})
+	s, err := tok.SignedString([]byte(testSecret))
+	require.NoError(t, err)
+	return s
+}
+
+// echoPrincipal — обработчик, возвращающий principal из контекста
+func echoPrincipal() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, ok := auth.FromContext(r.Context())
+		if !ok {
+			w.WriteHeader(http.StatusTeapot)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(p)
+	})
+}
+
+func serve(h http.Handler, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+// TestMiddleware_HS256 - тестирует проверку HS256-токенов
+func TestMiddleware_HS256(t *testing.T) {
+	v, err := auth.NewJWTVerifier(auth.JWTConfig{HS256Secret: testSecret})
+	require.NoError(t, err)
+	h := auth.Middleware(newTestLogger(), auth.NewAuthenticator(v, nil), echoPrincipal())
+
+	userID := uuid.New()
+	w := serve(h, "/api/subscriptions", map[string]string{
+		"Authorization": "Bearer " + hsToken(t, userID.String(), models.RoleAdmin, time.Now().Add(time.Hour)),
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+	var p auth.Principal
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&p))
+	assert.Equal(t, userID, p.UserID)
+	assert.Equal(t, models.RoleAdmin, p.Role)
+	assert.Equal(t, auth.MethodJWT, p.Method)
+
+	tenantID := uuid.New()
+	withTenant := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": userID.String(), "tenant_id": tenantID.String(), "exp": time.Now().Add(time.Hour).Unix()})
+	ts, _ := withTenant.SignedString([]byte(testSecret))
+	w = serve(h, "/api/subscriptions", map[string]string{"Authorization": "Bearer " + ts})
+	require.Equal(t, http.StatusOK, w.Code)
+	p = auth.Principal{}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&p))
+	assert.Equal(t, tenantID, p.TenantID)
+
+	expired := serve(h, "/api/subscriptions", map[string]string{
+		"Authorization": "Bearer " + hsToken(t, userID.String(), "", time.Now().Add(-time.Minute)),
+	})
+	assert.Equal(t, http.StatusUnauthorized, expired.Code)
+
+	wrongKey := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": userID.String(), "exp": time.Now().Add(time.Hour).Unix()})
+	s, _ := wrongKey.SignedString([]byte("other"))
+	assert.Equal(t, http.StatusUnauthorized, serve(h, "/api/subscriptions", map[string]string{"Authorization": "Bearer " + s}).Code)
+}
+
+// TestMiddleware_RS256JWKS - тестирует проверку RS256-токенов по ключам из JWKS-файла
+func TestMiddleware_RS256JWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	set := map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": "k1",
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}}
+	data, _ := json.Marshal(set)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	v, err := auth.NewJWTVerifier(auth.JWTConfig{JWKSFile: path, Issuer: "issuer"})
+	require.NoError(t, err)
+	h := auth.Middleware(newTestLogger(), auth.NewAuthenticator(v, nil), echoPrincipal())
+
+	sign := func(kid, iss string) string {
+		tok := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"sub": "svc", "iss": iss, "exp": time.Now().Add(time.Hour).Unix()})
+		tok.Header["kid"] = kid
+		s, err := tok.SignedString(key)
+		require.NoError(t, err)
+		return s
+	}
+
+	assert.Equal(t, http.StatusOK, serve(h, "/api/x", map[string]string{"Authorization": "Bearer " + sign("k1", "issuer")}).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(h, "/api/x", map[string]string{"Authorization": "Bearer " + sign("k2", "issuer")}).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(h, "/api/x", map[string]string{"Authorization": "Bearer " + sign("k1", "other")}).Code)
+	// HS256 не настроен
+	assert.Equal(t, http.StatusUnauthorized, serve(h, "/api/x", map[string]string{
+		"Authorization": "Bearer " + hsToken(t, "svc", "", time.Now().Add(time.Hour)),
+	}).Code)
+}
+
+// TestMiddleware_APIKey - тестирует аутентификацию по API-ключу
+func TestMiddleware_APIKey(t *testing.T) {
+	key, hash, err := auth.GenerateAPIKey()
+	require.NoError(t, err)
+	userID := uuid.New()
+	store := fakeKeyStore{hash: {ID: uuid.New(), KeyHash: hash, UserID: &userID, Role: models.RoleUser}}
+	h := auth.Middleware(newTestLogger(), auth.NewAuthenticator(nil, store), echoPrincipal())
+
+	for _, headers := range []map[string]string{
+		{"X-API-Key": key},
+		{"Authorization": "Bearer " + key},
+	} {
+		w := serve(h, "/api/subscriptions", headers)
+		require.Equal(t, http.StatusOK, w.Code)
+		var p auth.Principal
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&p))
+		assert.Equal(t, userID, p.UserID)
+		assert.Equal(t, auth.MethodAPIKey, p.Method)
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, serve(h, "/api/subscriptions", map[string]string{"X-API-Key": "sk_unknown"}).Code)
+
+	// ошибка хранилища — не повод отвечать 401
+	h = auth.Middleware(newTestLogger(), auth.NewAuthenticator(nil, failingKeyStore{}), echoPrincipal())
+	w := serve(h, "/api/subscriptions", map[string]string{"X-API-Key": key})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Empty(t, w.Header().Get("WWW-Authenticate"))
+}
+
+// TestMiddleware_PublicPaths - /healthz и swagger доступны без аутентификации, /api/* — нет
+func TestMiddleware_PublicPaths(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	h := auth.Middleware(newTestLogger(), auth.NewAuthenticator(nil, fakeKeyStore{}), ok)
+
+	assert.Equal(t, http.StatusOK, serve(h, "/healthz", nil).Code)
+	assert.Equal(t, http.StatusOK, serve(h, "/swagger/index.html", nil).Code)
+
+	w := serve(h, "/api/subscriptions", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Bearer")
+}
@@ -0,0 +1,43 @@
+// Package auth — аутентификация запросов к API: JWT (HS256/RS256) и API-ключи.
+package auth
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+)
+
+// Способы аутентификации
+const (
+	MethodJWT    = "jwt"
+	MethodAPIKey = "api_key"
+)
+
+// Principal — аутентифицированная вызывающая сторона
+type Principal struct {
+	Subject string
+	// UserID — пользователь, от имени которого выполняется запрос; uuid.Nil, если subject не UUID
+	UserID uuid.UUID
+	// TenantID — организация, к которой привязан principal; uuid.Nil, если привязки нет
+	TenantID uuid.UUID
+	Role     string
+	Method   string
+}
+
+func (p *Principal) IsAdmin() bool {
+	return p.Role == models.RoleAdmin
+}
+
+type principalKey struct{}
+
+// WithPrincipal — кладёт principal в контекст запроса
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext — principal текущего запроса, если он аутентифицирован
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
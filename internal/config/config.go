@@ -1,41 +1,474 @@
+// Package config — конфигурация сервиса: YAML-файл, переменные окружения поверх него и значения по умолчанию.
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olesia8novoselova/Subscriptions/internal/ratelimit"
+	"gopkg.in/yaml.v3"
+)
+
+// Хранилища подписок (STORAGE)
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+	StorageSQLite   = "sqlite"
+)
+
+// Хранилища кеша подписок (CACHE_BACKEND); пусто — кеш отключён
+const (
+	CacheMemory = "memory"
+	CacheRedis  = "redis"
+)
+
+// Хранилища корзин ограничения частоты запросов (RATE_LIMIT_STORE)
+const (
+	RateLimitMemory   = "memory"
+	RateLimitPostgres = "postgres"
 )
 
+// sslModes — допустимые значения sslmode у libpq
+var sslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// Config — настройки сервиса. Раздел YAML-файла указан в теге, переменная окружения — в envVars.
+// Log.Level, Pagination, Features.RequireIfMatch и RateLimit (кроме Store) применяются по SIGHUP без перезапуска.
 type Config struct {
-	DBHost     string
-	DBPort     string
-	DBUser     string
-	DBPassword string
-	DBName     string
-	ServerPort string
+	// Storage — хранилище подписок: postgres, memory или sqlite
+	Storage string `yaml:"storage"`
+	// SQLitePath — файл базы SQLite при STORAGE=sqlite
+	SQLitePath string `yaml:"sqlite_path"`
+
+	Server     ServerConfig     `yaml:"server"`
+	Log        LogConfig        `yaml:"log"`
+	DB         DBConfig         `yaml:"database"`
+	Auth       AuthConfig       `yaml:"auth"`
+	Pagination PaginationConfig `yaml:"pagination"`
+	Features   FeaturesConfig   `yaml:"features"`
+	Cache      CacheConfig      `yaml:"cache"`
+	RateLimit  RateLimitConfig  `yaml:"rate_limit"`
+
+	// IdempotencyTTL — срок хранения ответов на POST-запросы с Idempotency-Key
+	IdempotencyTTL time.Duration `yaml:"idempotency_ttl"`
+
+	// GraphQLMaxComplexity — максимальная оценочная стоимость запроса к /graphql
+	GraphQLMaxComplexity int `yaml:"graphql_max_complexity"`
+
+	// TracesExporter — экспорт спанов OpenTelemetry: otlp или none (OTEL_TRACES_EXPORTER);
+	// адрес коллектора задаётся стандартными OTEL_EXPORTER_OTLP_*
+	TracesExporter string `yaml:"traces_exporter"`
+}
+
+type ServerConfig struct {
+	Port string `yaml:"port"`
+	// GRPCPort — порт gRPC-сервера; пусто — gRPC отключён
+	GRPCPort string `yaml:"grpc_port"`
+
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+
+	// ShutdownTimeout — сколько ждать завершения текущих запросов после SIGTERM/SIGINT
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// ShutdownDelay — пауза между переводом /readyz в 503 и остановкой приёма соединений,
+	// чтобы балансировщик успел исключить экземпляр
+	ShutdownDelay time.Duration `yaml:"shutdown_delay"`
+}
+
+type LogConfig struct {
+	// Level — debug, info, warn или error
+	Level string `yaml:"level"`
+}
+
+// SlogLevel — уровень для slog; значение проверено в Validate
+func (c LogConfig) SlogLevel() slog.Level {
+	var l slog.Level
+	_ = l.UnmarshalText([]byte(c.Level))
+	return l
+}
+
+type DBConfig struct {
+	// DSN — строка подключения целиком; если задана, Host, Port, User, Password, Name и SSLMode не используются
+	DSN      string `yaml:"dsn"`
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Name     string `yaml:"name"`
+	SSLMode  string `yaml:"sslmode"`
+
+	// AutoMigrate — применять встроенные миграции при старте сервера
+	AutoMigrate bool `yaml:"auto_migrate"`
+
+	// Пул соединений: 0 в MaxOpenConns — без ограничения
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+}
+
+type AuthConfig struct {
+	Disabled         bool   `yaml:"disabled"`
+	JWTSecret        string `yaml:"jwt_hs256_secret"`
+	JWTPublicKeyFile string `yaml:"jwt_rsa_public_key_file"`
+	JWTJWKSFile      string `yaml:"jwt_jwks_file"`
+	JWTIssuer        string `yaml:"jwt_issuer"`
+	JWTAudience      string `yaml:"jwt_audience"`
+}
+
+// PaginationConfig — размер страницы списков подписок и пользователей, если limit не задан, и его максимум
+type PaginationConfig struct {
+	DefaultLimit int `yaml:"default_limit"`
+	MaxLimit     int `yaml:"max_limit"`
+}
+
+type FeaturesConfig struct {
+	// RequireIfMatch — PATCH, PUT и DELETE подписок без заголовка If-Match отклоняются (428)
+	RequireIfMatch bool `yaml:"require_if_match"`
+	// GraphQL — эндпоинт /graphql (только с PostgreSQL)
+	GraphQL bool `yaml:"graphql"`
+	// Swagger — документация /swagger/
+	Swagger bool `yaml:"swagger"`
+}
+
+// CacheConfig — кеш чтения подписок: Backend — memory или redis, пусто — отключён
+type CacheConfig struct {
+	Backend string        `yaml:"backend"`
+	TTL     time.Duration `yaml:"ttl"`
+	// Size — максимальное число записей LRU в памяти процесса
+	Size          int    `yaml:"size"`
+	RedisAddr     string `yaml:"redis_addr"`
+	RedisPassword string `yaml:"redis_password"`
+	RedisDB       int    `yaml:"redis_db"`
 }
 
+// RateLimitConfig — лимиты вида "60/1m" для чтения и записи, пусто — без ограничения.
+// IP — лимит на IP клиента до аутентификации. Store — memory (своя корзина в каждой реплике) или postgres (общая)
+type RateLimitConfig struct {
+	Read           ratelimit.Limit `yaml:"read"`
+	Write          ratelimit.Limit `yaml:"write"`
+	IP             ratelimit.Limit `yaml:"ip"`
+	Store          string          `yaml:"store"`
+	TrustForwarded bool            `yaml:"trust_forwarded"`
+}
+
+// Default — значения по умолчанию
+func Default() *Config {
+	return &Config{
+		Storage:    StoragePostgres,
+		SQLitePath: "subscriptions.db",
+		Server: ServerConfig{
+			Port:              "8080",
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       10 * time.Second,
+			WriteTimeout:      15 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			ShutdownTimeout:   15 * time.Second,
+		},
+		Log: LogConfig{Level: "info"},
+		DB: DBConfig{
+			Host:            "postgres",
+			Port:            "5432",
+			User:            "postgres",
+			Password:        "postgres",
+			Name:            "subscriptions",
+			SSLMode:         "disable",
+			MaxOpenConns:    20,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+		},
+		Pagination: PaginationConfig{DefaultLimit: 20, MaxLimit: 100},
+		Features:   FeaturesConfig{GraphQL: true, Swagger: true},
+		Cache: CacheConfig{
+			TTL:       30 * time.Second,
+			Size:      10000,
+			RedisAddr: "localhost:6379",
+		},
+		RateLimit:            RateLimitConfig{Store: RateLimitMemory},
+		IdempotencyTTL:       24 * time.Hour,
+		GraphQLMaxComplexity: 1000,
+		TracesExporter:       "none",
+	}
+}
+
+// LoadConfig — конфигурация из файла CONFIG_FILE (если задан) и переменных окружения
 func LoadConfig() (*Config, error) {
-	cfg := &Config{
-		DBHost:     getEnv("DB_HOST", "postgres"),
-		DBPort:     getEnv("DB_PORT", "5432"),
-		DBUser:     getEnv("DB_USER", "postgres"),
-		DBPassword: getEnv("DB_PASSWORD", "postgres"),
-		DBName:     getEnv("DB_NAME", "subscriptions"),
-		ServerPort: getEnv("SERVER_PORT", "8080"),
+	return Load(os.Getenv("CONFIG_FILE"))
+}
+
+// Load — значения по умолчанию, поверх них YAML-файл path (пусто — без файла), поверх — переменные
+// окружения. Неизвестные ключи файла считаются ошибкой. Возвращает сразу все ошибки разбора и проверки.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read config file: %w", err)
+		}
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("parse config file %s: %w", path, err)
+		}
 	}
 
-	if cfg.DBHost == "" {
-		return nil, fmt.Errorf("DB_HOST must be set")
+	var errs []error
+	for _, v := range cfg.envVars() {
+		if s, ok := os.LookupEnv(v.name); ok {
+			if err := v.set(s); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", v.name, err))
+			}
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err)
 	}
-	if cfg.DBUser == "" {
-		return nil, fmt.Errorf("DB_USER must be set")
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
 	}
 	return cfg, nil
 }
 
-func getEnv(key, def string) string {
-	if v, ok := os.LookupEnv(key); ok {
-		return v
+// envVar — переменная окружения и поле конфигурации, которое она задаёт
+type envVar struct {
+	name string
+	set  func(string) error
+}
+
+func (c *Config) envVars() []envVar {
+	return []envVar{
+		{"STORAGE", setString(&c.Storage)},
+		{"SQLITE_PATH", setString(&c.SQLitePath)},
+
+		{"SERVER_PORT", setString(&c.Server.Port)},
+		{"GRPC_PORT", setString(&c.Server.GRPCPort)},
+		{"SERVER_READ_HEADER_TIMEOUT", setDuration(&c.Server.ReadHeaderTimeout)},
+		{"SERVER_READ_TIMEOUT", setDuration(&c.Server.ReadTimeout)},
+		{"SERVER_WRITE_TIMEOUT", setDuration(&c.Server.WriteTimeout)},
+		{"SERVER_IDLE_TIMEOUT", setDuration(&c.Server.IdleTimeout)},
+		{"SHUTDOWN_TIMEOUT", setDuration(&c.Server.ShutdownTimeout)},
+		{"SHUTDOWN_DELAY", setDuration(&c.Server.ShutdownDelay)},
+
+		{"LOG_LEVEL", setString(&c.Log.Level)},
+
+		{"DB_DSN", setString(&c.DB.DSN)},
+		{"DB_HOST", setString(&c.DB.Host)},
+		{"DB_PORT", setString(&c.DB.Port)},
+		{"DB_USER", setString(&c.DB.User)},
+		{"DB_PASSWORD", setString(&c.DB.Password)},
+		{"DB_NAME", setString(&c.DB.Name)},
+		{"DB_SSLMODE", setString(&c.DB.SSLMode)},
+		{"DB_AUTO_MIGRATE", setBool(&c.DB.AutoMigrate)},
+		{"DB_MAX_OPEN_CONNS", setInt(&c.DB.MaxOpenConns)},
+		{"DB_MAX_IDLE_CONNS", setInt(&c.DB.MaxIdleConns)},
+		{"DB_CONN_MAX_LIFETIME", setDuration(&c.DB.ConnMaxLifetime)},
+		{"DB_CONN_MAX_IDLE_TIME", setDuration(&c.DB.ConnMaxIdleTime)},
+
+		{"AUTH_DISABLED", setBool(&c.Auth.Disabled)},
+		{"JWT_HS256_SECRET", setString(&c.Auth.JWTSecret)},
+		{"JWT_RSA_PUBLIC_KEY_FILE", setString(&c.Auth.JWTPublicKeyFile)},
+		{"JWT_JWKS_FILE", setString(&c.Auth.JWTJWKSFile)},
+		{"JWT_ISSUER", setString(&c.Auth.JWTIssuer)},
+		{"JWT_AUDIENCE", setString(&c.Auth.JWTAudience)},
+
+		{"PAGINATION_DEFAULT_LIMIT", setInt(&c.Pagination.DefaultLimit)},
+		{"PAGINATION_MAX_LIMIT", setInt(&c.Pagination.MaxLimit)},
+
+		{"REQUIRE_IF_MATCH", setBool(&c.Features.RequireIfMatch)},
+		{"FEATURE_GRAPHQL", setBool(&c.Features.GraphQL)},
+		{"FEATURE_SWAGGER", setBool(&c.Features.Swagger)},
+
+		{"CACHE_BACKEND", setString(&c.Cache.Backend)},
+		{"CACHE_TTL", setDuration(&c.Cache.TTL)},
+		{"CACHE_SIZE", setInt(&c.Cache.Size)},
+		{"REDIS_ADDR", setString(&c.Cache.RedisAddr)},
+		{"REDIS_PASSWORD", setString(&c.Cache.RedisPassword)},
+		{"REDIS_DB", setInt(&c.Cache.RedisDB)},
+
+		{"RATE_LIMIT_READ", setLimit(&c.RateLimit.Read)},
+		{"RATE_LIMIT_WRITE", setLimit(&c.RateLimit.Write)},
+		{"RATE_LIMIT_IP", setLimit(&c.RateLimit.IP)},
+		{"RATE_LIMIT_STORE", setString(&c.RateLimit.Store)},
+		{"RATE_LIMIT_TRUST_FORWARDED", setBool(&c.RateLimit.TrustForwarded)},
+
+		{"IDEMPOTENCY_TTL", setDuration(&c.IdempotencyTTL)},
+		{"GRAPHQL_MAX_COMPLEXITY", setInt(&c.GraphQLMaxComplexity)},
+		{"OTEL_TRACES_EXPORTER", setString(&c.TracesExporter)},
+	}
+}
+
+func setString(p *string) func(string) error {
+	return func(s string) error {
+		*p = s
+		return nil
+	}
+}
+
+func setBool(p *bool) func(string) error {
+	return func(s string) error {
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", s)
+		}
+		*p = v
+		return nil
+	}
+}
+
+func setInt(p *int) func(string) error {
+	return func(s string) error {
+		v, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", s)
+		}
+		*p = v
+		return nil
+	}
+}
+
+func setDuration(p *time.Duration) func(string) error {
+	return func(s string) error {
+		v, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration", s)
+		}
+		*p = v
+		return nil
+	}
+}
+
+func setLimit(p *ratelimit.Limit) func(string) error {
+	return func(s string) error {
+		v, err := ratelimit.ParseLimit(s)
+		if err != nil {
+			return err
+		}
+		*p = v
+		return nil
+	}
+}
+
+// Validate — проверяет все настройки и возвращает все найденные ошибки
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
 	}
-	return def
+	positive := func(d time.Duration, name string) {
+		check(d > 0, "%s must be a positive duration", name)
+	}
+
+	switch c.Storage {
+	case StoragePostgres, StorageMemory:
+	case StorageSQLite:
+		check(c.SQLitePath != "", "sqlite_path (SQLITE_PATH) must be set")
+	default:
+		check(false, "storage (STORAGE) must be postgres, memory or sqlite")
+	}
+
+	check(c.Server.Port != "", "server.port (SERVER_PORT) must be set")
+	positive(c.Server.ReadHeaderTimeout, "server.read_header_timeout (SERVER_READ_HEADER_TIMEOUT)")
+	positive(c.Server.ReadTimeout, "server.read_timeout (SERVER_READ_TIMEOUT)")
+	positive(c.Server.WriteTimeout, "server.write_timeout (SERVER_WRITE_TIMEOUT)")
+	positive(c.Server.IdleTimeout, "server.idle_timeout (SERVER_IDLE_TIMEOUT)")
+	positive(c.Server.ShutdownTimeout, "server.shutdown_timeout (SHUTDOWN_TIMEOUT)")
+	check(c.Server.ShutdownDelay >= 0, "server.shutdown_delay (SHUTDOWN_DELAY) must be a non-negative duration")
+
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.Log.Level)) == nil, "log.level (LOG_LEVEL) must be debug, info, warn or error")
+
+	if c.DB.DSN == "" {
+		check(c.DB.Host != "", "database.host (DB_HOST) must be set")
+		check(c.DB.User != "", "database.user (DB_USER) must be set")
+		check(c.DB.Name != "", "database.name (DB_NAME) must be set")
+		check(slices.Contains(sslModes, c.DB.SSLMode), "database.sslmode (DB_SSLMODE) must be one of %s", strings.Join(sslModes, ", "))
+	}
+	check(c.DB.MaxOpenConns >= 0, "database.max_open_conns (DB_MAX_OPEN_CONNS) must be a non-negative integer")
+	check(c.DB.MaxIdleConns >= 0, "database.max_idle_conns (DB_MAX_IDLE_CONNS) must be a non-negative integer")
+	check(c.DB.MaxOpenConns == 0 || c.DB.MaxIdleConns <= c.DB.MaxOpenConns,
+		"database.max_idle_conns (DB_MAX_IDLE_CONNS) must not exceed database.max_open_conns")
+	check(c.DB.ConnMaxLifetime >= 0, "database.conn_max_lifetime (DB_CONN_MAX_LIFETIME) must be a non-negative duration")
+	check(c.DB.ConnMaxIdleTime >= 0, "database.conn_max_idle_time (DB_CONN_MAX_IDLE_TIME) must be a non-negative duration")
+
+	check(c.Pagination.DefaultLimit > 0, "pagination.default_limit (PAGINATION_DEFAULT_LIMIT) must be a positive integer")
+	check(c.Pagination.MaxLimit >= c.Pagination.DefaultLimit,
+		"pagination.max_limit (PAGINATION_MAX_LIMIT) must not be less than pagination.default_limit")
+
+	switch c.Cache.Backend {
+	case "", CacheMemory:
+	case CacheRedis:
+		check(c.Cache.RedisAddr != "", "cache.redis_addr (REDIS_ADDR) must be set")
+	default:
+		check(false, "cache.backend (CACHE_BACKEND) must be empty, memory or redis")
+	}
+	positive(c.Cache.TTL, "cache.ttl (CACHE_TTL)")
+	check(c.Cache.Size > 0, "cache.size (CACHE_SIZE) must be a positive integer")
+	check(c.Cache.RedisDB >= 0, "cache.redis_db (REDIS_DB) must be a non-negative integer")
+
+	switch c.RateLimit.Store {
+	case RateLimitMemory:
+	case RateLimitPostgres:
+		check(c.Storage == StoragePostgres, "rate_limit.store (RATE_LIMIT_STORE) postgres requires storage postgres")
+	default:
+		check(false, "rate_limit.store (RATE_LIMIT_STORE) must be memory or postgres")
+	}
+
+	positive(c.IdempotencyTTL, "idempotency_ttl (IDEMPOTENCY_TTL)")
+	check(c.GraphQLMaxComplexity > 0, "graphql_max_complexity (GRAPHQL_MAX_COMPLEXITY) must be a positive integer")
+	check(c.TracesExporter == "none" || c.TracesExporter == "otlp", "traces_exporter (OTEL_TRACES_EXPORTER) must be otlp or none")
+
+	return errors.Join(errs...)
+}
+
+// DSN — строка подключения к PostgreSQL
+func (c *Config) DSN() string {
+	if c.DB.DSN != "" {
+		return c.DB.DSN
+	}
+	return fmt.Sprintf(
+		"host=%s user=%s password=%s dbname=%s port=%s sslmode=%s TimeZone=UTC",
+		c.DB.Host, c.DB.User, c.DB.Password, c.DB.Name, c.DB.Port, c.DB.SSLMode,
+	)
+}
+
+// RestartRequired — разделы конфигурации (ключи верхнего уровня YAML), изменения в которых
+// не применяются по SIGHUP и вступят в силу только после перезапуска
+func RestartRequired(old, updated *Config) []string {
+	a, b := *old, *updated
+	a.clearReloadable()
+	b.clearReloadable()
+
+	var changed []string
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	for i := 0; i < va.NumField(); i++ {
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			name, _, _ := strings.Cut(va.Type().Field(i).Tag.Get("yaml"), ",")
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+// clearReloadable — обнуляет настройки, которые применяются без перезапуска
+func (c *Config) clearReloadable() {
+	c.Log.Level = ""
+	c.Pagination = PaginationConfig{}
+	c.Features.RequireIfMatch = false
+	c.RateLimit.Read = ratelimit.Limit{}
+	c.RateLimit.Write = ratelimit.Limit{}
+	c.RateLimit.IP = ratelimit.Limit{}
+	c.RateLimit.TrustForwarded = false
 }
@@ -0,0 +1,121 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/olesia8novoselova/Subscriptions/internal/config"
+	"github.com/olesia8novoselova/Subscriptions/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+// TestLoad_FileAndEnv - тестирует значения из файла и их переопределение переменными окружения
+func TestLoad_FileAndEnv(t *testing.T) {
+	path := writeFile(t, `
+server:
+  port: "9000"
+  write_timeout: 30s
+log:
+  level: debug
+database:
+  host: db.internal
+  sslmode: require
+  max_open_conns: 50
+pagination:
+  default_limit: 10
+  max_limit: 200
+features:
+  graphql: false
+rate_limit:
+  write: 60/1m
+  ip: 300/1m
+`)
+	t.Setenv("DB_SSLMODE", "verify-full")
+	t.Setenv("PAGINATION_MAX_LIMIT", "150")
+
+	cfg, err := config.Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, "9000", cfg.Server.Port)
+	assert.Equal(t, 30*time.Second, cfg.Server.WriteTimeout)
+	assert.Equal(t, 10*time.Second, cfg.Server.ReadTimeout, "default is kept")
+	assert.Equal(t, "debug", cfg.Log.Level)
+	assert.Equal(t, 50, cfg.DB.MaxOpenConns)
+	assert.Equal(t, 10, cfg.Pagination.DefaultLimit)
+	assert.Equal(t, 150, cfg.Pagination.MaxLimit)
+	assert.False(t, cfg.Features.GraphQL)
+	assert.True(t, cfg.Features.Swagger)
+	assert.Equal(t, ratelimit.Limit{Requests: 60, Period: time.Minute}, cfg.RateLimit.Write)
+	assert.Equal(t, ratelimit.Limit{Requests: 300, Period: time.Minute}, cfg.RateLimit.IP)
+	assert.Contains(t, cfg.DSN(), "host=db.internal")
+	assert.Contains(t, cfg.DSN(), "sslmode=verify-full")
+
+	t.Setenv("DB_DSN", "postgres://app@db/subs?sslmode=require")
+	cfg, err = config.Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, "postgres://app@db/subs?sslmode=require", cfg.DSN())
+}
+
+// TestLoad_Errors - тестирует, что все ошибки конфигурации возвращаются сразу
+func TestLoad_Errors(t *testing.T) {
+	path := writeFile(t, `
+server:
+  read_timeout: -1s
+log:
+  level: verbose
+database:
+  sslmode: sometimes
+pagination:
+  default_limit: 50
+  max_limit: 10
+`)
+	t.Setenv("CACHE_SIZE", "many")
+
+	_, err := config.Load(path)
+	require.Error(t, err)
+	for _, msg := range []string{
+		"CACHE_SIZE",
+		"server.read_timeout",
+		"log.level",
+		"database.sslmode",
+		"pagination.max_limit",
+	} {
+		assert.ErrorContains(t, err, msg)
+	}
+
+	_, err = config.Load(writeFile(t, "server:\n  prot: 8080\n"))
+	assert.ErrorContains(t, err, "prot", "unknown keys are rejected")
+}
+
+// TestRestartRequired - тестирует определение изменений, которые не применяются по SIGHUP
+func TestRestartRequired(t *testing.T) {
+	old := config.Default()
+
+	updated := config.Default()
+	updated.Log.Level = "debug"
+	updated.Pagination.MaxLimit = 500
+	updated.Features.RequireIfMatch = true
+	updated.RateLimit.Read = ratelimit.Limit{Requests: 10, Period: time.Second}
+	assert.Empty(t, config.RestartRequired(old, updated))
+
+	updated.Server.WriteTimeout = time.Minute
+	updated.DB.MaxOpenConns = 5
+	updated.Features.GraphQL = false
+	assert.Equal(t, []string{"server", "database", "features"}, config.RestartRequired(old, updated))
+}
+
+// TestLoad_Example - тестирует, что config.example.yaml разбирается и совпадает со значениями по умолчанию
+func TestLoad_Example(t *testing.T) {
+	cfg, err := config.Load("../../config.example.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, config.Default(), cfg)
+}
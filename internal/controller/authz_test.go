@@ -0,0 +1,235 @@
+package controller_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/auth"
+	"github.com/olesia8novoselova/Subscriptions/internal/controller"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/olesia8novoselova/Subscriptions/internal/service"
+	"gorm.io/gorm"
+)
+
+// memRepo — хранилище подписок в памяти для проверки авторизации через настоящий сервис
+type memRepo struct {
+	subs []models.Subscription
+}
+
+func (m *memRepo) Create(ctx context.Context, s *models.Subscription) error {
+	m.subs = append(m.subs, *s)
+	return nil
+}
+
+func (m *memRepo) FindByID(ctx context.Context, id uuid.UUID) (*models.Subscription, error) {
+	for i := range m.subs {
+		if m.subs[i].ID == id {
+			s := m.subs[i]
+			return &s, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memRepo) List(ctx context.Context, f models.ListFilters) ([]models.Subscription, error) {
+	var res []models.Subscription
+	for _, s := range m.subs {
+		if f.UserID == nil || s.UserID == *f.UserID {
+			res = append(res, s)
+		}
+	}
+	return res, nil
+}
+
+func (m *memRepo) Delete(ctx context.Context, id uuid.UUID, version int) error {
+	for i := range m.subs {
+		if m.subs[i].ID == id && (version == 0 || m.subs[i].Version == version) {
+			m.subs = append(m.subs[:i], m.subs[i+1:]...)
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (m *memRepo) Update(ctx context.Context, id uuid.UUID, version int, fields map[string]any) (*models.Subscription, error) {
+	for i := range m.subs {
+		if m.subs[i].ID == id && (version == 0 || m.subs[i].Version == version) {
+			if v, ok := fields["price"].(int); ok {
+				m.subs[i].Price = v
+			}
+			m.subs[i].Version++
+			s := m.subs[i]
+			return &s, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memRepo) FindActiveInPeriod(ctx context.Context, from, to time.Time, f models.ListFilters) ([]models.Subscription, error) {
+	return m.List(ctx, f)
+}
+
+func (m *memRepo) ExistsOverlap(ctx context.Context, userID uuid.UUID, serviceName string, start time.Time, end *time.Time, excludeID *uuid.UUID) (bool, error) {
+	return false, nil
+}
+
+var (
+	alice = mustUUID("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+	bob   = mustUUID("0a9b1c5e-6f0d-4b7e-8e2a-3c4d5e6f7a8b")
+)
+
+func newAuthzHandler() (*controller.SubscriptionHandler, *memRepo) {
+	start := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	repo := &memRepo{subs: []models.Subscription{
+		{ID: mustUUID("b548150d-6198-4cc1-a186-8c4a1e0ccdcf"), ServiceName: "Netflix", Price: 500, UserID: alice, StartDate: start},
+		{ID: mustUUID("c2a1e6f4-1b3d-4a7e-9f20-6d8c5b4a3e21"), ServiceName: "Spotify", Price: 300, UserID: bob, StartDate: start},
+	}}
+	svc := service.NewSubscriptionService(repo)
+	return controller.NewSubscriptionHandler(svc, newTestLogger()), repo
+}
+
+func asUser(r *http.Request, userID uuid.UUID) *http.Request {
+	p := &auth.Principal{Subject: userID.String(), UserID: userID, Role: models.RoleUser, Method: auth.MethodJWT}
+	return r.WithContext(auth.WithPrincipal(r.Context(), p))
+}
+
+func asAdmin(r *http.Request) *http.Request {
+	p := &auth.Principal{Subject: "admin", Role: models.RoleAdmin, Method: auth.MethodJWT}
+	return r.WithContext(auth.WithPrincipal(r.Context(), p))
+}
+
+// TestAuthz_GetSubscription - пользователь видит только свои подписки, администратор — любые
+func TestAuthz_GetSubscription(t *testing.T) {
+	h, _ := newAuthzHandler()
+	path := "/api/subscriptions/c2a1e6f4-1b3d-4a7e-9f20-6d8c5b4a3e21" // подписка bob
+
+	w := httptest.NewRecorder()
+	h.GetSubscription(w, asUser(httptest.NewRequest(http.MethodGet, path, nil), alice))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("foreign subscription: status = %d, want 404", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.GetSubscription(w, asUser(httptest.NewRequest(http.MethodGet, path, nil), bob))
+	if w.Code != http.StatusOK {
+		t.Fatalf("own subscription: status = %d, want 200", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.GetSubscription(w, asAdmin(httptest.NewRequest(http.MethodGet, path, nil)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("admin: status = %d, want 200", w.Code)
+	}
+}
+
+// TestAuthz_ListSubscriptions - для пользователя фильтр user_id неявный, чужой user_id запрещён
+func TestAuthz_ListSubscriptions(t *testing.T) {
+	h, _ := newAuthzHandler()
+
+	w := httptest.NewRecorder()
+	h.ListSubscriptions(w, asUser(httptest.NewRequest(http.MethodGet, "/api/subscriptions", nil), alice))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	var arr []models.SubscriptionResponse
+	_ = json.NewDecoder(w.Body).Decode(&arr)
+	if len(arr) != 1 || arr[0].UserID != alice {
+		t.Fatalf("implicit filter: unexpected body: %+v", arr)
+	}
+
+	w = httptest.NewRecorder()
+	h.ListSubscriptions(w, asUser(httptest.NewRequest(http.MethodGet, "/api/subscriptions?user_id="+bob.String(), nil), alice))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("foreign user_id: status = %d, want 403", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.ListSubscriptions(w, asAdmin(httptest.NewRequest(http.MethodGet, "/api/subscriptions", nil)))
+	arr = nil
+	_ = json.NewDecoder(w.Body).Decode(&arr)
+	if w.Code != http.StatusOK || len(arr) != 2 {
+		t.Fatalf("admin: status = %d, items = %d, want 200 and 2", w.Code, len(arr))
+	}
+}
+
+// TestAuthz_GetTotalCost - сумма пользователя считается только по его подпискам
+func TestAuthz_GetTotalCost(t *testing.T) {
+	h, _ := newAuthzHandler()
+
+	w := httptest.NewRecorder()
+	h.GetTotalCost(w, asUser(httptest.NewRequest(http.MethodGet, "/api/subscriptions/total?from=07-2025&to=07-2025", nil), bob))
+	var got models.TotalCostResponse
+	_ = json.NewDecoder(w.Body).Decode(&got)
+	if w.Code != http.StatusOK || got.Total != 300 {
+		t.Fatalf("status = %d, total = %d, want 200 and 300", w.Code, got.Total)
+	}
+
+	w = httptest.NewRecorder()
+	h.GetTotalCost(w, asUser(httptest.NewRequest(http.MethodGet, "/api/subscriptions/total?from=07-2025&to=07-2025&user_id="+alice.String(), nil), bob))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("foreign user_id: status = %d, want 403", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.GetTotalCost(w, asAdmin(httptest.NewRequest(http.MethodGet, "/api/subscriptions/total?from=07-2025&to=07-2025", nil)))
+	got = models.TotalCostResponse{}
+	_ = json.NewDecoder(w.Body).Decode(&got)
+	if w.Code != http.StatusOK || got.Total != 800 {
+		t.Fatalf("admin: status = %d, total = %d, want 200 and 800", w.Code, got.Total)
+	}
+}
+
+// TestAuthz_CreateSubscription - пользователь создаёт подписки только для себя
+func TestAuthz_CreateSubscription(t *testing.T) {
+	h, _ := newAuthzHandler()
+
+	body := `{"service_name":"Okko","price":400,"start_date":"07-2025"}`
+	w := httptest.NewRecorder()
+	h.CreateSubscription(w, asUser(httptest.NewRequest(http.MethodPost, "/api/subscriptions", bytes.NewBufferString(body)), alice))
+	var got models.SubscriptionResponse
+	_ = json.NewDecoder(w.Body).Decode(&got)
+	if w.Code != http.StatusCreated || got.UserID != alice {
+		t.Fatalf("implicit user_id: status = %d, user_id = %s", w.Code, got.UserID)
+	}
+
+	body = `{"service_name":"Okko","price":400,"start_date":"07-2025","user_id":"` + bob.String() + `"}`
+	w = httptest.NewRecorder()
+	h.CreateSubscription(w, asUser(httptest.NewRequest(http.MethodPost, "/api/subscriptions", bytes.NewBufferString(body)), alice))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("foreign user_id: status = %d, want 403", w.Code)
+	}
+}
+
+// TestAuthz_PatchAndDelete - чужие подписки нельзя изменить или удалить
+func TestAuthz_PatchAndDelete(t *testing.T) {
+	h, repo := newAuthzHandler()
+	path := "/api/subscriptions/b548150d-6198-4cc1-a186-8c4a1e0ccdcf" // подписка alice
+
+	w := httptest.NewRecorder()
+	h.PatchSubscription(w, asUser(httptest.NewRequest(http.MethodPatch, path, strings.NewReader(`{"price":1}`)), bob))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("patch foreign: status = %d, want 404", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.DeleteSubscription(w, asUser(httptest.NewRequest(http.MethodDelete, path, nil), bob))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("delete foreign: status = %d, want 404", w.Code)
+	}
+	if len(repo.subs) != 2 {
+		t.Fatalf("subscription was deleted by another user")
+	}
+
+	w = httptest.NewRecorder()
+	h.DeleteSubscription(w, asUser(httptest.NewRequest(http.MethodDelete, path, nil), alice))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("delete own: status = %d, want 204", w.Code)
+	}
+}
@@ -0,0 +1,177 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/olesia8novoselova/Subscriptions/internal/service"
+	"gorm.io/gorm"
+)
+
+type BudgetService interface {
+	Create(ctx context.Context, userID string, req models.CreateBudgetRequest) (*models.Budget, error)
+	List(ctx context.Context, userID string) ([]models.Budget, error)
+	Delete(ctx context.Context, userID, id string) error
+	Alerts(ctx context.Context, userID, from, to string) ([]models.BudgetAlert, error)
+}
+
+type BudgetHandler struct {
+	svc BudgetService
+	log *slog.Logger
+}
+
+func NewBudgetHandler(svc BudgetService, log *slog.Logger) *BudgetHandler {
+	return &BudgetHandler{svc: svc, log: log}
+}
+
+// CreateBudget
+// @Summary Create budget
+// @Description Создаёт месячный бюджет пользователя: общий, по сервису или по категории. mode=reject запрещает превышение, mode=warn только предупреждает.
+// @Tags budgets
+// @Accept json
+// @Produce json
+// @Param  user_id  path  string  true  "User ID (UUID)"  example("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+// @Param  request  body  models.CreateBudgetRequest  true  "Budget body"
+// @Success  201  {object}  models.BudgetResponse
+// @Failure  400  {object}  map[string]string
+// @Failure  403  {object}  map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router  /api/users/{user_id}/budgets  [post]
+func (h *BudgetHandler) CreateBudget(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateBudgetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	b, err := h.svc.Create(r.Context(), r.PathValue("user_id"), req)
+	if err != nil {
+		if errors.Is(err, service.ErrForbidden) {
+			writeError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		h.log.ErrorContext(r.Context(), "create budget failed", "error", err)
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(toBudgetResponse(b))
+}
+
+// ListBudgets
+// @Summary List budgets
+// @Description Список бюджетов пользователя
+// @Tags budgets
+// @Produce json
+// @Param  user_id  path  string  true  "User ID (UUID)"  example("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+// @Success  200  {array}  models.BudgetResponse
+// @Failure  400  {object}  map[string]string
+// @Failure  403  {object}  map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router  /api/users/{user_id}/budgets  [get]
+func (h *BudgetHandler) ListBudgets(w http.ResponseWriter, r *http.Request) {
+	list, err := h.svc.List(r.Context(), r.PathValue("user_id"))
+	if err != nil {
+		if errors.Is(err, service.ErrForbidden) {
+			writeError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		h.log.ErrorContext(r.Context(), "list budgets failed", "error", err)
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resp := make([]models.BudgetResponse, 0, len(list))
+	for i := range list {
+		resp = append(resp, toBudgetResponse(&list[i]))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// DeleteBudget
+// @Summary Delete budget
+// @Description Удаляет бюджет пользователя
+// @Tags budgets
+// @Param  user_id  path  string  true  "User ID (UUID)"  example("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+// @Param  id  path  string  true  "Budget ID (UUID)"  example("0b3c1a4e-8f0f-4f55-a8c9-7d5f0a4f2c11")
+// @Success  204  "No Content"
+// @Failure  400  {object}  map[string]string
+// @Failure  404  {object}  map[string]string
+// @Failure  403  {object}  map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router  /api/users/{user_id}/budgets/{id}  [delete]
+func (h *BudgetHandler) DeleteBudget(w http.ResponseWriter, r *http.Request) {
+	if err := h.svc.Delete(r.Context(), r.PathValue("user_id"), r.PathValue("id")); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeError(w, http.StatusNotFound, "budget not found")
+			return
+		}
+		if errors.Is(err, service.ErrForbidden) {
+			writeError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		h.log.ErrorContext(r.Context(), "delete budget failed", "error", err)
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetBudgetAlerts
+// @Summary Budget overspend alerts
+// @Description Месяцы периода [from; to], в которых расходы пользователя превышают его бюджеты. Формат дат: MM-YYYY.
+// @Tags budgets
+// @Produce json
+// @Param  user_id  path  string  true  "User ID (UUID)"  example("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+// @Param  from  query  string  true  "From month (MM-YYYY)"  example("07-2025")
+// @Param  to  query  string  true  "To month (MM-YYYY)"  example("12-2025")
+// @Success  200  {array}  models.BudgetAlert
+// @Failure  400  {object}  map[string]string
+// @Failure  403  {object}  map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router  /api/users/{user_id}/budgets/alerts  [get]
+func (h *BudgetHandler) GetBudgetAlerts(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	from := q.Get("from")
+	to := q.Get("to")
+	if from == "" || to == "" {
+		writeError(w, http.StatusBadRequest, "from and to are required (MM-YYYY)")
+		return
+	}
+
+	alerts, err := h.svc.Alerts(r.Context(), r.PathValue("user_id"), from, to)
+	if err != nil {
+		if errors.Is(err, service.ErrForbidden) {
+			writeError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		h.log.ErrorContext(r.Context(), "budget alerts failed", "error", err)
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(alerts)
+}
+
+func toBudgetResponse(b *models.Budget) models.BudgetResponse {
+	return models.BudgetResponse{
+		ID:           b.ID,
+		UserID:       b.UserID,
+		MonthlyLimit: b.MonthlyLimit,
+		ServiceName:  b.ServiceName,
+		Category:     b.Category,
+		Mode:         b.Mode,
+	}
+}
@@ -0,0 +1,123 @@
+package controller_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/controller"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/olesia8novoselova/Subscriptions/internal/service"
+	"gorm.io/gorm"
+)
+
+type fakeBudgetService struct {
+	CreateFn func(ctx context.Context, userID string, req models.CreateBudgetRequest) (*models.Budget, error)
+	ListFn   func(ctx context.Context, userID string) ([]models.Budget, error)
+	DeleteFn func(ctx context.Context, userID, id string) error
+	AlertsFn func(ctx context.Context, userID, from, to string) ([]models.BudgetAlert, error)
+}
+
+func (f *fakeBudgetService) Create(ctx context.Context, userID string, req models.CreateBudgetRequest) (*models.Budget, error) {
+	return f.CreateFn(ctx, userID, req)
+}
+func (f *fakeBudgetService) List(ctx context.Context, userID string) ([]models.Budget, error) {
+	return f.ListFn(ctx, userID)
+}
+func (f *fakeBudgetService) Delete(ctx context.Context, userID, id string) error {
+	return f.DeleteFn(ctx, userID, id)
+}
+func (f *fakeBudgetService) Alerts(ctx context.Context, userID, from, to string) ([]models.BudgetAlert, error) {
+	return f.AlertsFn(ctx, userID, from, to)
+}
+
+func budgetMux(h *controller.BudgetHandler) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/users/{user_id}/budgets", h.CreateBudget)
+	mux.HandleFunc("GET /api/users/{user_id}/budgets", h.ListBudgets)
+	mux.HandleFunc("GET /api/users/{user_id}/budgets/alerts", h.GetBudgetAlerts)
+	mux.HandleFunc("DELETE /api/users/{user_id}/budgets/{id}", h.DeleteBudget)
+	return mux
+}
+
+// TestCreateBudget_Success - тестирует создание бюджета пользователя
+func TestCreateBudget_Success(t *testing.T) {
+	var gotUser string
+	fs := &fakeBudgetService{
+		CreateFn: func(ctx context.Context, userID string, req models.CreateBudgetRequest) (*models.Budget, error) {
+			gotUser = userID
+			return &models.Budget{ID: uuid.New(), UserID: mustUUID(userID), MonthlyLimit: req.MonthlyLimit, Mode: models.BudgetModeWarn}, nil
+		},
+	}
+	mux := budgetMux(controller.NewBudgetHandler(fs, newTestLogger()))
+
+	body := `{"monthly_limit":1500}`
+	req := httptest.NewRequest(http.MethodPost, "/api/users/60601fee-2bf1-4721-ae6f-7636e79a0cba/budgets", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201", w.Code)
+	}
+	if gotUser != "60601fee-2bf1-4721-ae6f-7636e79a0cba" {
+		t.Fatalf("user_id = %q", gotUser)
+	}
+	var got models.BudgetResponse
+	_ = json.NewDecoder(w.Body).Decode(&got)
+	if got.MonthlyLimit != 1500 {
+		t.Fatalf("unexpected body: %+v", got)
+	}
+}
+
+// TestDeleteBudget_NotFound - тестирует удаление несуществующего бюджета
+func TestDeleteBudget_NotFound(t *testing.T) {
+	fs := &fakeBudgetService{
+		DeleteFn: func(ctx context.Context, userID, id string) error { return gorm.ErrRecordNotFound },
+	}
+	mux := budgetMux(controller.NewBudgetHandler(fs, newTestLogger()))
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/users/60601fee-2bf1-4721-ae6f-7636e79a0cba/budgets/0b3c1a4e-8f0f-4f55-a8c9-7d5f0a4f2c11", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", w.Code)
+	}
+}
+
+// TestGetBudgetAlerts_MissingPeriod - тестирует обязательность from и to
+func TestGetBudgetAlerts_MissingPeriod(t *testing.T) {
+	mux := budgetMux(controller.NewBudgetHandler(&fakeBudgetService{}, newTestLogger()))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users/60601fee-2bf1-4721-ae6f-7636e79a0cba/budgets/alerts?from=07-2025", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", w.Code)
+	}
+}
+
+// TestCreateSubscription_BudgetExceeded - тестирует отказ при превышении бюджета
+func TestCreateSubscription_BudgetExceeded(t *testing.T) {
+	fs := &fakeService{
+		CreateFn: func(ctx context.Context, req models.CreateSubscriptionRequest) (*models.Subscription, error) {
+			return nil, service.ErrBudgetExceeded
+		},
+	}
+	h := controller.NewSubscriptionHandler(fs, newTestLogger())
+
+	body := `{"service_name":"Test Service","price":500,"user_id":"60601fee-2bf1-4721-ae6f-7636e79a0cba","start_date":"07-2025"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/subscriptions", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+
+	h.CreateSubscription(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422", w.Code)
+	}
+}
@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/olesia8novoselova/Subscriptions/internal/service"
+	"gorm.io/gorm"
+)
+
+var errBadETag = errors.New("If-Match: * must not be combined with other ETags")
+
+// etag — ETag подписки по номеру её версии
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ifMatch — версии из заголовка If-Match: один ETag, список через запятую или "*" (RFC 9110, 13.1.1).
+// present=false, если заголовка нет; anyTag=true для "*" (подойдёт любая версия).
+// If-Match требует строгого сравнения, поэтому слабые ETag (W/"3") и ETag, не похожие на выданные
+// сервисом, не совпадают ни с одной версией и в versions не попадают.
+func ifMatch(r *http.Request) (versions []int, anyTag, present bool, err error) {
+	var tags []string
+	for _, v := range r.Header.Values("If-Match") {
+		tags = append(tags, splitETags(v)...)
+	}
+	if len(tags) == 0 {
+		return nil, false, false, nil
+	}
+	for _, tag := range tags {
+		if tag == "*" {
+			if len(tags) > 1 {
+				return nil, false, true, errBadETag
+			}
+			return nil, true, true, nil
+		}
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+		n, err := strconv.Atoi(strings.Trim(tag, `"`))
+		if err != nil || n <= 0 || slices.Contains(versions, n) {
+			continue
+		}
+		versions = append(versions, n)
+	}
+	return versions, false, true, nil
+}
+
+// splitETags — элементы списка ETag; запятая внутри кавычек элементы не разделяет, пустые элементы пропускаются
+func splitETags(v string) []string {
+	var tags []string
+	quoted := false
+	start := 0
+	for i := 0; i <= len(v); i++ {
+		if i < len(v) {
+			if v[i] == '"' {
+				quoted = !quoted
+			}
+			if v[i] != ',' || quoted {
+				continue
+			}
+		}
+		if tag := strings.TrimSpace(v[start:i]); tag != "" {
+			tags = append(tags, tag)
+		}
+		start = i + 1
+	}
+	return tags
+}
+
+// HandlerOption — дополнительная настройка SubscriptionHandler
+type HandlerOption func(*SubscriptionHandler)
+
+// WithRequireIfMatch — PATCH, PUT и DELETE без If-Match отклоняются с 428 Precondition Required
+func WithRequireIfMatch() HandlerOption {
+	return func(h *SubscriptionHandler) {
+		h.requireIfMatch.Store(true)
+	}
+}
+
+// SetRequireIfMatch — включает или выключает обязательный If-Match без перезапуска
+func (h *SubscriptionHandler) SetRequireIfMatch(require bool) {
+	h.requireIfMatch.Store(require)
+}
+
+// preconditions — версия из If-Match для PATCH, PUT и DELETE; при ошибке ответ уже записан.
+// Если в списке несколько версий, выбирается совпавшая с текущей версией подписки id;
+// сервис всё равно сверяет её ещё раз при записи.
+func (h *SubscriptionHandler) preconditions(w http.ResponseWriter, r *http.Request, id string) (int, bool) {
+	versions, anyTag, present, err := ifMatch(r)
+	switch {
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err.Error())
+		return 0, false
+	case !present:
+		if h.requireIfMatch.Load() {
+			writeError(w, http.StatusPreconditionRequired, "If-Match header is required")
+			return 0, false
+		}
+		return 0, true
+	case anyTag:
+		return 0, true
+	case len(versions) == 1:
+		return versions[0], true
+	case len(versions) > 1:
+		sub, err := h.svc.GetByID(r.Context(), id)
+		switch {
+		case err == nil && slices.Contains(versions, sub.Version):
+			return sub.Version, true
+		case err == nil, errors.Is(err, gorm.ErrRecordNotFound):
+		case errors.Is(err, service.ErrForbidden):
+			writeError(w, http.StatusForbidden, err.Error())
+			return 0, false
+		case errors.Is(err, service.ErrValidation):
+			writeError(w, http.StatusBadRequest, err.Error())
+			return 0, false
+		default:
+			h.log.ErrorContext(r.Context(), "get subscription for If-Match failed", "error", err)
+			writeError(w, http.StatusInternalServerError, "internal error")
+			return 0, false
+		}
+	}
+	writeError(w, http.StatusPreconditionFailed, "ETag does not match current version")
+	return 0, false
+}
@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/olesia8novoselova/Subscriptions/internal/service"
+	"gorm.io/gorm"
+)
+
+type MemberService interface {
+	Add(ctx context.Context, subscriptionID string, req models.AddMemberRequest) (*models.SubscriptionMember, error)
+	List(ctx context.Context, subscriptionID string) ([]models.SubscriptionMember, error)
+	Remove(ctx context.Context, subscriptionID, id string) error
+}
+
+type MemberHandler struct {
+	svc MemberService
+	log *slog.Logger
+}
+
+func NewMemberHandler(svc MemberService, log *slog.Logger) *MemberHandler {
+	return &MemberHandler{svc: svc, log: log}
+}
+
+// AddMember
+// @Summary Add subscription member
+// @Description Добавляет участника совместной подписки с долей в процентах (share_type=percent) или фиксированной суммой (share_type=fixed). Остаток цены оплачивает владелец. Доля учитывается в /total с фильтром user_id.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param  id  path  string  true  "Subscription ID (UUID)"  example("b548150d-6198-4cc1-a186-8c4a1e0ccdcf")
+// @Param  request  body  models.AddMemberRequest  true  "Member body"
+// @Success  201  {object}  models.SubscriptionMemberResponse
+// @Failure  400  {object}  map[string]string
+// @Failure  404  {object}  map[string]string
+// @Failure  409  {object}  map[string]string
+// @Failure  422  {object}  map[string]string
+// @Failure  500  {object}  map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router  /api/subscriptions/{id}/members  [post]
+func (h *MemberHandler) AddMember(w http.ResponseWriter, r *http.Request) {
+	var req models.AddMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	m, err := h.svc.Add(r.Context(), r.PathValue("id"), req)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeError(w, http.StatusNotFound, "subscription not found")
+			return
+		}
+		if errors.Is(err, service.ErrOverlap) {
+			writeError(w, http.StatusConflict, "member already has overlapping subscription for this service")
+			return
+		}
+		if errors.Is(err, service.ErrUnknownUser) {
+			writeError(w, http.StatusUnprocessableEntity, "user not found")
+			return
+		}
+		if errors.Is(err, service.ErrValidation) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.log.ErrorContext(r.Context(), "add member failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(toMemberResponse(m))
+}
+
+// ListMembers
+// @Summary List subscription members
+// @Description Участники подписки и их доли при текущей цене
+// @Tags subscriptions
+// @Produce json
+// @Param  id  path  string  true  "Subscription ID (UUID)"  example("b548150d-6198-4cc1-a186-8c4a1e0ccdcf")
+// @Success  200  {array}  models.SubscriptionMemberResponse
+// @Failure  400  {object}  map[string]string
+// @Failure  404  {object}  map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router  /api/subscriptions/{id}/members  [get]
+func (h *MemberHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	list, err := h.svc.List(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeError(w, http.StatusNotFound, "subscription not found")
+			return
+		}
+		h.log.ErrorContext(r.Context(), "list members failed", "error", err)
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resp := make([]models.SubscriptionMemberResponse, 0, len(list))
+	for i := range list {
+		resp = append(resp, toMemberResponse(&list[i]))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// RemoveMember
+// @Summary Remove subscription member
+// @Description Удаляет участника подписки; его доля снова оплачивается владельцем
+// @Tags subscriptions
+// @Param  id  path  string  true  "Subscription ID (UUID)"  example("b548150d-6198-4cc1-a186-8c4a1e0ccdcf")
+// @Param  member_id  path  string  true  "Member ID (UUID)"  example("7d2e4f10-9a3b-4c5d-8e6f-1a2b3c4d5e6f")
+// @Success  204  "No Content"
+// @Failure  400  {object}  map[string]string
+// @Failure  404  {object}  map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router  /api/subscriptions/{id}/members/{member_id}  [delete]
+func (h *MemberHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	if err := h.svc.Remove(r.Context(), r.PathValue("id"), r.PathValue("member_id")); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeError(w, http.StatusNotFound, "member not found")
+			return
+		}
+		h.log.ErrorContext(r.Context(), "remove member failed", "error", err)
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func toMemberResponse(m *models.SubscriptionMember) models.SubscriptionMemberResponse {
+	return models.SubscriptionMemberResponse{
+		ID:             m.ID,
+		SubscriptionID: m.SubscriptionID,
+		UserID:         m.UserID,
+		ShareType:      m.ShareType,
+		ShareValue:     m.ShareValue,
+		Amount:         m.Amount,
+	}
+}
@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"gorm.io/gorm"
+)
+
+type ScheduledChangeService interface {
+	Create(ctx context.Context, subscriptionID string, req models.CreateScheduledChangeRequest) (*models.ScheduledChange, error)
+	List(ctx context.Context, subscriptionID string) ([]models.ScheduledChange, error)
+	Delete(ctx context.Context, subscriptionID, id string) error
+}
+
+type ScheduledChangeHandler struct {
+	svc ScheduledChangeService
+	log *slog.Logger
+}
+
+func NewScheduledChangeHandler(svc ScheduledChangeService, log *slog.Logger) *ScheduledChangeHandler {
+	return &ScheduledChangeHandler{svc: svc, log: log}
+}
+
+// CreateScheduledChange
+// @Summary Schedule price change or cancellation
+// @Description Планирует смену цены (new_price) или отмену (cancel) подписки начиная с месяца effective_date. Учитывается в прогнозе расходов.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param  id  path  string  true  "Subscription ID (UUID)"  example("b548150d-6198-4cc1-a186-8c4a1e0ccdcf")
+// @Param  request  body  models.CreateScheduledChangeRequest  true  "Change body"
+// @Success  201  {object}  models.ScheduledChangeResponse
+// @Failure  400  {object}  map[string]string
+// @Failure  404  {object}  map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router  /api/subscriptions/{id}/changes  [post]
+func (h *ScheduledChangeHandler) CreateScheduledChange(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateScheduledChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	c, err := h.svc.Create(r.Context(), r.PathValue("id"), req)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeError(w, http.StatusNotFound, "subscription not found")
+			return
+		}
+		h.log.ErrorContext(r.Context(), "create scheduled change failed", "error", err)
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(toScheduledChangeResponse(c))
+}
+
+// ListScheduledChanges
+// @Summary List scheduled changes
+// @Description Запланированные изменения подписки
+// @Tags subscriptions
+// @Produce json
+// @Param  id  path  string  true  "Subscription ID (UUID)"  example("b548150d-6198-4cc1-a186-8c4a1e0ccdcf")
+// @Success  200  {array}  models.ScheduledChangeResponse
+// @Failure  400  {object}  map[string]string
+// @Failure  404  {object}  map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router  /api/subscriptions/{id}/changes  [get]
+func (h *ScheduledChangeHandler) ListScheduledChanges(w http.ResponseWriter, r *http.Request) {
+	list, err := h.svc.List(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeError(w, http.StatusNotFound, "subscription not found")
+			return
+		}
+		h.log.ErrorContext(r.Context(), "list scheduled changes failed", "error", err)
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resp := make([]models.ScheduledChangeResponse, 0, len(list))
+	for i := range list {
+		resp = append(resp, toScheduledChangeResponse(&list[i]))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// DeleteScheduledChange
+// @Summary Delete scheduled change
+// @Description Удаляет запланированное изменение подписки
+// @Tags subscriptions
+// @Param  id  path  string  true  "Subscription ID (UUID)"  example("b548150d-6198-4cc1-a186-8c4a1e0ccdcf")
+// @Param  change_id  path  string  true  "Change ID (UUID)"  example("4a1e6c0b-3d2f-4b8e-9c71-5f0d2a8b6e13")
+// @Success  204  "No Content"
+// @Failure  400  {object}  map[string]string
+// @Failure  404  {object}  map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router  /api/subscriptions/{id}/changes/{change_id}  [delete]
+func (h *ScheduledChangeHandler) DeleteScheduledChange(w http.ResponseWriter, r *http.Request) {
+	if err := h.svc.Delete(r.Context(), r.PathValue("id"), r.PathValue("change_id")); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeError(w, http.StatusNotFound, "scheduled change not found")
+			return
+		}
+		h.log.ErrorContext(r.Context(), "delete scheduled change failed", "error", err)
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func toScheduledChangeResponse(c *models.ScheduledChange) models.ScheduledChangeResponse {
+	return models.ScheduledChangeResponse{
+		ID:             c.ID,
+		SubscriptionID: c.SubscriptionID,
+		EffectiveDate:  c.EffectiveDate.Format("01-2006"),
+		NewPrice:       c.NewPrice,
+		Cancel:         c.Cancel,
+	}
+}
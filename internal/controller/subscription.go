@@ -8,9 +8,11 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync/atomic"
 
 	"github.com/olesia8novoselova/Subscriptions/internal/models"
 	"github.com/olesia8novoselova/Subscriptions/internal/service"
+	"github.com/olesia8novoselova/Subscriptions/pkg/logging"
 	"gorm.io/gorm"
 )
 
@@ -18,18 +20,27 @@ type SubscriptionService interface {
 	Create(ctx context.Context, req models.CreateSubscriptionRequest) (*models.Subscription, error)
 	GetByID(ctx context.Context, id string) (*models.Subscription, error)
 	List(ctx context.Context, userID, serviceName string, limit, offset int) ([]models.Subscription, error)
-	Delete(ctx context.Context, id string) error
-	Patch(ctx context.Context, id string, req models.UpdateSubscriptionRequest) (*models.Subscription, error)
+	Delete(ctx context.Context, id string, ifMatch int) error
+	Patch(ctx context.Context, id string, req models.UpdateSubscriptionRequest, ifMatch int) (*models.Subscription, error)
+	Replace(ctx context.Context, id string, req models.CreateSubscriptionRequest, ifMatch int) (*models.Subscription, bool, error)
 	TotalCost(ctx context.Context, from, to, userID, serviceName string) (int, error)
+	Forecast(ctx context.Context, from string, months int, userID, serviceName string) (*models.ForecastResponse, error)
+	Duplicates(ctx context.Context, userID, from, to string) (*models.DuplicateReport, error)
 }
 
 type SubscriptionHandler struct {
 	svc SubscriptionService
 	log *slog.Logger
+
+	requireIfMatch atomic.Bool
 }
 
-func NewSubscriptionHandler(svc SubscriptionService, log *slog.Logger) *SubscriptionHandler {
-	return &SubscriptionHandler{svc: svc, log: log}
+func NewSubscriptionHandler(svc SubscriptionService, log *slog.Logger, opts ...HandlerOption) *SubscriptionHandler {
+	h := &SubscriptionHandler{svc: svc, log: log}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 // CreateSubscription
@@ -39,8 +50,14 @@ func NewSubscriptionHandler(svc SubscriptionService, log *slog.Logger) *Subscrip
 // @Accept json
 // @Produce json
 // @Param  request  body models.CreateSubscriptionRequest  true  "Subscription body"
+// @Param  Idempotency-Key  header  string  false  "Key for safe retries: the original response is replayed"
 // @Success  201  {object}  models.SubscriptionResponse
 // @Failure  400  {object}  map[string]string
+// @Failure  409  {object}  map[string]string
+// @Failure  422  {object}  map[string]string
+// @Failure  403  {object}  map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router  /api/subscriptions  [post]
 func (h *SubscriptionHandler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -50,23 +67,32 @@ func (h *SubscriptionHandler) CreateSubscription(w http.ResponseWriter, r *http.
 
 	var req models.CreateSubscriptionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, http.StatusBadRequest, "invalid JSON")
+		writeError(w, http.StatusBadRequest, "invalid JSON")
 		return
 	}
 
 	sub, err := h.svc.Create(r.Context(), req)
 	if err != nil {
 		if errors.Is(err, service.ErrOverlap) {
-			h.writeError(w, http.StatusConflict, "subscription overlaps with existing one for this user and service")
+			writeError(w, http.StatusConflict, "subscription overlaps with existing one for this user and service")
+			return
+		}
+		if errors.Is(err, service.ErrBudgetExceeded) {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
 			return
 		}
-		h.log.Error("create subscription failed", "error", err)
-		h.writeError(w, http.StatusBadRequest, err.Error())
+		if errors.Is(err, service.ErrForbidden) {
+			writeError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		h.log.ErrorContext(r.Context(), "create subscription failed", "error", err)
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	resp := toResponse(sub)
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(sub.Version))
 	w.WriteHeader(http.StatusCreated)
 	_ = json.NewEncoder(w).Encode(resp)
 }
@@ -78,8 +104,11 @@ func (h *SubscriptionHandler) CreateSubscription(w http.ResponseWriter, r *http.
 // @Produce json
 // @Param  id  path  string  true  "Subscription ID (UUID)"  example("b548150d-6198-4cc1-a186-8c4a1e0ccdcf")
 // @Success  200 {object}  models.SubscriptionResponse
+// @Header  200  {string}  ETag  "Версия подписки для If-Match"
 // @Failure  400 {object}  map[string]string
 // @Failure  404 {object}  map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router  /api/subscriptions/{id}  [get]
 func (h *SubscriptionHandler) GetSubscription(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -89,23 +118,24 @@ func (h *SubscriptionHandler) GetSubscription(w http.ResponseWriter, r *http.Req
 	// Получаем id из пути
 	id := strings.TrimPrefix(r.URL.Path, "/api/subscriptions/")
 	if id == "" || strings.Contains(id, "/") {
-		h.writeError(w, http.StatusBadRequest, "invalid id path")
+		writeError(w, http.StatusBadRequest, "invalid id path")
 		return
 	}
 
 	sub, err := h.svc.GetByID(r.Context(), id)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			h.writeError(w, http.StatusNotFound, "subscription not found")
+			writeError(w, http.StatusNotFound, "subscription not found")
 			return
 		}
-		h.log.Error("get subscription failed", "error", err)
-		h.writeError(w, http.StatusBadRequest, err.Error())
+		h.log.ErrorContext(r.Context(), "get subscription failed", "error", err)
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	resp := toResponse(sub)
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(sub.Version))
 	_ = json.NewEncoder(w).Encode(resp)
 }
 
@@ -116,10 +146,13 @@ func (h *SubscriptionHandler) GetSubscription(w http.ResponseWriter, r *http.Req
 // @Produce json
 // @Param  user_id  query  string  false  "Filter by user UUID"  example("b548150d-6198-4cc1-a186-8c4a1e0ccdcf")
 // @Param  service_name  query  string false  "Filter by service name"  example("Test Service")  default("Test Service")
-// @Param  limit  query  int  false  "Page size (default 20, max 100)"  example(20)  default(20)
+// @Param  limit  query  int  false  "Page size (pagination.default_limit and pagination.max_limit, 20 and 100 by default)"  example(20)  default(20)
 // @Param  offset  query  int  false  "Offset (default 0)" example(0)  default(0)
 // @Success  200 {array}  models.SubscriptionResponse
 // @Failure  400 {object}  map[string]string
+// @Failure  403  {object}  map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router  /api/subscriptions  [get]
 func (h *SubscriptionHandler) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -135,7 +168,7 @@ func (h *SubscriptionHandler) ListSubscriptions(w http.ResponseWriter, r *http.R
 	if v := q.Get("limit"); v != "" {
 		n, err := strconv.Atoi(v)
 		if err != nil {
-			h.writeError(w, http.StatusBadRequest, "limit must be integer")
+			writeError(w, http.StatusBadRequest, "limit must be integer")
 			return
 		}
 		limit = n
@@ -143,7 +176,7 @@ func (h *SubscriptionHandler) ListSubscriptions(w http.ResponseWriter, r *http.R
 	if v := q.Get("offset"); v != "" {
 		n, err := strconv.Atoi(v)
 		if err != nil {
-			h.writeError(w, http.StatusBadRequest, "offset must be integer")
+			writeError(w, http.StatusBadRequest, "offset must be integer")
 			return
 		}
 		offset = n
@@ -151,8 +184,12 @@ func (h *SubscriptionHandler) ListSubscriptions(w http.ResponseWriter, r *http.R
 
 	list, err := h.svc.List(r.Context(), userID, serviceName, limit, offset)
 	if err != nil {
-		h.log.Error("list subscriptions failed", "error", err)
-		h.writeError(w, http.StatusBadRequest, err.Error())
+		if errors.Is(err, service.ErrForbidden) {
+			writeError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		h.log.ErrorContext(r.Context(), "list subscriptions failed", "error", err)
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -169,9 +206,14 @@ func (h *SubscriptionHandler) ListSubscriptions(w http.ResponseWriter, r *http.R
 // @Description Удаляет подписку по её ID
 // @Tags subscriptions
 // @Param  id  path  string  true "Subscription ID (UUID)"  example("b548150d-6198-4cc1-a186-8c4a1e0ccdcf")
+// @Param  If-Match  header  string  false  "ETag из GET/PATCH; при несовпадении — 412"
 // @Success  204  "No Content"
 // @Failure  400  {object}  map[string]string
 // @Failure  404  {object}  map[string]string
+// @Failure  412  {object}  map[string]string
+// @Failure  428  {object}  map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router  /api/subscriptions/{id}  [delete]
 func (h *SubscriptionHandler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
@@ -180,17 +222,26 @@ func (h *SubscriptionHandler) DeleteSubscription(w http.ResponseWriter, r *http.
 	}
 	id := strings.TrimPrefix(r.URL.Path, "/api/subscriptions/")
 	if id == "" || strings.Contains(id, "/") {
-		h.writeError(w, http.StatusBadRequest, "invalid id path")
+		writeError(w, http.StatusBadRequest, "invalid id path")
+		return
+	}
+
+	version, ok := h.preconditions(w, r, id)
+	if !ok {
 		return
 	}
 
-	if err := h.svc.Delete(r.Context(), id); err != nil {
+	if err := h.svc.Delete(r.Context(), id, version); err != nil {
 		if err == gorm.ErrRecordNotFound {
-			h.writeError(w, http.StatusNotFound, "subscription not found")
+			writeError(w, http.StatusNotFound, "subscription not found")
+			return
+		}
+		if errors.Is(err, service.ErrPreconditionFailed) {
+			writeError(w, http.StatusPreconditionFailed, err.Error())
 			return
 		}
-		h.log.Error("delete subscription failed", "error", err)
-		h.writeError(w, http.StatusBadRequest, err.Error())
+		h.log.ErrorContext(r.Context(), "delete subscription failed", "error", err)
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -205,9 +256,17 @@ func (h *SubscriptionHandler) DeleteSubscription(w http.ResponseWriter, r *http.
 // @Produce json
 // @Param id  path  string  true  "Subscription ID"  format(uuid)  example("b548150d-6198-4cc1-a186-8c4a1e0ccdcf")
 // @Param request body  models.UpdateSubscriptionRequest  true  "Fields to update"
+// @Param  If-Match  header  string  false  "ETag из GET/PATCH; при несовпадении — 412"
 // @Success  200  {object}  models.SubscriptionResponse
+// @Header  200  {string}  ETag  "Новая версия подписки"
 // @Failure  400  {object}  map[string]string
 // @Failure  404  {object}  map[string]string
+// @Failure  409  {object}  map[string]string
+// @Failure  412  {object}  map[string]string
+// @Failure  422  {object}  map[string]string
+// @Failure  428  {object}  map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /api/subscriptions/{id}  [patch]
 func (h *SubscriptionHandler) PatchSubscription(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPatch {
@@ -216,33 +275,127 @@ func (h *SubscriptionHandler) PatchSubscription(w http.ResponseWriter, r *http.R
 	}
 	id := strings.TrimPrefix(r.URL.Path, "/api/subscriptions/")
 	if id == "" || strings.Contains(id, "/") {
-		h.writeError(w, http.StatusBadRequest, "invalid id path")
+		writeError(w, http.StatusBadRequest, "invalid id path")
 		return
 	}
 
 	var req models.UpdateSubscriptionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, http.StatusBadRequest, "invalid JSON")
+		writeError(w, http.StatusBadRequest, "invalid JSON")
 		return
 	}
 
-	sub, err := h.svc.Patch(r.Context(), id, req)
+	version, ok := h.preconditions(w, r, id)
+	if !ok {
+		return
+	}
+
+	sub, err := h.svc.Patch(r.Context(), id, req, version)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			h.writeError(w, http.StatusNotFound, "subscription not found")
+			writeError(w, http.StatusNotFound, "subscription not found")
+			return
+		}
+		if errors.Is(err, service.ErrPreconditionFailed) {
+			writeError(w, http.StatusPreconditionFailed, err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrOverlap) {
+			writeError(w, http.StatusConflict, "subscription overlaps with existing one for this user and service")
+			return
+		}
+		if errors.Is(err, service.ErrBudgetExceeded) {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		h.log.ErrorContext(r.Context(), "patch subscription failed", "error", err)
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resp := toResponse(sub)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(sub.Version))
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// ReplaceSubscription
+// @Summary Replace subscription
+// @Description Полная замена подписки: необязательные поля, которых нет в теле, очищаются. Проверки те же, что при создании. Если подписки с таким ID нет, она создаётся (201), кроме запроса с If-Match.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id  path  string  true  "Subscription ID"  format(uuid)  example("b548150d-6198-4cc1-a186-8c4a1e0ccdcf")
+// @Param request body  models.CreateSubscriptionRequest  true  "Full subscription body"
+// @Param  If-Match  header  string  false  "ETag из GET/PATCH/PUT; при несовпадении — 412"
+// @Success  200  {object}  models.SubscriptionResponse
+// @Success  201  {object}  models.SubscriptionResponse
+// @Header  200,201  {string}  ETag  "Новая версия подписки"
+// @Failure  400  {object}  map[string]string
+// @Failure  403  {object}  map[string]string
+// @Failure  404  {object}  map[string]string
+// @Failure  409  {object}  map[string]string
+// @Failure  412  {object}  map[string]string
+// @Failure  422  {object}  map[string]string
+// @Failure  428  {object}  map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /api/subscriptions/{id}  [put]
+func (h *SubscriptionHandler) ReplaceSubscription(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/subscriptions/")
+	if id == "" || strings.Contains(id, "/") {
+		writeError(w, http.StatusBadRequest, "invalid id path")
+		return
+	}
+
+	var req models.CreateSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	version, ok := h.preconditions(w, r, id)
+	if !ok {
+		return
+	}
+
+	sub, created, err := h.svc.Replace(r.Context(), id, req, version)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeError(w, http.StatusNotFound, "subscription not found")
+			return
+		}
+		if errors.Is(err, service.ErrIDConflict) {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrPreconditionFailed) {
+			writeError(w, http.StatusPreconditionFailed, err.Error())
 			return
 		}
 		if errors.Is(err, service.ErrOverlap) {
-			h.writeError(w, http.StatusConflict, "subscription overlaps with existing one for this user and service")
+			writeError(w, http.StatusConflict, "subscription overlaps with existing one for this user and service")
+			return
+		}
+		if errors.Is(err, service.ErrBudgetExceeded) {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
 			return
 		}
-		h.log.Error("patch subscription failed", "error", err)
-		h.writeError(w, http.StatusBadRequest, err.Error())
+		if errors.Is(err, service.ErrForbidden) {
+			writeError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		h.log.ErrorContext(r.Context(), "replace subscription failed", "error", err)
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	resp := toResponse(sub)
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(sub.Version))
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	}
 	_ = json.NewEncoder(w).Encode(resp)
 }
 
@@ -257,6 +410,9 @@ func (h *SubscriptionHandler) PatchSubscription(w http.ResponseWriter, r *http.R
 // @Param  service_name  query  string  false  "Filter by service name"  example("Yandex Plus")
 // @Success  200  {object}  models.TotalCostResponse
 // @Failure  400  {object}  map[string]string
+// @Failure  403  {object}  map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /api/subscriptions/total  [get]
 func (h *SubscriptionHandler) GetTotalCost(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -267,7 +423,7 @@ func (h *SubscriptionHandler) GetTotalCost(w http.ResponseWriter, r *http.Reques
 	from := q.Get("from")
 	to := q.Get("to")
 	if from == "" || to == "" {
-		h.writeError(w, http.StatusBadRequest, "from and to are required (MM-YYYY)")
+		writeError(w, http.StatusBadRequest, "from and to are required (MM-YYYY)")
 		return
 	}
 	userID := q.Get("user_id")
@@ -275,8 +431,12 @@ func (h *SubscriptionHandler) GetTotalCost(w http.ResponseWriter, r *http.Reques
 
 	total, err := h.svc.TotalCost(r.Context(), from, to, userID, serviceName)
 	if err != nil {
-		h.log.Error("total cost failed", "error", err)
-		h.writeError(w, http.StatusBadRequest, err.Error())
+		if errors.Is(err, service.ErrForbidden) {
+			writeError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		h.log.ErrorContext(r.Context(), "total cost failed", "error", err)
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -285,10 +445,83 @@ func (h *SubscriptionHandler) GetTotalCost(w http.ResponseWriter, r *http.Reques
 	_ = json.NewEncoder(w).Encode(resp)
 }
 
-func (h *SubscriptionHandler) writeError(w http.ResponseWriter, code int, msg string) {
+// GetForecast
+// @Summary Spending forecast
+// @Description Помесячный прогноз расходов на months месяцев вперёд. committed — подписки с датой окончания или запланированной отменой, projected — бессрочные. Учитываются запланированные изменения цены.
+// @Tags subscriptions
+// @Produce json
+// @Param  from  query  string  false  "First month (MM-YYYY), default current month"  example("01-2026")
+// @Param  months  query  int  false  "Number of months (default 12, max 60)"  example(12)  default(12)
+// @Param  user_id  query  string  false  "Filter by user UUID"  format(uuid)  example("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+// @Param  service_name  query  string  false  "Filter by service name"  example("Yandex Plus")
+// @Success  200  {object}  models.ForecastResponse
+// @Failure  400  {object}  map[string]string
+// @Failure  403  {object}  map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /api/subscriptions/forecast  [get]
+func (h *SubscriptionHandler) GetForecast(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	months := 0
+	if v := q.Get("months"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "months must be integer")
+			return
+		}
+		months = n
+	}
+
+	resp, err := h.svc.Forecast(r.Context(), q.Get("from"), months, q.Get("user_id"), q.Get("service_name"))
+	if err != nil {
+		if errors.Is(err, service.ErrForbidden) {
+			writeError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		h.log.ErrorContext(r.Context(), "forecast failed", "error", err)
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// GetDuplicates
+// @Summary Duplicate and redundant subscriptions
+// @Description Находит подписки пользователя с похожими названиями или одной категорией, которые оплачиваются в одни и те же месяцы, и показывает экономию от отмены каждой. По умолчанию период — 12 месяцев начиная с текущего.
+// @Tags subscriptions
+// @Produce json
+// @Param  user_id  path  string  true  "User ID (UUID)"  example("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+// @Param  from  query  string  false  "From month (MM-YYYY)"  example("07-2025")
+// @Param  to  query  string  false  "To month (MM-YYYY)"  example("06-2026")
+// @Success  200  {object}  models.DuplicateReport
+// @Failure  400  {object}  map[string]string
+// @Failure  403  {object}  map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /api/users/{user_id}/duplicates  [get]
+func (h *SubscriptionHandler) GetDuplicates(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	report, err := h.svc.Duplicates(r.Context(), r.PathValue("user_id"), q.Get("from"), q.Get("to"))
+	if err != nil {
+		if errors.Is(err, service.ErrForbidden) {
+			writeError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		h.log.ErrorContext(r.Context(), "duplicates analysis failed", "error", err)
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
-	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+	_ = json.NewEncoder(w).Encode(logging.ErrorBody(w, msg))
 }
 
 func toResponse(s *models.Subscription) models.SubscriptionResponse {
@@ -305,5 +538,8 @@ func toResponse(s *models.Subscription) models.SubscriptionResponse {
 		UserID:      s.UserID,
 		StartDate:   start,
 		EndDate:     endStr,
+		Category:    s.Category,
+
+		BudgetAlerts: s.BudgetAlerts,
 	}
 }
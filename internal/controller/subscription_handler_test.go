@@ -20,12 +20,15 @@ import (
 )
 
 type fakeService struct {
-	CreateFn    func(ctx context.Context, req models.CreateSubscriptionRequest) (*models.Subscription, error)
-	GetByIDFn   func(ctx context.Context, id string) (*models.Subscription, error)
-	ListFn      func(ctx context.Context, userID, serviceName string, limit, offset int) ([]models.Subscription, error)
-	DeleteFn    func(ctx context.Context, id string) error
-	PatchFn     func(ctx context.Context, id string, req models.UpdateSubscriptionRequest) (*models.Subscription, error)
-	TotalCostFn func(ctx context.Context, from, to, userID, serviceName string) (int, error)
+	CreateFn     func(ctx context.Context, req models.CreateSubscriptionRequest) (*models.Subscription, error)
+	GetByIDFn    func(ctx context.Context, id string) (*models.Subscription, error)
+	ListFn       func(ctx context.Context, userID, serviceName string, limit, offset int) ([]models.Subscription, error)
+	DeleteFn     func(ctx context.Context, id string, ifMatch int) error
+	PatchFn      func(ctx context.Context, id string, req models.UpdateSubscriptionRequest, ifMatch int) (*models.Subscription, error)
+	ReplaceFn    func(ctx context.Context, id string, req models.CreateSubscriptionRequest, ifMatch int) (*models.Subscription, bool, error)
+	TotalCostFn  func(ctx context.Context, from, to, userID, serviceName string) (int, error)
+	ForecastFn   func(ctx context.Context, from string, months int, userID, serviceName string) (*models.ForecastResponse, error)
+	DuplicatesFn func(ctx context.Context, userID, from, to string) (*models.DuplicateReport, error)
 }
 
 func (f *fakeService) Create(ctx context.Context, req models.CreateSubscriptionRequest) (*models.Subscription, error) {
@@ -37,15 +40,24 @@ func (f *fakeService) GetByID(ctx context.Context, id string) (*models.Subscript
 func (f *fakeService) List(ctx context.Context, userID, serviceName string, limit, offset int) ([]models.Subscription, error) {
 	return f.ListFn(ctx, userID, serviceName, limit, offset)
 }
-func (f *fakeService) Delete(ctx context.Context, id string) error {
-	return f.DeleteFn(ctx, id)
+func (f *fakeService) Delete(ctx context.Context, id string, ifMatch int) error {
+	return f.DeleteFn(ctx, id, ifMatch)
 }
-func (f *fakeService) Patch(ctx context.Context, id string, req models.UpdateSubscriptionRequest) (*models.Subscription, error) {
-	return f.PatchFn(ctx, id, req)
+func (f *fakeService) Patch(ctx context.Context, id string, req models.UpdateSubscriptionRequest, ifMatch int) (*models.Subscription, error) {
+	return f.PatchFn(ctx, id, req, ifMatch)
+}
+func (f *fakeService) Replace(ctx context.Context, id string, req models.CreateSubscriptionRequest, ifMatch int) (*models.Subscription, bool, error) {
+	return f.ReplaceFn(ctx, id, req, ifMatch)
 }
 func (f *fakeService) TotalCost(ctx context.Context, from, to, userID, serviceName string) (int, error) {
 	return f.TotalCostFn(ctx, from, to, userID, serviceName)
 }
+func (f *fakeService) Forecast(ctx context.Context, from string, months int, userID, serviceName string) (*models.ForecastResponse, error) {
+	return f.ForecastFn(ctx, from, months, userID, serviceName)
+}
+func (f *fakeService) Duplicates(ctx context.Context, userID, from, to string) (*models.DuplicateReport, error) {
+	return f.DuplicatesFn(ctx, userID, from, to)
+}
 
 func mustUUID(s string) uuid.UUID { u, _ := uuid.Parse(s); return u }
 
@@ -177,7 +189,7 @@ func TestListSubscriptions_OK(t *testing.T) {
 // TestDeleteSubscription_OK - тестирует успешное удаление подписки
 func TestDeleteSubscription_OK(t *testing.T) {
 	fs := &fakeService{
-		DeleteFn: func(ctx context.Context, id string) error { return nil },
+		DeleteFn: func(ctx context.Context, id string, ifMatch int) error { return nil },
 	}
 	h := controller.NewSubscriptionHandler(fs, newTestLogger())
 
@@ -194,7 +206,7 @@ func TestDeleteSubscription_OK(t *testing.T) {
 // TestDeleteSubscription_NotFound - тестирует удаление подписки, когда она не найдена
 func TestDeleteSubscription_NotFound(t *testing.T) {
 	fs := &fakeService{
-		DeleteFn: func(ctx context.Context, id string) error { return gorm.ErrRecordNotFound },
+		DeleteFn: func(ctx context.Context, id string, ifMatch int) error { return gorm.ErrRecordNotFound },
 	}
 	h := controller.NewSubscriptionHandler(fs, newTestLogger())
 
@@ -211,7 +223,7 @@ func TestDeleteSubscription_NotFound(t *testing.T) {
 // TestPatchSubscription_Conflict - тестирует конфликт при обновлении подписки
 func TestPatchSubscription_Conflict(t *testing.T) {
 	fs := &fakeService{
-		PatchFn: func(ctx context.Context, id string, req models.UpdateSubscriptionRequest) (*models.Subscription, error) {
+		PatchFn: func(ctx context.Context, id string, req models.UpdateSubscriptionRequest, ifMatch int) (*models.Subscription, error) {
 			return nil, service.ErrOverlap
 		},
 	}
@@ -231,7 +243,7 @@ func TestPatchSubscription_Conflict(t *testing.T) {
 // TestPatchSubscription_OK - тестирует успешное обновление подписки
 func TestPatchSubscription_OK(t *testing.T) {
 	fs := &fakeService{
-		PatchFn: func(ctx context.Context, id string, req models.UpdateSubscriptionRequest) (*models.Subscription, error) {
+		PatchFn: func(ctx context.Context, id string, req models.UpdateSubscriptionRequest, ifMatch int) (*models.Subscription, error) {
 			s := subDTO()
 			s.Price = 600
 			return s, nil
@@ -292,3 +304,237 @@ func TestGetTotalCost_ValidationError(t *testing.T) {
 		t.Fatalf("status = %d, want 400", w.Code)
 	}
 }
+
+// TestGetForecast_OK - тестирует получение прогноза расходов
+func TestGetForecast_OK(t *testing.T) {
+	var gotMonths int
+	fs := &fakeService{
+		ForecastFn: func(ctx context.Context, from string, months int, user, svc string) (*models.ForecastResponse, error) {
+			gotMonths = months
+			return &models.ForecastResponse{
+				Months:    []models.ForecastMonth{{Month: "01-2026", Committed: 100, Projected: 200, Total: 300}},
+				Committed: 100, Projected: 200, Total: 300,
+			}, nil
+		},
+	}
+	h := controller.NewSubscriptionHandler(fs, newTestLogger())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/subscriptions/forecast?from=01-2026&months=1", nil)
+	w := httptest.NewRecorder()
+
+	h.GetForecast(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	if gotMonths != 1 {
+		t.Fatalf("months = %d, want 1", gotMonths)
+	}
+	var got models.ForecastResponse
+	_ = json.NewDecoder(w.Body).Decode(&got)
+	if got.Total != 300 || len(got.Months) != 1 {
+		t.Fatalf("unexpected body: %+v", got)
+	}
+}
+
+// TestGetDuplicates_OK - тестирует анализ дублирующих подписок пользователя
+func TestGetDuplicates_OK(t *testing.T) {
+	var gotUser string
+	fs := &fakeService{
+		DuplicatesFn: func(ctx context.Context, userID, from, to string) (*models.DuplicateReport, error) {
+			gotUser = userID
+			return &models.DuplicateReport{
+				UserID:   mustUUID(userID),
+				Findings: []models.DuplicateFinding{{Reason: models.DuplicateReasonSimilarName, MaxSavings: 900}},
+			}, nil
+		},
+	}
+	h := controller.NewSubscriptionHandler(fs, newTestLogger())
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/users/{user_id}/duplicates", h.GetDuplicates)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users/60601fee-2bf1-4721-ae6f-7636e79a0cba/duplicates", nil)
+	w := httptest.NewRecorder()
+
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	if gotUser != "60601fee-2bf1-4721-ae6f-7636e79a0cba" {
+		t.Fatalf("user_id = %q", gotUser)
+	}
+	var got models.DuplicateReport
+	_ = json.NewDecoder(w.Body).Decode(&got)
+	if len(got.Findings) != 1 || got.Findings[0].MaxSavings != 900 {
+		t.Fatalf("unexpected body: %+v", got)
+	}
+}
+
+// TestPatchSubscription_IfMatch - тестирует передачу версии из If-Match, ответ 412 и новый ETag
+func TestPatchSubscription_IfMatch(t *testing.T) {
+	var gotVersion int
+	fs := &fakeService{
+		PatchFn: func(ctx context.Context, id string, req models.UpdateSubscriptionRequest, ifMatch int) (*models.Subscription, error) {
+			gotVersion = ifMatch
+			if ifMatch != 3 {
+				return nil, service.ErrPreconditionFailed
+			}
+			s := subDTO()
+			s.Version = 4
+			return s, nil
+		},
+	}
+	h := controller.NewSubscriptionHandler(fs, newTestLogger())
+	path := "/api/subscriptions/b548150d-6198-4cc1-a186-8c4a1e0ccdcf"
+
+	req := httptest.NewRequest(http.MethodPatch, path, bytes.NewBufferString(`{"price":600}`))
+	req.Header.Set("If-Match", `"3"`)
+	w := httptest.NewRecorder()
+	h.PatchSubscription(w, req)
+	if w.Code != http.StatusOK || gotVersion != 3 {
+		t.Fatalf("status = %d, version = %d, want 200 and 3", w.Code, gotVersion)
+	}
+	if etag := w.Header().Get("ETag"); etag != `"4"` {
+		t.Fatalf("ETag = %s, want \"4\"", etag)
+	}
+
+	req = httptest.NewRequest(http.MethodPatch, path, bytes.NewBufferString(`{"price":600}`))
+	req.Header.Set("If-Match", `"2"`)
+	w = httptest.NewRecorder()
+	h.PatchSubscription(w, req)
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale ETag: status = %d, want 412", w.Code)
+	}
+
+	// слабый ETag не совпадает даже с текущей версией
+	gotVersion = 0
+	req = httptest.NewRequest(http.MethodPatch, path, bytes.NewBufferString(`{"price":600}`))
+	req.Header.Set("If-Match", `W/"3"`)
+	w = httptest.NewRecorder()
+	h.PatchSubscription(w, req)
+	if w.Code != http.StatusPreconditionFailed || gotVersion != 0 {
+		t.Fatalf("weak ETag: status = %d, want 412 without calling service", w.Code)
+	}
+}
+
+// TestPatchSubscription_IfMatchList - тестирует список ETag в If-Match: совпадение с текущей версией или 412, но не 400
+func TestPatchSubscription_IfMatchList(t *testing.T) {
+	current := 3
+	gotVersion := 0
+	fs := &fakeService{
+		GetByIDFn: func(ctx context.Context, id string) (*models.Subscription, error) {
+			s := subDTO()
+			s.Version = current
+			return s, nil
+		},
+		PatchFn: func(ctx context.Context, id string, req models.UpdateSubscriptionRequest, ifMatch int) (*models.Subscription, error) {
+			gotVersion = ifMatch
+			s := subDTO()
+			s.Version = ifMatch + 1
+			return s, nil
+		},
+	}
+	h := controller.NewSubscriptionHandler(fs, newTestLogger())
+	path := "/api/subscriptions/b548150d-6198-4cc1-a186-8c4a1e0ccdcf"
+
+	cases := []struct {
+		header  string
+		code    int
+		version int
+	}{
+		{`"3", "4"`, http.StatusOK, 3},
+		{`"4","3"`, http.StatusOK, 3},
+		{`W/"4", "3"`, http.StatusOK, 3},
+		{`"5", "6"`, http.StatusPreconditionFailed, 0},
+		{`W/"3", W/"4"`, http.StatusPreconditionFailed, 0},
+		{`"3", *`, http.StatusBadRequest, 0},
+	}
+	for _, c := range cases {
+		gotVersion = 0
+		req := httptest.NewRequest(http.MethodPatch, path, bytes.NewBufferString(`{"price":600}`))
+		req.Header.Set("If-Match", c.header)
+		w := httptest.NewRecorder()
+		h.PatchSubscription(w, req)
+		if w.Code != c.code || gotVersion != c.version {
+			t.Fatalf("If-Match %s: status = %d, version = %d, want %d and %d", c.header, w.Code, gotVersion, c.code, c.version)
+		}
+	}
+
+	// несколько строк If-Match объединяются в один список
+	req := httptest.NewRequest(http.MethodPatch, path, bytes.NewBufferString(`{"price":600}`))
+	req.Header.Add("If-Match", `"1"`)
+	req.Header.Add("If-Match", `"3"`)
+	w := httptest.NewRecorder()
+	h.PatchSubscription(w, req)
+	if w.Code != http.StatusOK || gotVersion != 3 {
+		t.Fatalf("repeated If-Match: status = %d, version = %d, want 200 and 3", w.Code, gotVersion)
+	}
+
+	// подписки нет: список не совпадает ни с одной версией
+	fs.GetByIDFn = func(ctx context.Context, id string) (*models.Subscription, error) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	req = httptest.NewRequest(http.MethodPatch, path, bytes.NewBufferString(`{"price":600}`))
+	req.Header.Set("If-Match", `"3", "4"`)
+	w = httptest.NewRecorder()
+	h.PatchSubscription(w, req)
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("missing subscription: status = %d, want 412", w.Code)
+	}
+}
+
+// TestDeleteSubscription_RequireIfMatch - при WithRequireIfMatch удаление без If-Match отклоняется
+func TestDeleteSubscription_RequireIfMatch(t *testing.T) {
+	called := false
+	fs := &fakeService{
+		DeleteFn: func(ctx context.Context, id string, ifMatch int) error {
+			called = true
+			return nil
+		},
+	}
+	h := controller.NewSubscriptionHandler(fs, newTestLogger(), controller.WithRequireIfMatch())
+	path := "/api/subscriptions/b548150d-6198-4cc1-a186-8c4a1e0ccdcf"
+
+	w := httptest.NewRecorder()
+	h.DeleteSubscription(w, httptest.NewRequest(http.MethodDelete, path, nil))
+	if w.Code != http.StatusPreconditionRequired || called {
+		t.Fatalf("status = %d, want 428 without calling service", w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, path, nil)
+	req.Header.Set("If-Match", "*")
+	w = httptest.NewRecorder()
+	h.DeleteSubscription(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("If-Match *: status = %d, want 204", w.Code)
+	}
+}
+
+// TestReplaceSubscription_Status - тестирует ответ 201 при создании подписки через PUT и 200 при замене
+func TestReplaceSubscription_Status(t *testing.T) {
+	created := true
+	fs := &fakeService{
+		ReplaceFn: func(ctx context.Context, id string, req models.CreateSubscriptionRequest, ifMatch int) (*models.Subscription, bool, error) {
+			return subDTO(), created, nil
+		},
+	}
+	h := controller.NewSubscriptionHandler(fs, newTestLogger())
+
+	body := `{"service_name":"Test Service","price":500,"user_id":"60601fee-2bf1-4721-ae6f-7636e79a0cba","start_date":"07-2025"}`
+	w := httptest.NewRecorder()
+	h.ReplaceSubscription(w, httptest.NewRequest(http.MethodPut, "/api/subscriptions/b548150d-6198-4cc1-a186-8c4a1e0ccdcf", bytes.NewBufferString(body)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("upsert: status = %d, want 201", w.Code)
+	}
+
+	created = false
+	w = httptest.NewRecorder()
+	h.ReplaceSubscription(w, httptest.NewRequest(http.MethodPut, "/api/subscriptions/b548150d-6198-4cc1-a186-8c4a1e0ccdcf", bytes.NewBufferString(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("replace: status = %d, want 200", w.Code)
+	}
+	if w.Header().Get("ETag") == "" {
+		t.Fatalf("ETag header is missing")
+	}
+}
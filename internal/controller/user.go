@@ -0,0 +1,276 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/olesia8novoselova/Subscriptions/internal/service"
+	"gorm.io/gorm"
+)
+
+type UserService interface {
+	Create(ctx context.Context, req models.CreateUserRequest) (*models.User, error)
+	GetByID(ctx context.Context, id string) (*models.User, error)
+	List(ctx context.Context, limit, offset int) ([]models.User, error)
+	Patch(ctx context.Context, id string, req models.UpdateUserRequest) (*models.User, error)
+	Delete(ctx context.Context, id, policy, reassignTo string) error
+	Subscriptions(ctx context.Context, id string, limit, offset int) ([]models.Subscription, error)
+	Summary(ctx context.Context, id string) (*models.UserSummary, error)
+}
+
+type UserHandler struct {
+	svc UserService
+	log *slog.Logger
+}
+
+func NewUserHandler(svc UserService, log *slog.Logger) *UserHandler {
+	return &UserHandler{svc: svc, log: log}
+}
+
+// CreateUser
+// @Summary Create user
+// @Description Создаёт пользователя. id можно передать явно, иначе он будет сгенерирован. Только для администратора.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param  request  body  models.CreateUserRequest  true  "User body"
+// @Success  201  {object}  models.UserResponse
+// @Failure  400  {object}  map[string]string
+// @Failure  403  {object}  map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router  /api/users  [post]
+func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	u, err := h.svc.Create(r.Context(), req)
+	if err != nil {
+		h.writeUserError(w, r, "create user failed", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(toUserResponse(u))
+}
+
+// GetUser
+// @Summary Get user by id
+// @Description Возвращает пользователя по ID
+// @Tags users
+// @Produce json
+// @Param  id  path  string  true  "User ID (UUID)"  example("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+// @Success  200  {object}  models.UserResponse
+// @Failure  400  {object}  map[string]string
+// @Failure  403  {object}  map[string]string
+// @Failure  404  {object}  map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router  /api/users/{id}  [get]
+func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	u, err := h.svc.GetByID(r.Context(), r.PathValue("id"))
+	if err != nil {
+		h.writeUserError(w, r, "get user failed", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(toUserResponse(u))
+}
+
+// ListUsers
+// @Summary List users
+// @Description Список пользователей с пагинацией. Обычный пользователь видит только себя.
+// @Tags users
+// @Produce json
+// @Param  limit  query  int  false  "Page size (pagination.default_limit and pagination.max_limit, 20 and 100 by default)"  example(20)  default(20)
+// @Param  offset  query  int  false  "Offset (default 0)" example(0)  default(0)
+// @Success  200  {array}  models.UserResponse
+// @Failure  400  {object}  map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router  /api/users  [get]
+func (h *UserHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	limit, offset, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+
+	list, err := h.svc.List(r.Context(), limit, offset)
+	if err != nil {
+		h.writeUserError(w, r, "list users failed", err)
+		return
+	}
+
+	resp := make([]models.UserResponse, 0, len(list))
+	for i := range list {
+		resp = append(resp, toUserResponse(&list[i]))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// PatchUser
+// @Summary Patch user
+// @Description Частичное обновление имени и email пользователя. Пустой email удаляет его.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param  id  path  string  true  "User ID (UUID)"  example("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+// @Param  request  body  models.UpdateUserRequest  true  "Fields to update"
+// @Success  200  {object}  models.UserResponse
+// @Failure  400  {object}  map[string]string
+// @Failure  403  {object}  map[string]string
+// @Failure  404  {object}  map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router  /api/users/{id}  [patch]
+func (h *UserHandler) PatchUser(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	u, err := h.svc.Patch(r.Context(), r.PathValue("id"), req)
+	if err != nil {
+		h.writeUserError(w, r, "patch user failed", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(toUserResponse(u))
+}
+
+// DeleteUser
+// @Summary Delete user
+// @Description Удаляет пользователя и его бюджеты. Подписки пользователя: policy=block (по умолчанию) — удаление запрещено (409), cascade — удаляются, reassign — передаются пользователю reassign_to. Только для администратора.
+// @Tags users
+// @Param  id  path  string  true  "User ID (UUID)"  example("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+// @Param  policy  query  string  false  "Subscriptions policy"  Enums(block, cascade, reassign)  default(block)
+// @Param  reassign_to  query  string  false  "Target user ID for policy=reassign (UUID)"
+// @Success  204  "No Content"
+// @Failure  400  {object}  map[string]string
+// @Failure  403  {object}  map[string]string
+// @Failure  404  {object}  map[string]string
+// @Failure  409  {object}  map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router  /api/users/{id}  [delete]
+func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if err := h.svc.Delete(r.Context(), r.PathValue("id"), q.Get("policy"), q.Get("reassign_to")); err != nil {
+		h.writeUserError(w, r, "delete user failed", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListUserSubscriptions
+// @Summary List user subscriptions
+// @Description Подписки пользователя с пагинацией
+// @Tags users
+// @Produce json
+// @Param  user_id  path  string  true  "User ID (UUID)"  example("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+// @Param  limit  query  int  false  "Page size (pagination.default_limit and pagination.max_limit, 20 and 100 by default)"  example(20)  default(20)
+// @Param  offset  query  int  false  "Offset (default 0)" example(0)  default(0)
+// @Success  200  {array}  models.SubscriptionResponse
+// @Failure  400  {object}  map[string]string
+// @Failure  403  {object}  map[string]string
+// @Failure  404  {object}  map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router  /api/users/{user_id}/subscriptions  [get]
+func (h *UserHandler) ListUserSubscriptions(w http.ResponseWriter, r *http.Request) {
+	limit, offset, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+
+	list, err := h.svc.Subscriptions(r.Context(), r.PathValue("user_id"), limit, offset)
+	if err != nil {
+		h.writeUserError(w, r, "list user subscriptions failed", err)
+		return
+	}
+
+	resp := make([]models.SubscriptionResponse, 0, len(list))
+	for i := range list {
+		resp = append(resp, toResponse(&list[i]))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// GetUserSummary
+// @Summary User summary
+// @Description Число активных подписок пользователя и его расходы в текущем месяце
+// @Tags users
+// @Produce json
+// @Param  user_id  path  string  true  "User ID (UUID)"  example("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+// @Success  200  {object}  models.UserSummary
+// @Failure  400  {object}  map[string]string
+// @Failure  403  {object}  map[string]string
+// @Failure  404  {object}  map[string]string
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router  /api/users/{user_id}/summary  [get]
+func (h *UserHandler) GetUserSummary(w http.ResponseWriter, r *http.Request) {
+	sum, err := h.svc.Summary(r.Context(), r.PathValue("user_id"))
+	if err != nil {
+		h.writeUserError(w, r, "user summary failed", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(sum)
+}
+
+func (h *UserHandler) writeUserError(w http.ResponseWriter, r *http.Request, msg string, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		writeError(w, http.StatusNotFound, "user not found")
+	case errors.Is(err, service.ErrForbidden):
+		writeError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrUserHasSubscriptions), errors.Is(err, service.ErrOverlap):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		h.log.ErrorContext(r.Context(), msg, "error", err)
+		writeError(w, http.StatusBadRequest, err.Error())
+	}
+}
+
+// parsePage — limit и offset из query; при ошибке ответ уже записан
+func parsePage(w http.ResponseWriter, r *http.Request) (limit, offset int, ok bool) {
+	q := r.URL.Query()
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "limit must be integer")
+			return 0, 0, false
+		}
+		limit = n
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "offset must be integer")
+			return 0, 0, false
+		}
+		offset = n
+	}
+	return limit, offset, true
+}
+
+func toUserResponse(u *models.User) models.UserResponse {
+	return models.UserResponse{
+		ID:    u.ID,
+		Name:  u.Name,
+		Email: u.Email,
+	}
+}
@@ -0,0 +1,81 @@
+package controller_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/olesia8novoselova/Subscriptions/internal/controller"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/olesia8novoselova/Subscriptions/internal/service"
+	"gorm.io/gorm"
+)
+
+type fakeUserService struct {
+	controller.UserService
+	GetByIDFn func(ctx context.Context, id string) (*models.User, error)
+	DeleteFn  func(ctx context.Context, id, policy, reassignTo string) error
+}
+
+func (f *fakeUserService) GetByID(ctx context.Context, id string) (*models.User, error) {
+	return f.GetByIDFn(ctx, id)
+}
+func (f *fakeUserService) Delete(ctx context.Context, id, policy, reassignTo string) error {
+	return f.DeleteFn(ctx, id, policy, reassignTo)
+}
+
+func userMux(h *controller.UserHandler) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/users/{id}", h.GetUser)
+	mux.HandleFunc("DELETE /api/users/{id}", h.DeleteUser)
+	return mux
+}
+
+// TestGetUser_NotFound - тестирует ответ 404 для несуществующего пользователя
+func TestGetUser_NotFound(t *testing.T) {
+	fs := &fakeUserService{
+		GetByIDFn: func(ctx context.Context, id string) (*models.User, error) {
+			return nil, gorm.ErrRecordNotFound
+		},
+	}
+	mux := userMux(controller.NewUserHandler(fs, newTestLogger()))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users/60601fee-2bf1-4721-ae6f-7636e79a0cba", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", w.Code)
+	}
+}
+
+// TestDeleteUser_Policy - тестирует передачу политики удаления и ответ 409 при оставшихся подписках
+func TestDeleteUser_Policy(t *testing.T) {
+	var gotPolicy, gotTarget string
+	fs := &fakeUserService{
+		DeleteFn: func(ctx context.Context, id, policy, reassignTo string) error {
+			gotPolicy, gotTarget = policy, reassignTo
+			if policy == "" {
+				return fmt.Errorf("%w: 2 subscription(s)", service.ErrUserHasSubscriptions)
+			}
+			return nil
+		},
+	}
+	mux := userMux(controller.NewUserHandler(fs, newTestLogger()))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/users/60601fee-2bf1-4721-ae6f-7636e79a0cba", nil))
+	if w.Code != http.StatusConflict {
+		t.Fatalf("block: status = %d, want 409", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	path := "/api/users/60601fee-2bf1-4721-ae6f-7636e79a0cba?policy=reassign&reassign_to=0a9b1c5e-6f0d-4b7e-8e2a-3c4d5e6f7a8b"
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, path, nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("reassign: status = %d, want 204", w.Code)
+	}
+	if gotPolicy != models.UserDeleteReassign || gotTarget != "0a9b1c5e-6f0d-4b7e-8e2a-3c4d5e6f7a8b" {
+		t.Fatalf("unexpected args: policy=%q reassign_to=%q", gotPolicy, gotTarget)
+	}
+}
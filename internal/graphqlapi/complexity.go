@@ -0,0 +1,128 @@
+package graphqlapi
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+const (
+	// defaultLimit — размер страницы списков без limit, как в REST API. Максимум страницы задаётся
+	// конфигурацией (pagination.max_limit), поэтому оценка берёт limit как есть
+	defaultLimit = 20
+	// costCap — потолок оценки стоимости: больше любого лимита сложности, и произведение
+	// двух таких значений не переполняет int
+	costCap = math.MaxInt32
+	// nestedListSize — оценка числа элементов во вложенном списке без limit (User.subscriptions)
+	nestedListSize = 10
+	// MaxDepth — максимальная вложенность полей запроса
+	MaxDepth = 8
+)
+
+// listFields — поля-списки; их стоимость умножается на число элементов
+var listFields = map[string]bool{
+	"subscriptions": true,
+	"users":         true,
+}
+
+var errNoOperation = errors.New("operation not found")
+
+// complexity — оценка стоимости операции: каждое поле стоит 1, а поле-список умножает стоимость
+// выбранных в нём полей на limit (для вложенных списков — на nestedListSize).
+// Также возвращает глубину вложенности.
+func complexity(doc *ast.Document, operationName string, vars map[string]any) (cost, depth int, err error) {
+	var op *ast.OperationDefinition
+	fragments := map[string]*ast.FragmentDefinition{}
+	for _, def := range doc.Definitions {
+		switch d := def.(type) {
+		case *ast.OperationDefinition:
+			if operationName == "" || (d.Name != nil && d.Name.Value == operationName) {
+				if op != nil && operationName == "" {
+					return 0, 0, errors.New("operationName is required for a document with several operations")
+				}
+				op = d
+			}
+		case *ast.FragmentDefinition:
+			fragments[d.Name.Value] = d
+		}
+	}
+	if op == nil {
+		return 0, 0, errNoOperation
+	}
+
+	c := &costCounter{fragments: fragments, vars: vars}
+	cost, depth = c.selectionSet(op.SelectionSet, 1, map[string]bool{})
+	return cost, depth, nil
+}
+
+type costCounter struct {
+	fragments map[string]*ast.FragmentDefinition
+	vars      map[string]any
+}
+
+// selectionSet — стоимость и глубина набора полей; visiting защищает от циклов во фрагментах
+func (c *costCounter) selectionSet(set *ast.SelectionSet, level int, visiting map[string]bool) (cost, depth int) {
+	if set == nil || level > MaxDepth+1 {
+		return 0, 0
+	}
+	for _, sel := range set.Selections {
+		var (
+			fc, fd int
+		)
+		switch s := sel.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name.Value, "__") {
+				// интроспекция не обращается к данным и не ограничивается
+				continue
+			}
+			childCost, childDepth := c.selectionSet(s.SelectionSet, level+1, visiting)
+			fc = min(1+c.listSize(s, level)*childCost, costCap)
+			fd = 1 + childDepth
+		case *ast.InlineFragment:
+			fc, fd = c.selectionSet(s.SelectionSet, level, visiting)
+		case *ast.FragmentSpread:
+			name := s.Name.Value
+			frag, ok := c.fragments[name]
+			if !ok || visiting[name] {
+				continue
+			}
+			visiting[name] = true
+			fc, fd = c.selectionSet(frag.SelectionSet, level, visiting)
+			delete(visiting, name)
+		}
+		cost = min(cost+fc, costCap)
+		depth = max(depth, fd)
+	}
+	return cost, depth
+}
+
+// listSize — множитель стоимости поля: 1 для скаляров и объектов, число элементов для списков
+func (c *costCounter) listSize(f *ast.Field, level int) int {
+	if !listFields[f.Name.Value] {
+		return 1
+	}
+	if level > 1 {
+		return nestedListSize
+	}
+	n := defaultLimit
+	for _, arg := range f.Arguments {
+		if arg.Name.Value != "limit" {
+			continue
+		}
+		switch v := arg.Value.(type) {
+		case *ast.IntValue:
+			_, _ = fmt.Sscan(v.Value, &n)
+		case *ast.Variable:
+			if f, ok := c.vars[v.Name.Value].(float64); ok {
+				n = int(min(f, costCap))
+			}
+		}
+	}
+	if n <= 0 {
+		n = defaultLimit
+	}
+	return min(n, costCap)
+}
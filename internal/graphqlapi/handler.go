@@ -0,0 +1,114 @@
+package graphqlapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// maxBodyBytes — максимальный размер тела POST-запроса
+const maxBodyBytes = 1 << 20
+
+type Handler struct {
+	schema        graphql.Schema
+	subs          SubscriptionService
+	log           *slog.Logger
+	maxComplexity int
+}
+
+// NewHandler — обработчик /graphql; запросы дороже maxComplexity (см. complexity) отклоняются
+func NewHandler(subs SubscriptionService, users UserService, log *slog.Logger, maxComplexity int) (*Handler, error) {
+	schema, err := NewSchema(subs, users, log)
+	if err != nil {
+		return nil, fmt.Errorf("graphql schema: %w", err)
+	}
+	return &Handler{schema: schema, subs: subs, log: log, maxComplexity: maxComplexity}, nil
+}
+
+type request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// ServeHTTP
+// @Summary GraphQL query
+// @Description Запрос GraphQL (POST с JSON-телом или GET с параметрами query, operationName, variables). Схема: подписки, пользователи, totalCost и userSummary. Запросы глубже 8 уровней или дороже GRAPHQL_MAX_COMPLEXITY отклоняются.
+// @Tags graphql
+// @Accept json
+// @Produce json
+// @Param  request  body  object  true  "{query, operationName, variables}"
+// @Success  200  {object}  map[string]any
+// @Failure  400  {object}  map[string]any
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router  /graphql  [post]
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req request
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		req.Query = q.Get("query")
+		req.OperationName = q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				writeErrors(w, http.StatusBadRequest, "variables must be a JSON object")
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&req); err != nil {
+			writeErrors(w, http.StatusBadRequest, "invalid JSON")
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeErrors(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if req.Query == "" {
+		writeErrors(w, http.StatusBadRequest, "query is required")
+		return
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"})})
+	if err != nil {
+		writeErrors(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	cost, depth, err := complexity(doc, req.OperationName, req.Variables)
+	if err != nil {
+		writeErrors(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if depth > MaxDepth {
+		writeErrors(w, http.StatusBadRequest, fmt.Sprintf("query depth %d exceeds limit %d", depth, MaxDepth))
+		return
+	}
+	if cost > h.maxComplexity {
+		h.log.Warn("graphql query rejected", "complexity", cost, "limit", h.maxComplexity)
+		writeErrors(w, http.StatusBadRequest, fmt.Sprintf("query complexity %d exceeds limit %d", cost, h.maxComplexity))
+		return
+	}
+
+	res := graphql.Do(graphql.Params{
+		Schema:         h.schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        withLoader(r.Context(), h.subs),
+	})
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func writeErrors(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]any{"errors": []map[string]string{{"message": msg}}})
+}
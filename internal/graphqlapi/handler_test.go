@@ -0,0 +1,101 @@
+package graphqlapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/graphqlapi"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSubs struct {
+	graphqlapi.SubscriptionService
+	batches [][]uuid.UUID
+}
+
+func (f *fakeSubs) ListByUsers(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID][]models.Subscription, error) {
+	f.batches = append(f.batches, userIDs)
+	res := map[uuid.UUID][]models.Subscription{}
+	for _, id := range userIDs {
+		res[id] = []models.Subscription{{ID: uuid.New(), UserID: id, ServiceName: "Netflix", Price: 500}}
+	}
+	return res, nil
+}
+
+type fakeUsers struct {
+	graphqlapi.UserService
+	users []models.User
+}
+
+func (f *fakeUsers) List(ctx context.Context, limit, offset int) ([]models.User, error) {
+	return f.users, nil
+}
+
+func query(t *testing.T, h http.Handler, q string) (int, map[string]any) {
+	t.Helper()
+	body, _ := json.Marshal(map[string]string{"query": q})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body))))
+	var res map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("invalid response: %s", w.Body.String())
+	}
+	return w.Code, res
+}
+
+// TestHandler_BatchesUserSubscriptions - подписки списка пользователей загружаются одним запросом
+func TestHandler_BatchesUserSubscriptions(t *testing.T) {
+	subs := &fakeSubs{}
+	users := &fakeUsers{users: []models.User{{ID: uuid.New(), Name: "Alice"}, {ID: uuid.New(), Name: "Bob"}, {ID: uuid.New(), Name: "Carol"}}}
+	h, err := graphqlapi.NewHandler(subs, users, slog.New(slog.NewTextHandler(io.Discard, nil)), 1000)
+	assert.NoError(t, err)
+
+	code, res := query(t, h, `{ users { name subscriptions { serviceName price } } }`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Nil(t, res["errors"])
+	list := res["data"].(map[string]any)["users"].([]any)
+	assert.Len(t, list, 3)
+	first := list[0].(map[string]any)["subscriptions"].([]any)[0].(map[string]any)
+	assert.Equal(t, "Netflix", first["serviceName"])
+
+	assert.Len(t, subs.batches, 1)
+	assert.Len(t, subs.batches[0], 3)
+}
+
+// TestHandler_Limits - слишком дорогие и слишком глубокие запросы отклоняются до выполнения
+func TestHandler_Limits(t *testing.T) {
+	subs := &fakeSubs{}
+	h, err := graphqlapi.NewHandler(subs, &fakeUsers{}, slog.New(slog.NewTextHandler(io.Discard, nil)), 500)
+	assert.NoError(t, err)
+
+	// 1 + 100 * (1 + 1 + 10 * 2) = 2201
+	code, res := query(t, h, `{ users(limit: 100) { name subscriptions { id serviceName } } }`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, res["errors"].([]any)[0].(map[string]any)["message"], "complexity 2201 exceeds limit 500")
+
+	// limit больше 100 (pagination.max_limit бывает больше) учитывается полностью: 1 + 1000 * 1
+	code, res = query(t, h, `{ users(limit: 1000) { name } }`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, res["errors"].([]any)[0].(map[string]any)["message"], "complexity 1001 exceeds limit 500")
+
+	// огромный limit не переполняет оценку
+	code, _ = query(t, h, `{ a: users(limit: 2147483647) { subscriptions { id } } b: users(limit: 2147483647) { subscriptions { id } } }`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = query(t, h, `fragment F on Subscription { id } { users(limit: 5) { subscriptions { ...F } } }`)
+	assert.Equal(t, http.StatusOK, code)
+
+	// алиасы складываются: 3 * (1 + 20 * (1 + 10 * 1)) = 663
+	batches := len(subs.batches)
+	code, _ = query(t, h, `{ a: users { subscriptions { id } } b: users { subscriptions { id } } c: users { subscriptions { id } } }`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Len(t, subs.batches, batches)
+}
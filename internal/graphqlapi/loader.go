@@ -0,0 +1,56 @@
+package graphqlapi
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+)
+
+type loaderKey struct{}
+
+// subscriptionLoader — пакетная загрузка User.subscriptions в пределах одного запроса.
+// Резолвер регистрирует user_id и возвращает thunk; исполнитель GraphQL вызывает thunk'и
+// после того, как разрешены все соседние поля, поэтому первый из них загружает подписки
+// всех накопленных пользователей одним вызовом ListByUsers.
+type subscriptionLoader struct {
+	svc SubscriptionService
+
+	mu      sync.Mutex
+	pending []uuid.UUID
+	loaded  map[uuid.UUID][]models.Subscription
+}
+
+func withLoader(ctx context.Context, svc SubscriptionService) context.Context {
+	return context.WithValue(ctx, loaderKey{}, &subscriptionLoader{svc: svc, loaded: map[uuid.UUID][]models.Subscription{}})
+}
+
+func loaderFrom(ctx context.Context) *subscriptionLoader {
+	return ctx.Value(loaderKey{}).(*subscriptionLoader)
+}
+
+func (l *subscriptionLoader) load(ctx context.Context, userID uuid.UUID, wrap func(error) error) func() (any, error) {
+	l.mu.Lock()
+	if _, ok := l.loaded[userID]; !ok {
+		l.pending = append(l.pending, userID)
+	}
+	l.mu.Unlock()
+
+	return func() (any, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if len(l.pending) > 0 {
+			batch := l.pending
+			l.pending = nil
+			res, err := l.svc.ListByUsers(ctx, batch)
+			if err != nil {
+				return nil, wrap(err)
+			}
+			for _, id := range batch {
+				l.loaded[id] = res[id]
+			}
+		}
+		return subscriptionPtrs(l.loaded[userID]), nil
+	}
+}
@@ -0,0 +1,249 @@
+// Package graphqlapi — GraphQL-эндпоинт /graphql для выборок подписок, пользователей и расходов.
+package graphqlapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/olesia8novoselova/Subscriptions/internal/service"
+	"gorm.io/gorm"
+)
+
+type SubscriptionService interface {
+	GetByID(ctx context.Context, id string) (*models.Subscription, error)
+	List(ctx context.Context, userID, serviceName string, limit, offset int) ([]models.Subscription, error)
+	ListByUsers(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID][]models.Subscription, error)
+	TotalCost(ctx context.Context, from, to, userID, serviceName string) (int, error)
+}
+
+type UserService interface {
+	GetByID(ctx context.Context, id string) (*models.User, error)
+	List(ctx context.Context, limit, offset int) ([]models.User, error)
+	Summary(ctx context.Context, id string) (*models.UserSummary, error)
+}
+
+// resolver — резолверы полей схемы
+type resolver struct {
+	subs  SubscriptionService
+	users UserService
+	log   *slog.Logger
+}
+
+// NewSchema — схема GraphQL: подписки, пользователи и агрегаты расходов
+func NewSchema(subs SubscriptionService, users UserService, log *slog.Logger) (graphql.Schema, error) {
+	r := &resolver{subs: subs, users: users, log: log}
+
+	subscriptionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Subscription",
+		Fields: graphql.Fields{
+			"id":          subField(graphql.NewNonNull(graphql.ID), func(s *models.Subscription) any { return s.ID.String() }),
+			"serviceName": subField(graphql.NewNonNull(graphql.String), func(s *models.Subscription) any { return s.ServiceName }),
+			"price":       subField(graphql.NewNonNull(graphql.Int), func(s *models.Subscription) any { return s.Price }),
+			"userId":      subField(graphql.NewNonNull(graphql.ID), func(s *models.Subscription) any { return s.UserID.String() }),
+			"startDate":   subField(graphql.NewNonNull(graphql.String), func(s *models.Subscription) any { return s.StartDate.Format("01-2006") }),
+			"endDate": subField(graphql.String, func(s *models.Subscription) any {
+				if s.EndDate == nil {
+					return nil
+				}
+				return s.EndDate.Format("01-2006")
+			}),
+			"category": subField(graphql.NewNonNull(graphql.String), func(s *models.Subscription) any { return s.Category }),
+			"version":  subField(graphql.NewNonNull(graphql.Int), func(s *models.Subscription) any { return s.Version }),
+		},
+	})
+
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"id":   userField(graphql.NewNonNull(graphql.ID), func(u *models.User) any { return u.ID.String() }),
+			"name": userField(graphql.NewNonNull(graphql.String), func(u *models.User) any { return u.Name }),
+			"email": userField(graphql.String, func(u *models.User) any {
+				if u.Email == nil {
+					return nil
+				}
+				return *u.Email
+			}),
+			"subscriptions": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(subscriptionType))),
+				Description: "Подписки пользователя; для списка пользователей загружаются одним запросом",
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					u := p.Source.(*models.User)
+					return loaderFrom(p.Context).load(p.Context, u.ID, r.wrap), nil
+				},
+			},
+		},
+	})
+
+	summaryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "UserSummary",
+		Fields: graphql.Fields{
+			"userId":              summaryField(graphql.NewNonNull(graphql.ID), func(s *models.UserSummary) any { return s.UserID.String() }),
+			"name":                summaryField(graphql.NewNonNull(graphql.String), func(s *models.UserSummary) any { return s.Name }),
+			"month":               summaryField(graphql.NewNonNull(graphql.String), func(s *models.UserSummary) any { return s.Month }),
+			"activeSubscriptions": summaryField(graphql.NewNonNull(graphql.Int), func(s *models.UserSummary) any { return s.ActiveSubscriptions }),
+			"monthlySpend":        summaryField(graphql.NewNonNull(graphql.Int), func(s *models.UserSummary) any { return s.MonthlySpend }),
+		},
+	})
+
+	page := graphql.FieldConfigArgument{
+		"limit":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultLimit},
+		"offset": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+	}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"subscription": &graphql.Field{
+				Type: subscriptionType,
+				Args: graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					sub, err := r.subs.GetByID(p.Context, p.Args["id"].(string))
+					if errors.Is(err, gorm.ErrRecordNotFound) {
+						return nil, nil
+					}
+					if err != nil {
+						return nil, r.wrap(err)
+					}
+					return sub, nil
+				},
+			},
+			"subscriptions": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(subscriptionType))),
+				Args: withArgs(page, graphql.FieldConfigArgument{
+					"userId":      &graphql.ArgumentConfig{Type: graphql.ID},
+					"serviceName": &graphql.ArgumentConfig{Type: graphql.String},
+				}),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					list, err := r.subs.List(p.Context, stringArg(p, "userId"), stringArg(p, "serviceName"), intArg(p, "limit"), intArg(p, "offset"))
+					if err != nil {
+						return nil, r.wrap(err)
+					}
+					return subscriptionPtrs(list), nil
+				},
+			},
+			"totalCost": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "Суммарная стоимость за период [from; to] (MM-YYYY), как GET /api/subscriptions/total",
+				Args: graphql.FieldConfigArgument{
+					"from":        &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"to":          &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"userId":      &graphql.ArgumentConfig{Type: graphql.ID},
+					"serviceName": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					total, err := r.subs.TotalCost(p.Context, stringArg(p, "from"), stringArg(p, "to"), stringArg(p, "userId"), stringArg(p, "serviceName"))
+					if err != nil {
+						return nil, r.wrap(err)
+					}
+					return total, nil
+				},
+			},
+			"user": &graphql.Field{
+				Type: userType,
+				Args: graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					u, err := r.users.GetByID(p.Context, p.Args["id"].(string))
+					if errors.Is(err, gorm.ErrRecordNotFound) {
+						return nil, nil
+					}
+					if err != nil {
+						return nil, r.wrap(err)
+					}
+					return u, nil
+				},
+			},
+			"users": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(userType))),
+				Args: page,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					list, err := r.users.List(p.Context, intArg(p, "limit"), intArg(p, "offset"))
+					if err != nil {
+						return nil, r.wrap(err)
+					}
+					res := make([]*models.User, 0, len(list))
+					for i := range list {
+						res = append(res, &list[i])
+					}
+					return res, nil
+				},
+			},
+			"userSummary": &graphql.Field{
+				Type:        summaryType,
+				Description: "Активные подписки и расходы пользователя в текущем месяце",
+				Args:        graphql.FieldConfigArgument{"userId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					sum, err := r.users.Summary(p.Context, p.Args["userId"].(string))
+					if errors.Is(err, gorm.ErrRecordNotFound) {
+						return nil, nil
+					}
+					if err != nil {
+						return nil, r.wrap(err)
+					}
+					return sum, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query})
+}
+
+// wrap — ошибки валидации и доступа возвращаются клиенту как есть, остальные логируются и скрываются
+func (r *resolver) wrap(err error) error {
+	if errors.Is(err, service.ErrValidation) || errors.Is(err, service.ErrForbidden) {
+		return err
+	}
+	r.log.Error("graphql resolver failed", "error", err)
+	return errors.New("internal error")
+}
+
+func subField(t graphql.Output, get func(*models.Subscription) any) *graphql.Field {
+	return &graphql.Field{Type: t, Resolve: func(p graphql.ResolveParams) (any, error) {
+		return get(p.Source.(*models.Subscription)), nil
+	}}
+}
+
+func userField(t graphql.Output, get func(*models.User) any) *graphql.Field {
+	return &graphql.Field{Type: t, Resolve: func(p graphql.ResolveParams) (any, error) {
+		return get(p.Source.(*models.User)), nil
+	}}
+}
+
+func summaryField(t graphql.Output, get func(*models.UserSummary) any) *graphql.Field {
+	return &graphql.Field{Type: t, Resolve: func(p graphql.ResolveParams) (any, error) {
+		return get(p.Source.(*models.UserSummary)), nil
+	}}
+}
+
+func withArgs(a, b graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+	res := make(graphql.FieldConfigArgument, len(a)+len(b))
+	for k, v := range a {
+		res[k] = v
+	}
+	for k, v := range b {
+		res[k] = v
+	}
+	return res
+}
+
+func stringArg(p graphql.ResolveParams, name string) string {
+	v, _ := p.Args[name].(string)
+	return v
+}
+
+func intArg(p graphql.ResolveParams, name string) int {
+	v, _ := p.Args[name].(int)
+	return v
+}
+
+func subscriptionPtrs(list []models.Subscription) []*models.Subscription {
+	res := make([]*models.Subscription, 0, len(list))
+	for i := range list {
+		res = append(res, &list[i])
+	}
+	return res
+}
@@ -0,0 +1,94 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/olesia8novoselova/Subscriptions/internal/auth"
+	"github.com/olesia8novoselova/Subscriptions/internal/tenant"
+	"github.com/olesia8novoselova/Subscriptions/pkg/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Ключи метаданных повторяют HTTP-заголовки REST API
+const (
+	mdAuthorization = "authorization"
+	mdAPIKey        = "x-api-key"
+	mdTenantID      = "x-tenant-id"
+	mdRequestID     = "x-request-id"
+)
+
+// UnaryInterceptor — ID запроса, аутентификация и выбор арендатора по метаданным, как в HTTP-middleware.
+// a == nil отключает аутентификацию.
+func UnaryInterceptor(log *slog.Logger, a *auth.Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authorize(ctx, log, a, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor — то же, что UnaryInterceptor, для потоковых методов
+func StreamInterceptor(log *slog.Logger, a *auth.Authenticator) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authorize(ss.Context(), log, a, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func authorize(ctx context.Context, log *slog.Logger, a *auth.Authenticator, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	requestID := logging.NewRequestID(first(md, mdRequestID))
+	_ = grpc.SetHeader(ctx, metadata.Pairs(mdRequestID, requestID))
+	ctx = logging.WithRequestID(ctx, requestID)
+	ctx = logging.WithLogger(ctx, log.With("grpc_method", method))
+
+	if a != nil {
+		p, err := a.AuthenticateCredentials(ctx, first(md, mdAPIKey), first(md, mdAuthorization))
+		if err != nil && !errors.Is(err, auth.ErrUnauthenticated) {
+			log.ErrorContext(ctx, "grpc authentication failed", "method", method, "error", err)
+			return nil, status.Error(codes.Internal, "internal error")
+		}
+		if err != nil {
+			log.WarnContext(ctx, "unauthenticated grpc request", "method", method, "error", err)
+			return nil, status.Error(codes.Unauthenticated, "authentication required")
+		}
+		ctx = auth.WithPrincipal(ctx, p)
+	}
+
+	id, err := tenant.Resolve(ctx, first(md, mdTenantID))
+	switch {
+	case errors.Is(err, tenant.ErrInvalidID):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+	return tenant.WithTenant(ctx, id), nil
+}
+
+func first(md metadata.MD, key string) string {
+	if v := md.Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// serverStream — поток с контекстом, дополненным principal и арендатором
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,190 @@
+// Package grpcapi — gRPC-сервер с теми же операциями над подписками, что и REST API.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	subscriptionsv1 "github.com/olesia8novoselova/Subscriptions/api/subscriptions/v1"
+	"github.com/olesia8novoselova/Subscriptions/internal/auth"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/olesia8novoselova/Subscriptions/internal/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// streamPageSize — запрашиваемый размер страницы StreamSubscriptions; сервис может вернуть
+// меньше (pagination.max_limit), поэтому поток заканчивается на пустой странице
+const streamPageSize = 100
+
+type SubscriptionService interface {
+	Create(ctx context.Context, req models.CreateSubscriptionRequest) (*models.Subscription, error)
+	GetByID(ctx context.Context, id string) (*models.Subscription, error)
+	List(ctx context.Context, userID, serviceName string, limit, offset int) ([]models.Subscription, error)
+	Delete(ctx context.Context, id string, ifMatch int) error
+	Patch(ctx context.Context, id string, req models.UpdateSubscriptionRequest, ifMatch int) (*models.Subscription, error)
+	TotalCost(ctx context.Context, from, to, userID, serviceName string) (int, error)
+}
+
+type Server struct {
+	subscriptionsv1.UnimplementedSubscriptionServiceServer
+
+	svc SubscriptionService
+	log *slog.Logger
+}
+
+func NewServer(svc SubscriptionService, log *slog.Logger) *Server {
+	return &Server{svc: svc, log: log}
+}
+
+// New — gRPC-сервер с зарегистрированным SubscriptionService.
+// authenticator == nil отключает аутентификацию (AUTH_DISABLED).
+func New(svc SubscriptionService, log *slog.Logger, authenticator *auth.Authenticator) *grpc.Server {
+	gs := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(UnaryInterceptor(log, authenticator)),
+		grpc.ChainStreamInterceptor(StreamInterceptor(log, authenticator)),
+	)
+	subscriptionsv1.RegisterSubscriptionServiceServer(gs, NewServer(svc, log))
+	return gs
+}
+
+func (s *Server) CreateSubscription(ctx context.Context, req *subscriptionsv1.CreateSubscriptionRequest) (*subscriptionsv1.Subscription, error) {
+	sub, err := s.svc.Create(ctx, models.CreateSubscriptionRequest{
+		ServiceName: req.GetServiceName(),
+		Price:       int(req.GetPrice()),
+		UserID:      req.GetUserId(),
+		StartDate:   req.GetStartDate(),
+		EndDate:     req.EndDate,
+		Category:    req.GetCategory(),
+	})
+	if err != nil {
+		return nil, s.toStatus(ctx, "create subscription failed", err)
+	}
+	return toProto(sub), nil
+}
+
+func (s *Server) GetSubscription(ctx context.Context, req *subscriptionsv1.GetSubscriptionRequest) (*subscriptionsv1.Subscription, error) {
+	sub, err := s.svc.GetByID(ctx, req.GetId())
+	if err != nil {
+		return nil, s.toStatus(ctx, "get subscription failed", err)
+	}
+	return toProto(sub), nil
+}
+
+func (s *Server) ListSubscriptions(ctx context.Context, req *subscriptionsv1.ListSubscriptionsRequest) (*subscriptionsv1.ListSubscriptionsResponse, error) {
+	list, err := s.svc.List(ctx, req.GetUserId(), req.GetServiceName(), int(req.GetLimit()), int(req.GetOffset()))
+	if err != nil {
+		return nil, s.toStatus(ctx, "list subscriptions failed", err)
+	}
+	resp := &subscriptionsv1.ListSubscriptionsResponse{Subscriptions: make([]*subscriptionsv1.Subscription, 0, len(list))}
+	for i := range list {
+		resp.Subscriptions = append(resp.Subscriptions, toProto(&list[i]))
+	}
+	return resp, nil
+}
+
+// StreamSubscriptions — отправляет подписки постранично, пока они не закончатся
+func (s *Server) StreamSubscriptions(req *subscriptionsv1.StreamSubscriptionsRequest, stream grpc.ServerStreamingServer[subscriptionsv1.Subscription]) error {
+	ctx := stream.Context()
+	for offset := 0; ; {
+		list, err := s.svc.List(ctx, req.GetUserId(), req.GetServiceName(), streamPageSize, offset)
+		if err != nil {
+			return s.toStatus(ctx, "stream subscriptions failed", err)
+		}
+		if len(list) == 0 {
+			return nil
+		}
+		for i := range list {
+			if err := stream.Send(toProto(&list[i])); err != nil {
+				return err
+			}
+		}
+		offset += len(list)
+	}
+}
+
+func (s *Server) PatchSubscription(ctx context.Context, req *subscriptionsv1.PatchSubscriptionRequest) (*subscriptionsv1.Subscription, error) {
+	upd := models.UpdateSubscriptionRequest{
+		ServiceName: req.ServiceName,
+		StartDate:   req.StartDate,
+		EndDate:     req.EndDate,
+		Category:    req.Category,
+	}
+	if req.Price != nil {
+		price := int(req.GetPrice())
+		upd.Price = &price
+	}
+	sub, err := s.svc.Patch(ctx, req.GetId(), upd, int(req.GetIfMatch()))
+	if err != nil {
+		return nil, s.toStatus(ctx, "patch subscription failed", err)
+	}
+	return toProto(sub), nil
+}
+
+func (s *Server) DeleteSubscription(ctx context.Context, req *subscriptionsv1.DeleteSubscriptionRequest) (*subscriptionsv1.DeleteSubscriptionResponse, error) {
+	if err := s.svc.Delete(ctx, req.GetId(), int(req.GetIfMatch())); err != nil {
+		return nil, s.toStatus(ctx, "delete subscription failed", err)
+	}
+	return &subscriptionsv1.DeleteSubscriptionResponse{}, nil
+}
+
+func (s *Server) GetTotalCost(ctx context.Context, req *subscriptionsv1.GetTotalCostRequest) (*subscriptionsv1.GetTotalCostResponse, error) {
+	total, err := s.svc.TotalCost(ctx, req.GetFrom(), req.GetTo(), req.GetUserId(), req.GetServiceName())
+	if err != nil {
+		return nil, s.toStatus(ctx, "total cost failed", err)
+	}
+	return &subscriptionsv1.GetTotalCostResponse{Total: int64(total)}, nil
+}
+
+// toStatus — код gRPC по доменной ошибке; непредвиденные ошибки логируются и скрываются от клиента
+func (s *Server) toStatus(ctx context.Context, msg string, err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return status.Error(codes.NotFound, "subscription not found")
+	case errors.Is(err, service.ErrValidation):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, service.ErrForbidden):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, service.ErrOverlap):
+		return status.Error(codes.AlreadyExists, "subscription overlaps with existing one for this user and service")
+	case errors.Is(err, service.ErrIDConflict):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, service.ErrPreconditionFailed), errors.Is(err, service.ErrBudgetExceeded):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	s.log.ErrorContext(ctx, msg, "error", err)
+	return status.Error(codes.Internal, "internal error")
+}
+
+func toProto(s *models.Subscription) *subscriptionsv1.Subscription {
+	out := &subscriptionsv1.Subscription{
+		Id:          s.ID.String(),
+		ServiceName: s.ServiceName,
+		Price:       int64(s.Price),
+		UserId:      s.UserID.String(),
+		StartDate:   s.StartDate.Format("01-2006"),
+		Category:    s.Category,
+		Version:     int32(s.Version),
+	}
+	if s.EndDate != nil {
+		end := s.EndDate.Format("01-2006")
+		out.EndDate = &end
+	}
+	for _, a := range s.BudgetAlerts {
+		out.BudgetAlerts = append(out.BudgetAlerts, &subscriptionsv1.BudgetAlert{
+			BudgetId:     a.BudgetID.String(),
+			Month:        a.Month,
+			MonthlyLimit: int64(a.MonthlyLimit),
+			Spent:        int64(a.Spent),
+			Mode:         a.Mode,
+		})
+	}
+	return out
+}
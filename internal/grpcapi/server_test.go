@@ -0,0 +1,133 @@
+package grpcapi_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	subscriptionsv1 "github.com/olesia8novoselova/Subscriptions/api/subscriptions/v1"
+	"github.com/olesia8novoselova/Subscriptions/internal/auth"
+	"github.com/olesia8novoselova/Subscriptions/internal/grpcapi"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/olesia8novoselova/Subscriptions/internal/service"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"gorm.io/gorm"
+)
+
+type fakeService struct {
+	grpcapi.SubscriptionService
+	createErr error
+	getErr    error
+	total     int
+	// maxLimit — урезает страницу List, как pagination.max_limit меньше запрошенного
+	maxLimit int
+}
+
+func (f *fakeService) Create(ctx context.Context, req models.CreateSubscriptionRequest) (*models.Subscription, error) {
+	if f.createErr != nil {
+		return nil, f.createErr
+	}
+	return &models.Subscription{
+		ID:          uuid.New(),
+		ServiceName: req.ServiceName,
+		Price:       req.Price,
+		StartDate:   time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC),
+		Version:     1,
+	}, nil
+}
+
+func (f *fakeService) GetByID(ctx context.Context, id string) (*models.Subscription, error) {
+	return nil, f.getErr
+}
+
+func (f *fakeService) List(ctx context.Context, userID, serviceName string, limit, offset int) ([]models.Subscription, error) {
+	if f.maxLimit > 0 {
+		limit = min(limit, f.maxLimit)
+	}
+	n := min(limit, f.total-offset)
+	res := make([]models.Subscription, max(n, 0))
+	for i := range res {
+		res[i] = models.Subscription{ID: uuid.New(), ServiceName: serviceName}
+	}
+	return res, nil
+}
+
+func dial(t *testing.T, svc grpcapi.SubscriptionService, a *auth.Authenticator) subscriptionsv1.SubscriptionServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	gs := grpcapi.New(svc, slog.New(slog.NewTextHandler(io.Discard, nil)), a)
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return subscriptionsv1.NewSubscriptionServiceClient(conn)
+}
+
+// TestServer_StatusCodes - тестирует соответствие доменных ошибок кодам gRPC
+func TestServer_StatusCodes(t *testing.T) {
+	tests := []struct {
+		err  error
+		want codes.Code
+	}{
+		{nil, codes.OK},
+		{fmt.Errorf("%w: price must be positive integer", service.ErrValidation), codes.InvalidArgument},
+		{service.ErrOverlap, codes.AlreadyExists},
+		{service.ErrBudgetExceeded, codes.FailedPrecondition},
+		{fmt.Errorf("%w: access to another user's data", service.ErrForbidden), codes.PermissionDenied},
+		{fmt.Errorf("db error: connection refused"), codes.Internal},
+	}
+	for _, tt := range tests {
+		client := dial(t, &fakeService{createErr: tt.err}, nil)
+		sub, err := client.CreateSubscription(context.Background(), &subscriptionsv1.CreateSubscriptionRequest{ServiceName: "Netflix", Price: 500})
+		assert.Equal(t, tt.want, status.Code(err), "error %v", tt.err)
+		if tt.err == nil {
+			assert.Equal(t, "07-2025", sub.GetStartDate())
+			assert.Equal(t, int64(500), sub.GetPrice())
+		}
+	}
+
+	client := dial(t, &fakeService{getErr: gorm.ErrRecordNotFound}, nil)
+	_, err := client.GetSubscription(context.Background(), &subscriptionsv1.GetSubscriptionRequest{Id: uuid.NewString()})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+// TestServer_StreamSubscriptions - поток отдаёт все подписки, читая их постранично,
+// в том числе когда сервис урезает страницу
+func TestServer_StreamSubscriptions(t *testing.T) {
+	for _, maxLimit := range []int{0, 40} {
+		client := dial(t, &fakeService{total: 250, maxLimit: maxLimit}, nil)
+
+		stream, err := client.StreamSubscriptions(context.Background(), &subscriptionsv1.StreamSubscriptionsRequest{ServiceName: "Netflix"})
+		assert.NoError(t, err)
+		n := 0
+		for {
+			sub, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, "Netflix", sub.GetServiceName())
+			n++
+		}
+		assert.Equal(t, 250, n, "max limit %d", maxLimit)
+	}
+}
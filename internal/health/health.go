@@ -0,0 +1,88 @@
+// Package health — пробы живости (/healthz) и готовности (/readyz).
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+	StatusShutdown    = "shutting_down"
+)
+
+// Check — проверка зависимости; details попадают в ответ /readyz рядом со статусом проверки
+type Check func(ctx context.Context) (details map[string]any, err error)
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Probe — набор проверок готовности. После Shutdown готовность не проходит,
+// чтобы балансировщик перестал направлять запросы, пока сервер дорабатывает текущие.
+type Probe struct {
+	timeout      time.Duration
+	checks       []namedCheck
+	shuttingDown atomic.Bool
+}
+
+// New — timeout ограничивает время всех проверок одного запроса к /readyz
+func New(timeout time.Duration) *Probe {
+	return &Probe{timeout: timeout}
+}
+
+// Add — регистрирует проверку; вызывается до начала обслуживания запросов
+func (p *Probe) Add(name string, check Check) {
+	p.checks = append(p.checks, namedCheck{name: name, check: check})
+}
+
+// Shutdown — переводит пробу готовности в состояние остановки
+func (p *Probe) Shutdown() {
+	p.shuttingDown.Store(true)
+}
+
+// Live — процесс жив и обслуживает HTTP; зависимости не проверяются
+func (p *Probe) Live(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok"))
+}
+
+// Ready — 200, если все проверки прошли, иначе 503 с результатом каждой проверки
+func (p *Probe) Ready(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), p.timeout)
+	defer cancel()
+
+	status := StatusOK
+	checks := make(map[string]map[string]any, len(p.checks))
+	for _, c := range p.checks {
+		details, err := c.check(ctx)
+		res := map[string]any{}
+		for k, v := range details {
+			res[k] = v
+		}
+		res["status"] = StatusOK
+		if err != nil {
+			res["status"] = StatusUnavailable
+			res["error"] = err.Error()
+			status = StatusUnavailable
+		}
+		checks[c.name] = res
+	}
+	if p.shuttingDown.Load() {
+		status = StatusShutdown
+	}
+
+	code := http.StatusOK
+	if status != StatusOK {
+		code = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]any{"status": status, "checks": checks})
+}
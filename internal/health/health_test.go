@@ -0,0 +1,62 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/olesia8novoselova/Subscriptions/internal/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type readyBody struct {
+	Status string                    `json:"status"`
+	Checks map[string]map[string]any `json:"checks"`
+}
+
+func ready(t *testing.T, p *health.Probe) (int, readyBody) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	p.Ready(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var body readyBody
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	return rec.Code, body
+}
+
+// TestReady - тестирует ответ /readyz по результатам проверок и при остановке
+func TestReady(t *testing.T) {
+	var dbErr error
+	p := health.New(time.Second)
+	p.Add("database", func(context.Context) (map[string]any, error) { return nil, dbErr })
+	p.Add("migrations", func(context.Context) (map[string]any, error) {
+		return map[string]any{"version": 11}, nil
+	})
+
+	code, body := ready(t, p)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, health.StatusOK, body.Status)
+	assert.Equal(t, health.StatusOK, body.Checks["database"]["status"])
+	assert.EqualValues(t, 11, body.Checks["migrations"]["version"])
+
+	dbErr = errors.New("connection refused")
+	code, body = ready(t, p)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, health.StatusUnavailable, body.Status)
+	assert.Equal(t, "connection refused", body.Checks["database"]["error"])
+	assert.Equal(t, health.StatusOK, body.Checks["migrations"]["status"])
+
+	dbErr = nil
+	p.Shutdown()
+	code, body = ready(t, p)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, health.StatusShutdown, body.Status)
+
+	rec := httptest.NewRecorder()
+	p.Live(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code, "liveness does not depend on shutdown")
+}
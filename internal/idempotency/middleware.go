@@ -0,0 +1,184 @@
+// Package idempotency — повтор POST-запросов по заголовку Idempotency-Key.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/olesia8novoselova/Subscriptions/internal/auth"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/olesia8novoselova/Subscriptions/pkg/logging"
+)
+
+// Header — заголовок с ключом идемпотентности
+const Header = "Idempotency-Key"
+
+// ReplayedHeader — признак того, что ответ взят из сохранённого результата
+const ReplayedHeader = "Idempotent-Replayed"
+
+const maxKeyLen = 255
+
+// MaxBodyBytes — максимальный размер тела запроса с Idempotency-Key: тело хешируется целиком
+const MaxBodyBytes = 1 << 20
+
+// replayHeaders — заголовки ответа, которые сохраняются вместе с телом
+var replayHeaders = []string{"Content-Type", "ETag", "Location"}
+
+type Store interface {
+	// Reserve — занимает ключ для нового запроса. Если действующий ключ уже есть, возвращает его.
+	Reserve(ctx context.Context, rec *models.IdempotencyKey) (*models.IdempotencyKey, error)
+	// Complete — сохраняет ответ на запрос
+	Complete(ctx context.Context, rec *models.IdempotencyKey) error
+	// Release — освобождает ключ, если ответ не сохранён (повтор выполнит запрос заново)
+	Release(ctx context.Context, rec *models.IdempotencyKey) error
+}
+
+// Middleware — обрабатывает POST-запросы с заголовком Idempotency-Key.
+// Первый запрос выполняется, а его ответ (кроме 5xx) сохраняется на ttl; повтор с тем же ключом
+// и тем же телом получает сохранённый ответ, с другим телом — 422, пока исходный выполняется — 409.
+// Тело больше MaxBodyBytes отклоняется с 413 до обращения к хранилищу.
+// Ключи разделены по арендатору и вызывающей стороне, поэтому Middleware ставится после аутентификации.
+func Middleware(log *slog.Logger, store Store, ttl time.Duration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(Header)
+		if r.Method != http.MethodPost || key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxKeyLen {
+			writeError(w, http.StatusBadRequest, Header+" is too long")
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxBodyBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeError(w, http.StatusRequestEntityTooLarge, "request body is too large")
+				return
+			}
+			writeError(w, http.StatusBadRequest, "failed to read request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		now := time.Now()
+		rec := &models.IdempotencyKey{
+			Scope:       scope(r.Context()),
+			Key:         key,
+			RequestHash: requestHash(r, body),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(ttl),
+		}
+		existing, err := store.Reserve(r.Context(), rec)
+		if err != nil {
+			log.ErrorContext(r.Context(), "idempotency reserve failed", "error", err)
+			writeError(w, http.StatusInternalServerError, "idempotency store error")
+			return
+		}
+		if existing != nil {
+			switch {
+			case existing.RequestHash != rec.RequestHash:
+				writeError(w, http.StatusUnprocessableEntity, Header+" was already used with a different request")
+			case existing.StatusCode == 0:
+				writeError(w, http.StatusConflict, "request with this "+Header+" is still in progress")
+			default:
+				replay(w, existing)
+			}
+			return
+		}
+
+		rw := &recorder{ResponseWriter: w, status: http.StatusOK}
+		completed := false
+		defer func() {
+			if !completed {
+				// панику обработчика или 5xx не запоминаем: повтор должен выполниться заново
+				if err := store.Release(context.WithoutCancel(r.Context()), rec); err != nil {
+					log.ErrorContext(r.Context(), "idempotency release failed", "error", err)
+				}
+			}
+		}()
+
+		next.ServeHTTP(rw, r)
+
+		if rw.status >= http.StatusInternalServerError {
+			return
+		}
+		rec.StatusCode = rw.status
+		rec.ResponseBody = rw.body.Bytes()
+		rec.ResponseHeaders = map[string]string{}
+		for _, h := range replayHeaders {
+			if v := rw.Header().Get(h); v != "" {
+				rec.ResponseHeaders[h] = v
+			}
+		}
+		if err := store.Complete(context.WithoutCancel(r.Context()), rec); err != nil {
+			log.ErrorContext(r.Context(), "idempotency complete failed", "error", err)
+			return
+		}
+		completed = true
+	})
+}
+
+// scope — вызывающая сторона, которой принадлежит ключ
+func scope(ctx context.Context) string {
+	if p, ok := auth.FromContext(ctx); ok {
+		return p.Method + ":" + p.Subject
+	}
+	return ""
+}
+
+// requestHash — отпечаток запроса: метод, путь с query и тело
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + "\n" + r.URL.RequestURI() + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func replay(w http.ResponseWriter, rec *models.IdempotencyKey) {
+	for k, v := range rec.ResponseHeaders {
+		w.Header().Set(k, v)
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(rec.StatusCode)
+	_, _ = w.Write(rec.ResponseBody)
+}
+
+// recorder — пропускает ответ клиенту и запоминает статус и тело
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (rw *recorder) WriteHeader(code int) {
+	if !rw.wroteHeader {
+		rw.status = code
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recorder) Write(b []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(logging.ErrorBody(w, msg))
+}
@@ -0,0 +1,127 @@
+package idempotency_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/olesia8novoselova/Subscriptions/internal/idempotency"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+type memStore struct {
+	mu   sync.Mutex
+	recs map[string]models.IdempotencyKey
+}
+
+func newMemStore() *memStore {
+	return &memStore{recs: map[string]models.IdempotencyKey{}}
+}
+
+func (s *memStore) Reserve(_ context.Context, rec *models.IdempotencyKey) (*models.IdempotencyKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.recs[rec.Scope+"/"+rec.Key]; ok && e.ExpiresAt.After(time.Now()) {
+		return &e, nil
+	}
+	s.recs[rec.Scope+"/"+rec.Key] = *rec
+	return nil, nil
+}
+
+func (s *memStore) Complete(_ context.Context, rec *models.IdempotencyKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recs[rec.Scope+"/"+rec.Key] = *rec
+	return nil
+}
+
+func (s *memStore) Release(_ context.Context, rec *models.IdempotencyKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.recs, rec.Scope+"/"+rec.Key)
+	return nil
+}
+
+func post(h http.Handler, key, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/api/subscriptions", strings.NewReader(body))
+	if key != "" {
+		r.Header.Set(idempotency.Header, key)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+// TestMiddleware_Replay - повтор с тем же ключом возвращает исходный ответ без повторного выполнения,
+// повтор с другим телом отклоняется
+func TestMiddleware_Replay(t *testing.T) {
+	calls := 0
+	h := idempotency.Middleware(slog.New(slog.NewTextHandler(io.Discard, nil)), newMemStore(), time.Hour,
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("ETag", `"1"`)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id":"1"}`))
+		}))
+
+	first := post(h, "k1", `{"price":500}`)
+	assert.Equal(t, http.StatusCreated, first.Code)
+
+	second := post(h, "k1", `{"price":500}`)
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, `{"id":"1"}`, second.Body.String())
+	assert.Equal(t, `"1"`, second.Header().Get("ETag"))
+	assert.Equal(t, "true", second.Header().Get(idempotency.ReplayedHeader))
+	assert.Equal(t, 1, calls)
+
+	other := post(h, "k1", `{"price":600}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, other.Code)
+	assert.Equal(t, 1, calls)
+
+	post(h, "", `{"price":500}`)
+	assert.Equal(t, 2, calls)
+}
+
+// TestMiddleware_ServerError - ответ 5xx не сохраняется, повтор выполняет запрос заново
+func TestMiddleware_ServerError(t *testing.T) {
+	calls := 0
+	h := idempotency.Middleware(slog.New(slog.NewTextHandler(io.Discard, nil)), newMemStore(), time.Hour,
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls == 1 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		}))
+
+	assert.Equal(t, http.StatusInternalServerError, post(h, "k1", `{}`).Code)
+	assert.Equal(t, http.StatusCreated, post(h, "k1", `{}`).Code)
+	assert.Equal(t, 2, calls)
+}
+
+// TestMiddleware_BodyTooLarge - слишком большое тело отклоняется с 413 и не занимает ключ
+func TestMiddleware_BodyTooLarge(t *testing.T) {
+	store := newMemStore()
+	calls := 0
+	h := idempotency.Middleware(slog.New(slog.NewTextHandler(io.Discard, nil)), store, time.Hour,
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusCreated)
+		}))
+
+	w := post(h, "k1", strings.Repeat("x", idempotency.MaxBodyBytes+1))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, 0, calls)
+	assert.Empty(t, store.recs)
+
+	assert.Equal(t, http.StatusCreated, post(h, "k1", `{}`).Code)
+}
@@ -0,0 +1,152 @@
+// Package metrics — метрики Prometheus: HTTP-запросы по шаблонам маршрутов, пул соединений БД,
+// кеш чтения и доменные показатели. Все метрики регистрируются в собственном реестре и отдаются Handler.
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/olesia8novoselova/Subscriptions/internal/repository/cache"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "subscriptions"
+
+type Metrics struct {
+	reg      *prometheus.Registry
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	overlaps prometheus.Counter
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		reg: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method, route pattern and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		overlaps: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "overlap_rejected_total",
+			Help:      "Subscriptions rejected because they overlap an existing one.",
+		}),
+	}
+	m.reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests, m.duration, m.overlaps,
+	)
+	return m
+}
+
+// Handler — обработчик /metrics
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.reg, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError})
+}
+
+// Middleware — считает запросы и их длительность. Маршрут берётся из шаблона mux
+// (например, "GET /api/users/{id}"), а не из пути, чтобы UUID не раздували число рядов;
+// запросы без подходящего шаблона учитываются как "unmatched".
+func (m *Metrics) Middleware(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		_, route := mux.Handler(r)
+		if route == "" {
+			route = "unmatched"
+		}
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+
+		status := strconv.Itoa(sw.status)
+		m.requests.WithLabelValues(r.Method, route, status).Inc()
+		m.duration.WithLabelValues(r.Method, route, status).Observe(time.Since(start).Seconds())
+	})
+}
+
+// OverlapRejected — реализует service.Metrics
+func (m *Metrics) OverlapRejected() {
+	m.overlaps.Inc()
+}
+
+// RegisterDB — статистика пула соединений (sql.DB.Stats)
+func (m *Metrics) RegisterDB(db *sql.DB) {
+	m.reg.MustRegister(collectors.NewDBStatsCollector(db, namespace))
+}
+
+// RegisterCache — попадания, промахи и ошибки кеша чтения подписок
+func (m *Metrics) RegisterCache(c interface{ Stats() cache.Stats }) {
+	counter := func(name, help string, value func(cache.Stats) uint64) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      name,
+			Help:      help,
+		}, func() float64 { return float64(value(c.Stats())) })
+	}
+	m.reg.MustRegister(
+		counter("hits_total", "Subscription cache hits.", func(s cache.Stats) uint64 { return s.Hits }),
+		counter("misses_total", "Subscription cache misses.", func(s cache.Stats) uint64 { return s.Misses }),
+		counter("errors_total", "Subscription cache store errors.", func(s cache.Stats) uint64 { return s.Errors }),
+	)
+}
+
+// ActiveCounter — число подписок всех арендаторов, активных в месяце at
+type ActiveCounter interface {
+	CountActive(ctx context.Context, at time.Time) (int64, error)
+}
+
+// RegisterActiveSubscriptions — gauge активных в текущем месяце подписок; считается при каждом сборе метрик
+func (m *Metrics) RegisterActiveSubscriptions(c ActiveCounter) {
+	m.reg.MustRegister(&activeCollector{
+		counter: c,
+		desc: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "active"),
+			"Subscriptions active in the current month across all tenants.", nil, nil),
+	})
+}
+
+type activeCollector struct {
+	counter ActiveCounter
+	desc    *prometheus.Desc
+}
+
+func (c *activeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *activeCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	n, err := c.counter.CountActive(ctx, time.Now())
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(n))
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,76 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olesia8novoselova/Subscriptions/internal/metrics"
+	"github.com/olesia8novoselova/Subscriptions/internal/repository/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeActive struct{ err error }
+
+func (f fakeActive) CountActive(context.Context, time.Time) (int64, error) { return 42, f.err }
+
+type fakeCache struct{}
+
+func (fakeCache) Stats() cache.Stats { return cache.Stats{Hits: 3, Misses: 1} }
+
+func scrape(t *testing.T, m *metrics.Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+// TestMiddleware - тестирует учёт запросов по шаблону маршрута, а не по пути
+func TestMiddleware(t *testing.T) {
+	m := metrics.New()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	h := m.Middleware(mux, mux)
+
+	for _, path := range []string{"/api/users/1", "/api/users/2", "/nope"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	out := scrape(t, m)
+	assert.Contains(t, out, `subscriptions_http_requests_total{method="GET",route="GET /api/users/{id}",status="404"} 2`)
+	assert.Contains(t, out, `subscriptions_http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, out, `subscriptions_http_request_duration_seconds_count{method="GET",route="GET /api/users/{id}",status="404"} 2`)
+	assert.NotContains(t, out, "/api/users/1")
+}
+
+// TestDomainMetrics - тестирует доменные показатели и метрики кеша
+func TestDomainMetrics(t *testing.T) {
+	m := metrics.New()
+	m.RegisterActiveSubscriptions(fakeActive{})
+	m.RegisterCache(fakeCache{})
+	m.OverlapRejected()
+
+	out := scrape(t, m)
+	assert.Contains(t, out, "subscriptions_active 42")
+	assert.Contains(t, out, "subscriptions_overlap_rejected_total 1")
+	assert.Contains(t, out, "subscriptions_cache_hits_total 3")
+	assert.Contains(t, out, "subscriptions_cache_misses_total 1")
+
+	// ошибка подсчёта не мешает отдать остальные метрики
+	m = metrics.New()
+	m.RegisterActiveSubscriptions(fakeActive{err: errors.New("db down")})
+	m.OverlapRejected()
+	out = scrape(t, m)
+	assert.False(t, strings.Contains(out, "subscriptions_active 42"))
+	assert.Contains(t, out, "subscriptions_overlap_rejected_total 1")
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OverlapConflict — две подписки пользователя на один сервис с пересекающимися периодами.
+// Такие строки могли остаться от данных, загруженных до ограничения uniq_user_service_period,
+// или возникнуть через участие в совместной подписке, которое ограничение не учитывает.
+type OverlapConflict struct {
+	UserID      uuid.UUID  `json:"user_id"`
+	ServiceName string     `json:"service_name"`
+	FirstID     uuid.UUID  `json:"first_id"`
+	FirstStart  time.Time  `json:"first_start"`
+	FirstEnd    *time.Time `json:"first_end,omitempty"`
+	SecondID    uuid.UUID  `json:"second_id"`
+	SecondStart time.Time  `json:"second_start"`
+	SecondEnd   *time.Time `json:"second_end,omitempty"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Роли вызывающей стороны
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// APIKey — ключ доступа к API. В БД хранится только SHA-256 хеш ключа.
+type APIKey struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey"`
+	Name      string     `json:"name" gorm:"type:text;not null"`
+	KeyHash   string     `json:"-" gorm:"type:text;not null;uniqueIndex"`
+	UserID    *uuid.UUID `json:"user_id,omitempty" gorm:"type:uuid"`
+	TenantID  *uuid.UUID `json:"tenant_id,omitempty" gorm:"type:uuid"`
+	Role      string     `json:"role" gorm:"type:text;not null;default:'user'"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty" gorm:"type:timestamptz"`
+
+	CreatedAt time.Time `json:"-" gorm:"type:timestamptz;not null;default:now()"`
+}
+
+func (APIKey) TableName() string { return "api_keys" }
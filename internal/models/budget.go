@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Режимы бюджета: предупреждать или запрещать превышение
+const (
+	BudgetModeWarn   = "warn"
+	BudgetModeReject = "reject"
+)
+
+// Budget — месячный лимит расходов пользователя на подписки.
+// Если задан ServiceName или Category, лимит учитывает только соответствующие подписки.
+type Budget struct {
+	ID           uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	TenantID     uuid.UUID `json:"-" gorm:"type:uuid;not null;index"`
+	UserID       uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	MonthlyLimit int       `json:"monthly_limit" gorm:"type:int;not null"`
+	ServiceName  *string   `json:"service_name,omitempty" gorm:"type:text"`
+	Category     *string   `json:"category,omitempty" gorm:"type:text"`
+	Mode         string    `json:"mode" gorm:"type:text;not null;default:'warn'"`
+
+	CreatedAt time.Time `json:"-" gorm:"type:timestamptz;not null;default:now()"`
+	UpdatedAt time.Time `json:"-" gorm:"type:timestamptz;not null;default:now()"`
+}
+
+// CreateBudgetRequest — тело запроса на создание бюджета
+type CreateBudgetRequest struct {
+	MonthlyLimit int     `json:"monthly_limit" example:"1500"`
+	ServiceName  *string `json:"service_name,omitempty" example:"Yandex Plus"`
+	Category     *string `json:"category,omitempty" example:"music"`
+	Mode         string  `json:"mode,omitempty" example:"warn"` // warn | reject, по умолчанию warn
+}
+
+// BudgetResponse — ответ с данными бюджета
+type BudgetResponse struct {
+	ID           uuid.UUID `json:"id" example:"0b3c1a4e-8f0f-4f55-a8c9-7d5f0a4f2c11"`
+	UserID       uuid.UUID `json:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	MonthlyLimit int       `json:"monthly_limit" example:"1500"`
+	ServiceName  *string   `json:"service_name,omitempty" example:"Yandex Plus"`
+	Category     *string   `json:"category,omitempty" example:"music"`
+	Mode         string    `json:"mode" example:"warn"`
+}
+
+// BudgetAlert — превышение бюджета в конкретном месяце
+type BudgetAlert struct {
+	BudgetID     uuid.UUID `json:"budget_id" example:"0b3c1a4e-8f0f-4f55-a8c9-7d5f0a4f2c11"`
+	Month        string    `json:"month" example:"08-2025"`
+	MonthlyLimit int       `json:"monthly_limit" example:"1500"`
+	Spent        int       `json:"spent" example:"1790"`
+	Mode         string    `json:"mode" example:"warn"`
+}
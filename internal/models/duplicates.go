@@ -0,0 +1,39 @@
+package models
+
+import "github.com/google/uuid"
+
+// Причины, по которым подписки считаются дублирующими друг друга
+const (
+	DuplicateReasonSimilarName  = "similar_name"
+	DuplicateReasonSameCategory = "same_category"
+)
+
+// DuplicateCandidate — подписка из найденной пары и экономия от её отмены
+// за месяцы пересечения
+type DuplicateCandidate struct {
+	SubscriptionID uuid.UUID `json:"subscription_id" example:"b548150d-6198-4cc1-a186-8c4a1e0ccdcf"`
+	ServiceName    string    `json:"service_name" example:"Spotify"`
+	Price          int       `json:"price" example:"299"`
+	Category       string    `json:"category,omitempty" example:"music"`
+	Savings        int       `json:"savings" example:"897"`
+}
+
+// DuplicateFinding — пара подписок, которые пересекаются по времени и, вероятно,
+// дублируют друг друга
+type DuplicateFinding struct {
+	Reason        string               `json:"reason" example:"similar_name"`
+	Similarity    float64              `json:"similarity" example:"0.86"`
+	OverlapFrom   string               `json:"overlap_from" example:"07-2025"`
+	OverlapTo     string               `json:"overlap_to" example:"09-2025"`
+	OverlapMonths int                  `json:"overlap_months" example:"3"`
+	MaxSavings    int                  `json:"max_savings" example:"1497"`
+	Subscriptions []DuplicateCandidate `json:"subscriptions"`
+}
+
+// DuplicateReport — результат анализа подписок пользователя за период
+type DuplicateReport struct {
+	UserID   uuid.UUID          `json:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	From     string             `json:"from" example:"07-2025"`
+	To       string             `json:"to" example:"06-2026"`
+	Findings []DuplicateFinding `json:"findings"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ScheduledChange — запланированное изменение подписки, начиная с месяца EffectiveDate:
+// новая цена (NewPrice) либо отмена (Cancel, с этого месяца подписка не оплачивается)
+type ScheduledChange struct {
+	ID             uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	SubscriptionID uuid.UUID `json:"subscription_id" gorm:"type:uuid;not null;index"`
+	EffectiveDate  time.Time `json:"effective_date" gorm:"type:date;not null"`
+	NewPrice       *int      `json:"new_price,omitempty" gorm:"type:int"`
+	Cancel         bool      `json:"cancel" gorm:"not null;default:false"`
+
+	CreatedAt time.Time `json:"-" gorm:"type:timestamptz;not null;default:now()"`
+}
+
+// CreateScheduledChangeRequest — тело запроса на планирование изменения
+type CreateScheduledChangeRequest struct {
+	EffectiveDate string `json:"effective_date" example:"01-2026"`
+	NewPrice      *int   `json:"new_price,omitempty" example:"599"`
+	Cancel        bool   `json:"cancel,omitempty" example:"false"`
+}
+
+// ScheduledChangeResponse — ответ с данными запланированного изменения
+type ScheduledChangeResponse struct {
+	ID             uuid.UUID `json:"id" example:"4a1e6c0b-3d2f-4b8e-9c71-5f0d2a8b6e13"`
+	SubscriptionID uuid.UUID `json:"subscription_id" example:"b548150d-6198-4cc1-a186-8c4a1e0ccdcf"`
+	EffectiveDate  string    `json:"effective_date" example:"01-2026"`
+	NewPrice       *int      `json:"new_price,omitempty" example:"599"`
+	Cancel         bool      `json:"cancel" example:"false"`
+}
+
+// ForecastMonth — прогноз расходов на один месяц
+// Committed — подписки с датой окончания (или запланированной отменой), Projected — бессрочные
+type ForecastMonth struct {
+	Month     string `json:"month" example:"01-2026"`
+	Committed int    `json:"committed" example:"500"`
+	Projected int    `json:"projected" example:"1290"`
+	Total     int    `json:"total" example:"1790"`
+}
+
+// ForecastResponse — помесячный прогноз расходов и итоги за весь период
+type ForecastResponse struct {
+	Months    []ForecastMonth `json:"months"`
+	Committed int             `json:"committed" example:"6000"`
+	Projected int             `json:"projected" example:"15480"`
+	Total     int             `json:"total" example:"21480"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyKey — сохранённый результат POST-запроса с заголовком Idempotency-Key.
+// Ключ уникален в пределах арендатора и вызывающей стороны (Scope).
+type IdempotencyKey struct {
+	TenantID    uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Scope       string    `gorm:"type:text;primaryKey"`
+	Key         string    `gorm:"type:text;primaryKey"`
+	RequestHash string    `gorm:"type:text;not null"`
+
+	// StatusCode — 0, пока исходный запрос ещё выполняется
+	StatusCode      int               `gorm:"type:int;not null;default:0"`
+	ResponseBody    []byte            `gorm:"type:bytea"`
+	ResponseHeaders map[string]string `gorm:"serializer:json;type:jsonb"`
+
+	CreatedAt time.Time `gorm:"type:timestamptz;not null;default:now()"`
+	ExpiresAt time.Time `gorm:"type:timestamptz;not null"`
+}
+
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
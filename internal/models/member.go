@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Способы задать долю участника в оплате подписки
+const (
+	ShareTypePercent = "percent" // процент от цены подписки
+	ShareTypeFixed   = "fixed"   // фиксированная сумма в месяц
+)
+
+// SubscriptionMember — участник совместной (семейной) подписки.
+// Владелец подписки (Subscription.UserID) оплачивает остаток цены после долей участников.
+type SubscriptionMember struct {
+	ID             uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	SubscriptionID uuid.UUID `json:"subscription_id" gorm:"type:uuid;not null;index"`
+	TenantID       uuid.UUID `json:"-" gorm:"type:uuid;not null"`
+	UserID         uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	ShareType      string    `json:"share_type" gorm:"type:text;not null"`
+	ShareValue     int       `json:"share_value" gorm:"type:int;not null"`
+
+	// Amount — доля участника при текущей цене подписки, не хранится в БД
+	Amount int `json:"-" gorm:"-"`
+
+	CreatedAt time.Time `json:"-" gorm:"type:timestamptz;not null;default:now()"`
+}
+
+// AddMemberRequest — тело запроса на добавление участника подписки
+type AddMemberRequest struct {
+	UserID     string `json:"user_id" example:"0a9b1c5e-6f0d-4b7e-8e2a-3c4d5e6f7a8b"`
+	ShareType  string `json:"share_type" example:"percent" enums:"percent,fixed"`
+	ShareValue int    `json:"share_value" example:"25"`
+}
+
+// SubscriptionMemberResponse — участник подписки и его доля при текущей цене
+type SubscriptionMemberResponse struct {
+	ID             uuid.UUID `json:"id" example:"7d2e4f10-9a3b-4c5d-8e6f-1a2b3c4d5e6f"`
+	SubscriptionID uuid.UUID `json:"subscription_id" example:"b548150d-6198-4cc1-a186-8c4a1e0ccdcf"`
+	UserID         uuid.UUID `json:"user_id" example:"0a9b1c5e-6f0d-4b7e-8e2a-3c4d5e6f7a8b"`
+	ShareType      string    `json:"share_type" example:"percent"`
+	ShareValue     int       `json:"share_value" example:"25"`
+	Amount         int       `json:"amount" example:"75"`
+}
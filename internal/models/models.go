@@ -9,11 +9,18 @@ import (
 // Subscription — основная модель подписки в БД
 type Subscription struct {
 	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey"`
+	TenantID    uuid.UUID  `json:"-" gorm:"type:uuid;not null;index"`
 	ServiceName string     `json:"service_name" gorm:"type:text;not null"`
 	Price       int        `json:"price" gorm:"type:int;not null"`
 	UserID      uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
 	StartDate   time.Time  `json:"start_date" gorm:"type:date;not null"`
 	EndDate     *time.Time `json:"end_date,omitempty" gorm:"type:date"`
+	Category    string     `json:"category,omitempty" gorm:"type:text;not null;default:''"`
+	// Version — номер версии для оптимистичной блокировки, отдаётся как ETag
+	Version int `json:"-" gorm:"type:int;not null;default:1"`
+
+	// BudgetAlerts — предупреждения о превышении бюджета, не хранятся в БД
+	BudgetAlerts []BudgetAlert `json:"-" gorm:"-"`
 
 	CreatedAt time.Time `json:"-" gorm:"type:timestamptz;not null;default:now()"`
 	UpdatedAt time.Time `json:"-" gorm:"type:timestamptz;not null;default:now()"`
@@ -26,6 +33,7 @@ type CreateSubscriptionRequest struct {
 	UserID      string  `json:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
 	StartDate   string  `json:"start_date" example:"07-2025"`
 	EndDate     *string `json:"end_date,omitempty" example:"09-2025"`
+	Category    string  `json:"category,omitempty" example:"music"`
 }
 
 // SubscriptionResponse — ответ на запрос подписки
@@ -36,6 +44,9 @@ type SubscriptionResponse struct {
 	UserID      uuid.UUID `json:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
 	StartDate   string    `json:"start_date" example:"07-2025"`
 	EndDate     *string   `json:"end_date,omitempty" example:"09-2025"`
+	Category    string    `json:"category,omitempty" example:"music"`
+
+	BudgetAlerts []BudgetAlert `json:"budget_alerts,omitempty"`
 }
 
 // ListFilters — фильтры для списка подписок
@@ -45,6 +56,12 @@ type ListFilters struct {
 	ServiceName string
 	Limit       int
 	Offset      int
+
+	// IncludeShared — вместе с UserID также подписки, в которых пользователь — участник
+	IncludeShared bool
+
+	// UserIDs — подписки любого из пользователей (пакетная загрузка), учитывается в List
+	UserIDs []uuid.UUID
 }
 
 type UpdateSubscriptionRequest struct {
@@ -52,6 +69,7 @@ type UpdateSubscriptionRequest struct {
 	Price       *int    `json:"price,omitempty" example:"450"`
 	StartDate   *string `json:"start_date,omitempty" example:"08-2025"`
 	EndDate     *string `json:"end_date,omitempty" example:""` // "" — очистить конец
+	Category    *string `json:"category,omitempty" example:"music"`
 }
 
 // TotalCostResponse — суммарная стоимость подписок
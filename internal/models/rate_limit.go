@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// RateLimitBucket — корзина token bucket одной вызывающей стороны, общая для всех реплик.
+// Не привязана к арендатору: лимит действует на клиента целиком.
+type RateLimitBucket struct {
+	Key        string    `gorm:"type:text;primaryKey"`
+	Tokens     float64   `gorm:"type:double precision;not null"`
+	RefilledAt time.Time `gorm:"type:timestamptz;not null"`
+	// ExpiresAt — момент, когда корзина наполнится и запись можно удалить
+	ExpiresAt time.Time `gorm:"type:timestamptz;not null"`
+}
+
+func (RateLimitBucket) TableName() string {
+	return "rate_limit_buckets"
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Политики удаления пользователя, у которого остались подписки
+const (
+	UserDeleteBlock    = "block"    // удаление запрещено
+	UserDeleteCascade  = "cascade"  // подписки удаляются вместе с пользователем
+	UserDeleteReassign = "reassign" // подписки передаются другому пользователю
+)
+
+// User — пользователь, владелец подписок и бюджетов
+type User struct {
+	// первичный ключ — (tenant_id, id): ID уникален в пределах арендатора
+	ID       uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	TenantID uuid.UUID `json:"-" gorm:"type:uuid;primaryKey"`
+	Name     string    `json:"name" gorm:"type:text;not null;default:''"`
+	Email    *string   `json:"email,omitempty" gorm:"type:text"`
+
+	CreatedAt time.Time `json:"-" gorm:"type:timestamptz;not null;default:now()"`
+	UpdatedAt time.Time `json:"-" gorm:"type:timestamptz;not null;default:now()"`
+}
+
+// CreateUserRequest — тело запроса на создание пользователя
+type CreateUserRequest struct {
+	ID    string  `json:"id,omitempty" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"` // пусто — сгенерировать
+	Name  string  `json:"name" example:"Alice"`
+	Email *string `json:"email,omitempty" example:"alice@example.com"`
+}
+
+// UpdateUserRequest — частичное изменение пользователя
+type UpdateUserRequest struct {
+	Name  *string `json:"name,omitempty" example:"Alice Smith"`
+	Email *string `json:"email,omitempty" example:"alice@example.com"` // "" — очистить email
+}
+
+// UserResponse — ответ на запрос пользователя
+type UserResponse struct {
+	ID    uuid.UUID `json:"id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	Name  string    `json:"name" example:"Alice"`
+	Email *string   `json:"email,omitempty" example:"alice@example.com"`
+}
+
+// UserSummary — сводка по пользователю за текущий месяц
+type UserSummary struct {
+	UserID              uuid.UUID `json:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	Name                string    `json:"name" example:"Alice"`
+	Month               string    `json:"month" example:"07-2025"`
+	ActiveSubscriptions int       `json:"active_subscriptions" example:"3"`
+	MonthlySpend        int       `json:"monthly_spend" example:"1200"`
+}
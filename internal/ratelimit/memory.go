@@ -0,0 +1,51 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval — как часто удаляются наполнившиеся корзины
+const sweepInterval = time.Minute
+
+// Memory — корзины в памяти процесса; каждая реплика считает запросы отдельно
+type Memory struct {
+	mu      sync.Mutex
+	buckets map[string]memoryBucket
+	swept   time.Time
+}
+
+type memoryBucket struct {
+	Bucket
+	// full — с этого момента корзина полная и её можно забыть
+	full time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{buckets: map[string]memoryBucket{}, swept: time.Now()}
+}
+
+func (m *Memory) Take(_ context.Context, key string, l Limit) (Result, error) {
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var prev *Bucket
+	if b, ok := m.buckets[key]; ok {
+		prev = &b.Bucket
+	}
+	next, res := Take(prev, l, now)
+	m.buckets[key] = memoryBucket{Bucket: next, full: now.Add(res.Reset)}
+
+	if now.Sub(m.swept) >= sweepInterval {
+		for k, b := range m.buckets {
+			if !b.full.After(now) {
+				delete(m.buckets, k)
+			}
+		}
+		m.swept = now
+	}
+	return res, nil
+}
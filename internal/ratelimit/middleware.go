@@ -0,0 +1,148 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/olesia8novoselova/Subscriptions/internal/auth"
+	"github.com/olesia8novoselova/Subscriptions/pkg/logging"
+)
+
+// Config — лимиты для чтения (GET, HEAD, OPTIONS) и для изменяющих запросов
+type Config struct {
+	Read  Limit
+	Write Limit
+	// IP — общий лимит на IP клиента до аутентификации (IPMiddleware): ограничивает и запросы
+	// с неверными учётными данными, которые не доходят до Middleware
+	IP Limit
+	// TrustForwarded — сервер стоит за прокси: IP клиента берётся из последнего адреса X-Forwarded-For
+	TrustForwarded bool
+}
+
+// Limiter — ограничение частоты запросов с лимитами, которые можно заменить без перезапуска
+type Limiter struct {
+	log   *slog.Logger
+	store Store
+	cfg   atomic.Pointer[Config]
+}
+
+func New(log *slog.Logger, store Store, cfg Config) *Limiter {
+	l := &Limiter{log: log, store: store}
+	l.SetConfig(cfg)
+	return l
+}
+
+// SetConfig — заменяет лимиты; корзины клиентов сохраняются
+func (l *Limiter) SetConfig(cfg Config) {
+	l.cfg.Store(&cfg)
+}
+
+// Middleware — ограничивает частоту запросов к /api/* и /graphql. Корзина выбирается по API-ключу,
+// пользователю из JWT или IP клиента, отдельно для чтения и записи, поэтому Middleware ставится
+// после аутентификации. Ответ с лимитом получает заголовки RateLimit-*, отклонённый — 429 и Retry-After.
+// Если хранилище недоступно, запрос пропускается.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !limited(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cfg := l.cfg.Load()
+		class, limit := "write", cfg.Write
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			class, limit = "read", cfg.Read
+		}
+		if l.take(w, r, class+":"+clientKey(r, cfg.TrustForwarded), limit) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// IPMiddleware — ограничивает частоту запросов к /api/* и /graphql с одного IP лимитом Config.IP.
+// Ставится перед аутентификацией, чтобы подбор ключей и токенов тоже упирался в лимит.
+func (l *Limiter) IPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !limited(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cfg := l.cfg.Load()
+		if l.take(w, r, "ip:"+clientIP(r, cfg.TrustForwarded), cfg.IP) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// limited — запрос к API, на который распространяются лимиты (пробы и метрики не ограничиваются)
+func limited(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/api/") || r.URL.Path == "/graphql"
+}
+
+// take — расходует токен корзины key и выставляет заголовки RateLimit-*. false — лимит исчерпан,
+// ответ 429 уже записан
+func (l *Limiter) take(w http.ResponseWriter, r *http.Request, key string, limit Limit) bool {
+	if !limit.Enabled() {
+		return true
+	}
+	res, err := l.store.Take(r.Context(), key, limit)
+	if err != nil {
+		l.log.ErrorContext(r.Context(), "rate limit store failed", "error", err)
+		return true
+	}
+
+	h := w.Header()
+	h.Set("RateLimit-Policy", strconv.Itoa(limit.Requests)+";w="+ceilSeconds(limit.Period))
+	h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", ceilSeconds(res.Reset))
+	if !res.Allowed {
+		h.Set("Retry-After", ceilSeconds(res.RetryAfter))
+		h.Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_ = json.NewEncoder(w).Encode(logging.ErrorBody(w, "rate limit exceeded"))
+		return false
+	}
+	return true
+}
+
+// clientKey — вызывающая сторона: API-ключ, пользователь JWT или IP клиента
+func clientKey(r *http.Request, trustForwarded bool) string {
+	if p, ok := auth.FromContext(r.Context()); ok {
+		if p.Method == auth.MethodAPIKey {
+			// Subject API-ключа — "api_key:<id>"
+			return p.Subject
+		}
+		return "user:" + p.Subject
+	}
+	return "ip:" + clientIP(r, trustForwarded)
+}
+
+func clientIP(r *http.Request, trustForwarded bool) string {
+	if trustForwarded {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			// последний адрес добавлен нашим прокси, предыдущие мог подставить сам клиент
+			parts := strings.Split(xff, ",")
+			if ip := strings.TrimSpace(parts[len(parts)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ceilSeconds — целое число секунд с округлением вверх, как требуют Retry-After и RateLimit-Reset
+func ceilSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
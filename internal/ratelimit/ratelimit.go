@@ -0,0 +1,123 @@
+// Package ratelimit — ограничение частоты запросов к API алгоритмом token bucket.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit — корзина на Requests запросов, которая полностью наполняется за Period.
+// Нулевое значение — ограничение отключено.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// ParseLimit — разбирает лимит вида "60/1m" (60 запросов в минуту); пустая строка — без ограничения
+func ParseLimit(s string) (Limit, error) {
+	if s == "" {
+		return Limit{}, nil
+	}
+	n, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("rate limit %q must look like 60/1m", s)
+	}
+	requests, err := strconv.Atoi(n)
+	if err != nil || requests <= 0 {
+		return Limit{}, fmt.Errorf("rate limit %q: number of requests must be a positive integer", s)
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("rate limit %q: period must be a positive duration", s)
+	}
+	return Limit{Requests: requests, Period: d}, nil
+}
+
+// UnmarshalText — лимит из файла конфигурации, формат как у ParseLimit
+func (l *Limit) UnmarshalText(text []byte) error {
+	v, err := ParseLimit(string(text))
+	if err != nil {
+		return err
+	}
+	*l = v
+	return nil
+}
+
+func (l Limit) MarshalText() ([]byte, error) {
+	if !l.Enabled() {
+		return nil, nil
+	}
+	return []byte(l.String()), nil
+}
+
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Period > 0
+}
+
+func (l Limit) String() string {
+	if !l.Enabled() {
+		return "unlimited"
+	}
+	return strconv.Itoa(l.Requests) + "/" + l.Period.String()
+}
+
+// rate — токенов в секунду
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// Bucket — состояние корзины: число токенов на момент Refilled
+type Bucket struct {
+	Tokens   float64
+	Refilled time.Time
+}
+
+// Result — решение по запросу и значения для заголовков RateLimit-*
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter — через сколько появится токен; 0, если запрос разрешён
+	RetryAfter time.Duration
+	// Reset — через сколько корзина наполнится полностью
+	Reset time.Duration
+}
+
+// Take — пополняет корзину b за прошедшее время и расходует один токен, если он есть.
+// b == nil — корзины ещё нет, она считается полной. Возвращает новое состояние корзины.
+func Take(b *Bucket, l Limit, now time.Time) (Bucket, Result) {
+	capacity := float64(l.Requests)
+	tokens := capacity
+	if b != nil {
+		tokens = b.Tokens
+		// часы реплик могут расходиться: время назад корзину не пополняет
+		if elapsed := now.Sub(b.Refilled).Seconds(); elapsed > 0 {
+			tokens = math.Min(capacity, tokens+elapsed*l.rate())
+		}
+	}
+
+	res := Result{Limit: l.Requests}
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - tokens) / l.rate())
+	}
+	res.Remaining = int(tokens)
+	res.Reset = seconds((capacity - tokens) / l.rate())
+	return Bucket{Tokens: tokens, Refilled: now}, res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// Store — хранилище корзин
+type Store interface {
+	// Take — расходует токен из корзины key (см. Take)
+	Take(ctx context.Context, key string, l Limit) (Result, error)
+}
@@ -0,0 +1,177 @@
+package ratelimit_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/auth"
+	"github.com/olesia8novoselova/Subscriptions/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseLimit - тестирует разбор лимитов вида N/период
+func TestParseLimit(t *testing.T) {
+	l, err := ratelimit.ParseLimit("60/1m")
+	require.NoError(t, err)
+	assert.Equal(t, ratelimit.Limit{Requests: 60, Period: time.Minute}, l)
+
+	l, err = ratelimit.ParseLimit("")
+	require.NoError(t, err)
+	assert.False(t, l.Enabled())
+
+	for _, s := range []string{"60", "0/1m", "x/1m", "60/0s", "60/soon"} {
+		_, err := ratelimit.ParseLimit(s)
+		assert.Error(t, err, s)
+	}
+}
+
+// TestTake - тестирует расход и пополнение корзины
+func TestTake(t *testing.T) {
+	l := ratelimit.Limit{Requests: 2, Period: 2 * time.Second}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	b, res := ratelimit.Take(nil, l, now)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 1, res.Remaining)
+	assert.Equal(t, time.Second, res.Reset)
+
+	b, res = ratelimit.Take(&b, l, now)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+
+	b, res = ratelimit.Take(&b, l, now.Add(500*time.Millisecond))
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 500*time.Millisecond, res.RetryAfter)
+	assert.Equal(t, 1500*time.Millisecond, res.Reset)
+
+	_, res = ratelimit.Take(&b, l, now.Add(time.Second))
+	assert.True(t, res.Allowed, "one token is refilled per second")
+
+	// время назад (расхождение часов реплик) корзину не пополняет и не ломает
+	_, res = ratelimit.Take(&ratelimit.Bucket{Tokens: 0.5, Refilled: now}, l, now.Add(-time.Minute))
+	assert.False(t, res.Allowed)
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("store is down")
+}
+
+func serve(h http.Handler, method, path, remoteAddr string, p *auth.Principal) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.RemoteAddr = remoteAddr
+	if p != nil {
+		req = req.WithContext(auth.WithPrincipal(req.Context(), p))
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+// TestMiddleware - тестирует раздельные лимиты чтения и записи, ключи клиентов и ответ 429
+func TestMiddleware(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	limiter := ratelimit.New(log, ratelimit.NewMemory(), ratelimit.Config{
+		Read:  ratelimit.Limit{Requests: 2, Period: time.Minute},
+		Write: ratelimit.Limit{Requests: 1, Period: time.Minute},
+	})
+	h := limiter.Middleware(ok)
+
+	rec := serve(h, http.MethodPost, "/api/subscriptions", "10.0.0.1:5000", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "1;w=60", rec.Header().Get("RateLimit-Policy"))
+
+	rec = serve(h, http.MethodPost, "/api/subscriptions", "10.0.0.1:5001", nil)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+	assert.Equal(t, "60", rec.Header().Get("RateLimit-Reset"))
+	var body map[string]string
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t, "rate limit exceeded", body["error"])
+
+	// чтение считается отдельно от записи
+	assert.Equal(t, http.StatusOK, serve(h, http.MethodGet, "/api/subscriptions", "10.0.0.1:5002", nil).Code)
+	// другой IP и аутентифицированные клиенты — свои корзины
+	assert.Equal(t, http.StatusOK, serve(h, http.MethodPost, "/api/subscriptions", "10.0.0.2:5000", nil).Code)
+	user := &auth.Principal{Subject: uuid.NewString(), Method: auth.MethodJWT}
+	assert.Equal(t, http.StatusOK, serve(h, http.MethodPost, "/api/subscriptions", "10.0.0.1:5003", user).Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve(h, http.MethodPost, "/api/subscriptions", "10.0.0.3:5000", user).Code)
+	key := &auth.Principal{Subject: "api_key:" + uuid.NewString(), Method: auth.MethodAPIKey}
+	assert.Equal(t, http.StatusOK, serve(h, http.MethodPost, "/api/subscriptions", "10.0.0.1:5004", key).Code)
+
+	// пробы и метрики не ограничиваются
+	rec = serve(h, http.MethodGet, "/healthz", "10.0.0.1:5005", nil)
+	assert.Empty(t, rec.Header().Get("RateLimit-Limit"))
+
+	// новые лимиты применяются без перезапуска, накопленные корзины сохраняются
+	limiter.SetConfig(ratelimit.Config{Write: ratelimit.Limit{Requests: 1, Period: time.Second}})
+	assert.Equal(t, http.StatusTooManyRequests, serve(h, http.MethodPost, "/api/subscriptions", "10.0.0.1:5006", nil).Code)
+	rec = serve(h, http.MethodGet, "/api/subscriptions", "10.0.0.1:5007", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("RateLimit-Limit"), "read limit is switched off")
+
+	// при недоступном хранилище запросы пропускаются
+	h = ratelimit.New(log, failingStore{}, ratelimit.Config{Write: ratelimit.Limit{Requests: 1, Period: time.Minute}}).Middleware(ok)
+	assert.Equal(t, http.StatusOK, serve(h, http.MethodPost, "/api/subscriptions", "10.0.0.1:5000", nil).Code)
+}
+
+// TestIPMiddleware - тестирует общий лимит на IP до аутентификации
+func TestIPMiddleware(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	// обработчик отвечает 401, как auth.Middleware на неверный ключ
+	unauthorized := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusUnauthorized) })
+	limiter := ratelimit.New(log, ratelimit.NewMemory(), ratelimit.Config{
+		IP: ratelimit.Limit{Requests: 2, Period: time.Minute},
+	})
+	h := limiter.IPMiddleware(unauthorized)
+
+	assert.Equal(t, http.StatusUnauthorized, serve(h, http.MethodGet, "/api/subscriptions", "10.0.0.1:5000", nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(h, http.MethodPost, "/graphql", "10.0.0.1:5001", nil).Code)
+	// чтение и запись расходуют одну корзину
+	rec := serve(h, http.MethodGet, "/api/subscriptions", "10.0.0.1:5002", nil)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "30", rec.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusUnauthorized, serve(h, http.MethodGet, "/api/subscriptions", "10.0.0.2:5000", nil).Code)
+	assert.Empty(t, serve(h, http.MethodGet, "/healthz", "10.0.0.1:5003", nil).Header().Get("RateLimit-Limit"))
+
+	// лимиты чтения и записи на IPMiddleware не влияют
+	limiter.SetConfig(ratelimit.Config{Read: ratelimit.Limit{Requests: 1, Period: time.Minute}})
+	assert.Equal(t, http.StatusUnauthorized, serve(h, http.MethodGet, "/api/subscriptions", "10.0.0.1:5004", nil).Code)
+}
+
+// TestMiddleware_TrustForwarded - тестирует IP клиента из X-Forwarded-For за доверенным прокси
+func TestMiddleware_TrustForwarded(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	h := ratelimit.New(log, ratelimit.NewMemory(), ratelimit.Config{
+		Read:           ratelimit.Limit{Requests: 1, Period: time.Minute},
+		TrustForwarded: true,
+	}).Middleware(ok)
+
+	get := func(xff string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/subscriptions", nil)
+		req.RemoteAddr = "10.0.0.254:443"
+		req.Header.Set("X-Forwarded-For", xff)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	assert.Equal(t, http.StatusOK, get("203.0.113.1"))
+	assert.Equal(t, http.StatusOK, get("203.0.113.2"))
+	// адрес, подставленный клиентом, не помогает обойти лимит
+	assert.Equal(t, http.StatusTooManyRequests, get("198.51.100.7, 203.0.113.1"))
+}
@@ -0,0 +1,268 @@
+// Package cache — кеширующий декоратор хранилища подписок (read-through).
+// Кешируются FindByID и FindActiveInPeriod (основа /total); остальные методы идут в хранилище напрямую.
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/olesia8novoselova/Subscriptions/internal/service"
+	"github.com/olesia8novoselova/Subscriptions/internal/tenant"
+)
+
+// Store — хранилище кеша: LRU в памяти процесса или Redis
+type Store interface {
+	// Get — значение по ключу; ok == false, если его нет или срок истёк
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+	// Counter и Incr — счётчик без срока жизни; отсутствующий счётчик равен 0
+	Counter(ctx context.Context, key string) (int64, error)
+	Incr(ctx context.Context, key string) (int64, error)
+}
+
+// Stats — статистика обращений к кешу
+type Stats struct {
+	Hits     uint64  `json:"hits"`
+	Misses   uint64  `json:"misses"`
+	Errors   uint64  `json:"errors"`
+	HitRatio float64 `json:"hit_ratio"`
+}
+
+// SubscriptionRepo — декоратор service.SubscriptionRepository.
+//
+// Подписка кешируется по ID и удаляется из кеша при Update и Delete. Результаты FindActiveInPeriod
+// кешируются с номером поколения арендатора, который увеличивается при любом изменении подписок
+// или участников, так что старые записи перестают читаться и истекают по TTL.
+// Ошибки кеша не прерывают запрос: он выполняется в хранилище напрямую.
+type SubscriptionRepo struct {
+	next  service.SubscriptionRepository
+	store Store
+	ttl   time.Duration
+	log   *slog.Logger
+
+	hits, misses, errors atomic.Uint64
+}
+
+func New(next service.SubscriptionRepository, store Store, ttl time.Duration, log *slog.Logger) *SubscriptionRepo {
+	return &SubscriptionRepo{next: next, store: store, ttl: ttl, log: log}
+}
+
+// Stats — счётчики попаданий и промахов с момента запуска
+func (r *SubscriptionRepo) Stats() Stats {
+	s := Stats{Hits: r.hits.Load(), Misses: r.misses.Load(), Errors: r.errors.Load()}
+	if total := s.Hits + s.Misses; total > 0 {
+		s.HitRatio = float64(s.Hits) / float64(total)
+	}
+	return s
+}
+
+func (r *SubscriptionRepo) Create(ctx context.Context, s *models.Subscription) error {
+	if err := r.next.Create(ctx, s); err != nil {
+		return err
+	}
+	r.invalidate(ctx, nil)
+	return nil
+}
+
+func (r *SubscriptionRepo) FindByID(ctx context.Context, id uuid.UUID) (*models.Subscription, error) {
+	key := subKey(ctx, id)
+	var sub models.Subscription
+	if r.load(ctx, key, &sub) {
+		return &sub, nil
+	}
+	res, err := r.next.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	r.save(ctx, key, res)
+	return res, nil
+}
+
+func (r *SubscriptionRepo) List(ctx context.Context, f models.ListFilters) ([]models.Subscription, error) {
+	return r.next.List(ctx, f)
+}
+
+func (r *SubscriptionRepo) Delete(ctx context.Context, id uuid.UUID, version int) error {
+	if err := r.next.Delete(ctx, id, version); err != nil {
+		return err
+	}
+	r.invalidate(ctx, &id)
+	return nil
+}
+
+func (r *SubscriptionRepo) Update(ctx context.Context, id uuid.UUID, version int, fields map[string]any) (*models.Subscription, error) {
+	res, err := r.next.Update(ctx, id, version, fields)
+	if err != nil {
+		return nil, err
+	}
+	r.invalidate(ctx, &id)
+	return res, nil
+}
+
+func (r *SubscriptionRepo) FindActiveInPeriod(ctx context.Context, from, to time.Time, f models.ListFilters) ([]models.Subscription, error) {
+	gen, err := r.store.Counter(ctx, genKey(ctx))
+	if err != nil {
+		r.fail("cache generation read failed", err)
+		return r.next.FindActiveInPeriod(ctx, from, to, f)
+	}
+
+	user := ""
+	if f.UserID != nil {
+		user = f.UserID.String()
+	}
+	key := fmt.Sprintf("period:%s:%d:%s:%s:%s:%t:%q",
+		tenant.FromContext(ctx), gen, from.Format("2006-01"), to.Format("2006-01"), user, f.IncludeShared, f.ServiceName)
+
+	var res []models.Subscription
+	if r.load(ctx, key, &res) {
+		return res, nil
+	}
+	res, err = r.next.FindActiveInPeriod(ctx, from, to, f)
+	if err != nil {
+		return nil, err
+	}
+	r.save(ctx, key, res)
+	return res, nil
+}
+
+// ExistsOverlap не кешируется: проверка защищает запись и должна видеть актуальные данные
+func (r *SubscriptionRepo) ExistsOverlap(ctx context.Context, userID uuid.UUID, serviceName string, start time.Time, end *time.Time, excludeID *uuid.UUID) (bool, error) {
+	return r.next.ExistsOverlap(ctx, userID, serviceName, start, end, excludeID)
+}
+
+// Members — декоратор хранилища участников: добавление и удаление участника меняет
+// результаты FindActiveInPeriod с IncludeShared, поэтому сбрасывает поколение арендатора
+func (r *SubscriptionRepo) Members(next service.MemberRepository) service.MemberRepository {
+	return &memberRepo{MemberRepository: next, cache: r}
+}
+
+type memberRepo struct {
+	service.MemberRepository
+	cache *SubscriptionRepo
+}
+
+func (m *memberRepo) Create(ctx context.Context, member *models.SubscriptionMember) error {
+	if err := m.MemberRepository.Create(ctx, member); err != nil {
+		return err
+	}
+	m.cache.invalidate(ctx, nil)
+	return nil
+}
+
+func (m *memberRepo) Delete(ctx context.Context, subscriptionID, id uuid.UUID) error {
+	if err := m.MemberRepository.Delete(ctx, subscriptionID, id); err != nil {
+		return err
+	}
+	m.cache.invalidate(ctx, nil)
+	return nil
+}
+
+// Users — декоратор хранилища пользователей: удаление пользователя удаляет его подписки
+// или передаёт их другому пользователю, поэтому сбрасывает их из кеша вместе с поколением арендатора
+func (r *SubscriptionRepo) Users(next service.UserRepository) service.UserRepository {
+	return &userRepo{UserRepository: next, cache: r}
+}
+
+type userRepo struct {
+	service.UserRepository
+	cache *SubscriptionRepo
+}
+
+func (u *userRepo) Delete(ctx context.Context, id uuid.UUID, policy string, reassignTo uuid.UUID) error {
+	// Limit -1 — без ограничения
+	owned, err := u.cache.next.List(ctx, models.ListFilters{UserID: &id, Limit: -1})
+	if err != nil {
+		return err
+	}
+	if err := u.UserRepository.Delete(ctx, id, policy, reassignTo); err != nil {
+		return err
+	}
+	ids := make([]uuid.UUID, len(owned))
+	for i, s := range owned {
+		ids[i] = s.ID
+	}
+	if err := Invalidate(ctx, u.cache.store, ids...); err != nil {
+		u.cache.fail("cache invalidation failed", err)
+	}
+	return nil
+}
+
+// invalidate — удаляет подписку id (если задана) и увеличивает поколение арендатора
+func (r *SubscriptionRepo) invalidate(ctx context.Context, id *uuid.UUID) {
+	var ids []uuid.UUID
+	if id != nil {
+		ids = append(ids, *id)
+	}
+	if err := Invalidate(ctx, r.store, ids...); err != nil {
+		r.fail("cache invalidation failed", err)
+	}
+}
+
+// Invalidate — сбрасывает кеш арендатора из ctx для изменений, сделанных в обход декоратора
+// (массовые операции cmd/subsadmin): удаляет подписки ids и увеличивает поколение арендатора,
+// так что выборки для /total пересчитываются из хранилища при следующем чтении
+func Invalidate(ctx context.Context, store Store, ids ...uuid.UUID) error {
+	var errs []error
+	for _, id := range ids {
+		if err := store.Delete(ctx, subKey(ctx, id)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if _, err := store.Incr(ctx, genKey(ctx)); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// load — читает и декодирует значение; false — промах или ошибка кеша
+func (r *SubscriptionRepo) load(ctx context.Context, key string, dst any) bool {
+	data, ok, err := r.store.Get(ctx, key)
+	if err == nil && ok {
+		err = gob.NewDecoder(bytes.NewReader(data)).Decode(dst)
+		if err == nil {
+			r.hits.Add(1)
+			return true
+		}
+	}
+	if err != nil {
+		r.fail("cache read failed", err)
+	}
+	r.misses.Add(1)
+	return false
+}
+
+func (r *SubscriptionRepo) save(ctx context.Context, key string, v any) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(v)
+	if err == nil {
+		err = r.store.Set(ctx, key, buf.Bytes(), r.ttl)
+	}
+	if err != nil {
+		r.fail("cache write failed", err)
+	}
+}
+
+func (r *SubscriptionRepo) fail(msg string, err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	r.errors.Add(1)
+	r.log.Warn(msg, "error", err)
+}
+
+func subKey(ctx context.Context, id uuid.UUID) string {
+	return "sub:" + tenant.FromContext(ctx).String() + ":" + id.String()
+}
+
+func genKey(ctx context.Context) string {
+	return "gen:" + tenant.FromContext(ctx).String()
+}
@@ -0,0 +1,300 @@
+package cache_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/olesia8novoselova/Subscriptions/internal/repository/cache"
+	"github.com/olesia8novoselova/Subscriptions/internal/repository/memory"
+	"github.com/olesia8novoselova/Subscriptions/internal/repository/repotest"
+	"github.com/olesia8novoselova/Subscriptions/internal/service"
+	"github.com/olesia8novoselova/Subscriptions/internal/tenant"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// countingRepo — считает чтения, дошедшие до хранилища
+type countingRepo struct {
+	service.SubscriptionRepository
+	finds, periods atomic.Int32
+}
+
+func (r *countingRepo) FindByID(ctx context.Context, id uuid.UUID) (*models.Subscription, error) {
+	r.finds.Add(1)
+	return r.SubscriptionRepository.FindByID(ctx, id)
+}
+
+func (r *countingRepo) FindActiveInPeriod(ctx context.Context, from, to time.Time, f models.ListFilters) ([]models.Subscription, error) {
+	r.periods.Add(1)
+	return r.SubscriptionRepository.FindActiveInPeriod(ctx, from, to, f)
+}
+
+func newRedis(t *testing.T) (*miniredis.Miniredis, *cache.Redis) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return mr, cache.NewRedis(client, "subs:")
+}
+
+func month(y int, m time.Month) time.Time {
+	return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// TestSubscriptionRepo_Contract - тестирует, что декоратор сохраняет контракт хранилища
+func TestSubscriptionRepo_Contract(t *testing.T) {
+	t.Run("LRU", func(t *testing.T) {
+		repotest.Run(t, func(t *testing.T) repotest.Backend {
+			return repotest.Backend{Repo: cache.New(memory.New(), cache.NewLRU(100), time.Minute, discard)}
+		})
+	})
+	t.Run("Redis", func(t *testing.T) {
+		repotest.Run(t, func(t *testing.T) repotest.Backend {
+			_, store := newRedis(t)
+			return repotest.Backend{Repo: cache.New(memory.New(), store, time.Minute, discard)}
+		})
+	})
+}
+
+// TestSubscriptionRepo_ReadThrough - тестирует попадания в кеш и сброс при изменении и удалении
+func TestSubscriptionRepo_ReadThrough(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), uuid.New())
+	next := &countingRepo{SubscriptionRepository: memory.New()}
+	repo := cache.New(next, cache.NewLRU(100), time.Minute, discard)
+
+	sub := &models.Subscription{ID: uuid.New(), ServiceName: "Okko", Price: 199, UserID: uuid.New(), StartDate: month(2025, 1)}
+	require.NoError(t, repo.Create(ctx, sub))
+
+	for range 3 {
+		got, err := repo.FindByID(ctx, sub.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, got.Version)
+	}
+	assert.EqualValues(t, 1, next.finds.Load())
+
+	_, err := repo.Update(ctx, sub.ID, 1, map[string]any{"price": 299})
+	require.NoError(t, err)
+	got, err := repo.FindByID(ctx, sub.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 299, got.Price)
+	assert.Equal(t, 2, got.Version)
+	assert.EqualValues(t, 2, next.finds.Load())
+
+	require.NoError(t, repo.Delete(ctx, sub.ID, 0))
+	_, err = repo.FindByID(ctx, sub.ID)
+	assert.Error(t, err)
+
+	s := repo.Stats()
+	assert.EqualValues(t, 2, s.Hits)
+	assert.EqualValues(t, 3, s.Misses)
+	assert.InDelta(t, 0.4, s.HitRatio, 1e-9)
+}
+
+// TestSubscriptionRepo_PeriodInvalidation - тестирует сброс кеша выборок за период при создании подписки
+// и изменении участников
+func TestSubscriptionRepo_PeriodInvalidation(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), uuid.New())
+	next := &countingRepo{SubscriptionRepository: memory.New()}
+	repo := cache.New(next, cache.NewLRU(100), time.Minute, discard)
+	user := uuid.New()
+	from, to := month(2025, 1), month(2025, 12)
+	f := models.ListFilters{UserID: &user}
+
+	require.NoError(t, repo.Create(ctx, &models.Subscription{ID: uuid.New(), ServiceName: "Okko", Price: 199, UserID: user, StartDate: from}))
+	for range 2 {
+		res, err := repo.FindActiveInPeriod(ctx, from, to, f)
+		require.NoError(t, err)
+		assert.Len(t, res, 1)
+	}
+	assert.EqualValues(t, 1, next.periods.Load())
+
+	// другой фильтр — другой ключ
+	_, err := repo.FindActiveInPeriod(ctx, from, to, models.ListFilters{UserID: &user, ServiceName: "okko"})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, next.periods.Load())
+
+	require.NoError(t, repo.Create(ctx, &models.Subscription{ID: uuid.New(), ServiceName: "Kion", Price: 99, UserID: user, StartDate: from}))
+	res, err := repo.FindActiveInPeriod(ctx, from, to, f)
+	require.NoError(t, err)
+	assert.Len(t, res, 2)
+	assert.EqualValues(t, 3, next.periods.Load())
+
+	members := repo.Members(nopMembers{})
+	require.NoError(t, members.Create(ctx, &models.SubscriptionMember{}))
+	_, err = repo.FindActiveInPeriod(ctx, from, to, f)
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, next.periods.Load())
+}
+
+// TestInvalidate - тестирует сброс кеша после изменений в обход декоратора
+func TestInvalidate(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), uuid.New())
+	next := &countingRepo{SubscriptionRepository: memory.New()}
+	_, store := newRedis(t)
+	repo := cache.New(next, store, time.Minute, discard)
+	sub := &models.Subscription{ID: uuid.New(), ServiceName: "Okko", Price: 199, UserID: uuid.New(), StartDate: month(2025, 1)}
+	require.NoError(t, repo.Create(ctx, sub))
+	from, to := month(2025, 1), month(2025, 12)
+
+	for range 2 {
+		_, err := repo.FindByID(ctx, sub.ID)
+		require.NoError(t, err)
+		_, err = repo.FindActiveInPeriod(ctx, from, to, models.ListFilters{})
+		require.NoError(t, err)
+	}
+	assert.EqualValues(t, 1, next.finds.Load())
+	assert.EqualValues(t, 1, next.periods.Load())
+
+	// кеш другого арендатора не затрагивается
+	require.NoError(t, cache.Invalidate(tenant.WithTenant(context.Background(), uuid.New()), store, sub.ID))
+	_, err := repo.FindByID(ctx, sub.ID)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, next.finds.Load())
+
+	require.NoError(t, cache.Invalidate(ctx, store, sub.ID))
+	_, err = repo.FindByID(ctx, sub.ID)
+	require.NoError(t, err)
+	_, err = repo.FindActiveInPeriod(ctx, from, to, models.ListFilters{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, next.finds.Load())
+	assert.EqualValues(t, 2, next.periods.Load())
+}
+
+type nopMembers struct{}
+
+func (nopMembers) Create(context.Context, *models.SubscriptionMember) error { return nil }
+func (nopMembers) ListBySubscriptions(context.Context, []uuid.UUID) ([]models.SubscriptionMember, error) {
+	return nil, nil
+}
+func (nopMembers) Delete(context.Context, uuid.UUID, uuid.UUID) error { return nil }
+
+// cascadeUsers — удаляет подписки пользователя в хранилище напрямую, как ON DELETE CASCADE
+type cascadeUsers struct {
+	service.UserRepository
+	subs service.SubscriptionRepository
+}
+
+func (u cascadeUsers) Delete(ctx context.Context, id uuid.UUID, _ string, _ uuid.UUID) error {
+	owned, err := u.subs.List(ctx, models.ListFilters{UserID: &id, Limit: -1})
+	if err != nil {
+		return err
+	}
+	for _, s := range owned {
+		if err := u.subs.Delete(ctx, s.ID, s.Version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TestSubscriptionRepo_UserDelete - тестирует сброс подписок пользователя из кеша при его удалении
+func TestSubscriptionRepo_UserDelete(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), uuid.New())
+	next := &countingRepo{SubscriptionRepository: memory.New()}
+	repo := cache.New(next, cache.NewLRU(100), time.Minute, discard)
+	user := uuid.New()
+	sub := &models.Subscription{ID: uuid.New(), ServiceName: "Okko", Price: 199, UserID: user, StartDate: month(2025, 1)}
+	require.NoError(t, repo.Create(ctx, sub))
+	from, to := month(2025, 1), month(2025, 12)
+
+	_, err := repo.FindByID(ctx, sub.ID)
+	require.NoError(t, err)
+	res, err := repo.FindActiveInPeriod(ctx, from, to, models.ListFilters{})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	users := repo.Users(cascadeUsers{subs: next})
+	require.NoError(t, users.Delete(ctx, user, models.UserDeleteCascade, uuid.Nil))
+
+	_, err = repo.FindByID(ctx, sub.ID)
+	assert.Error(t, err, "deleted subscription must not be served from cache")
+	res, err = repo.FindActiveInPeriod(ctx, from, to, models.ListFilters{})
+	require.NoError(t, err)
+	assert.Empty(t, res)
+}
+
+// TestSubscriptionRepo_StoreDown - тестирует работу без кеша при недоступном Redis
+func TestSubscriptionRepo_StoreDown(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), uuid.New())
+	mr, store := newRedis(t)
+	repo := cache.New(memory.New(), store, time.Minute, discard)
+	sub := &models.Subscription{ID: uuid.New(), ServiceName: "Okko", Price: 199, UserID: uuid.New(), StartDate: month(2025, 1)}
+	require.NoError(t, repo.Create(ctx, sub))
+
+	mr.Close()
+	got, err := repo.FindByID(ctx, sub.ID)
+	require.NoError(t, err)
+	assert.Equal(t, sub.ID, got.ID)
+	_, err = repo.FindActiveInPeriod(ctx, month(2025, 1), month(2025, 2), models.ListFilters{})
+	require.NoError(t, err)
+	assert.NotZero(t, repo.Stats().Errors)
+}
+
+// TestLRU - тестирует вытеснение давно не использованных записей и счётчики
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewLRU(2)
+	require.NoError(t, c.Set(ctx, "a", []byte("1"), 0))
+	require.NoError(t, c.Set(ctx, "b", []byte("2"), 0))
+	_, ok, _ := c.Get(ctx, "a")
+	require.True(t, ok)
+	require.NoError(t, c.Set(ctx, "c", []byte("3"), 0))
+
+	_, ok, _ = c.Get(ctx, "b")
+	assert.False(t, ok, "b must be evicted")
+	v, ok, _ := c.Get(ctx, "a")
+	assert.True(t, ok)
+	assert.Equal(t, "1", string(v))
+	assert.Equal(t, 2, c.Len())
+
+	require.NoError(t, c.Set(ctx, "d", []byte("4"), time.Nanosecond))
+	time.Sleep(time.Millisecond)
+	_, ok, _ = c.Get(ctx, "d")
+	assert.False(t, ok, "d must expire")
+
+	n, _ := c.Counter(ctx, "gen")
+	assert.Zero(t, n)
+	n, _ = c.Incr(ctx, "gen")
+	assert.EqualValues(t, 1, n)
+}
+
+// TestRedis - тестирует хранение, срок жизни и счётчики в Redis
+func TestRedis(t *testing.T) {
+	ctx := context.Background()
+	mr, store := newRedis(t)
+
+	require.NoError(t, store.Set(ctx, "a", []byte("1"), time.Minute))
+	assert.True(t, mr.Exists("subs:a"))
+	v, ok, err := store.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "1", string(v))
+
+	mr.FastForward(2 * time.Minute)
+	_, ok, err = store.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	n, err := store.Counter(ctx, "gen")
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	_, err = store.Incr(ctx, "gen")
+	require.NoError(t, err)
+	n, err = store.Counter(ctx, "gen")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, n)
+
+	require.NoError(t, store.Set(ctx, "b", []byte("2"), 0))
+	require.NoError(t, store.Delete(ctx, "b"))
+	_, ok, _ = store.Get(ctx, "b")
+	assert.False(t, ok)
+}
@@ -0,0 +1,206 @@
+// Package memory — хранилище подписок в памяти процесса для локальной разработки и тестов.
+// Данные теряются при перезапуске.
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/olesia8novoselova/Subscriptions/internal/service"
+	"github.com/olesia8novoselova/Subscriptions/internal/tenant"
+	"gorm.io/gorm"
+)
+
+// SubscriptionRepo — реализация service.SubscriptionRepository в памяти с той же семантикой периодов,
+// что у postgres.SubscriptionRepo. Участников совместных подписок хранилище не знает,
+// поэтому пересечения проверяются только по владельцу. Как и ограничение EXCLUDE в PostgreSQL,
+// Create и Update под той же блокировкой отклоняют пересекающуюся подписку с service.ErrOverlap.
+type SubscriptionRepo struct {
+	mu   sync.RWMutex
+	subs map[uuid.UUID]models.Subscription
+	now  func() time.Time
+}
+
+func New() *SubscriptionRepo {
+	return &SubscriptionRepo{
+		subs: make(map[uuid.UUID]models.Subscription),
+		now:  time.Now,
+	}
+}
+
+// Все операции ограничены арендатором из контекста
+
+func (r *SubscriptionRepo) Create(ctx context.Context, s *models.Subscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.subs[s.ID]; ok {
+		return gorm.ErrDuplicatedKey
+	}
+	if r.existsOverlap(ctx, s.UserID, s.ServiceName, s.StartDate, s.EndDate, nil) {
+		return service.ErrOverlap
+	}
+	now := r.now().UTC()
+	s.TenantID = tenant.FromContext(ctx)
+	s.Version = 1
+	s.CreatedAt, s.UpdatedAt = now, now
+	r.subs[s.ID] = *s
+	return nil
+}
+
+func (r *SubscriptionRepo) FindByID(ctx context.Context, id uuid.UUID) (*models.Subscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.get(ctx, id)
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &s, nil
+}
+
+func (r *SubscriptionRepo) List(ctx context.Context, f models.ListFilters) ([]models.Subscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var res []models.Subscription
+	for _, s := range r.subs {
+		if r.match(ctx, s, f) && (len(f.UserIDs) == 0 || containsID(f.UserIDs, s.UserID)) {
+			res = append(res, s)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if !res[i].StartDate.Equal(res[j].StartDate) {
+			return res[i].StartDate.After(res[j].StartDate)
+		}
+		return res[i].CreatedAt.After(res[j].CreatedAt)
+	})
+
+	res = res[min(max(f.Offset, 0), len(res)):]
+	if f.Limit >= 0 {
+		res = res[:min(f.Limit, len(res))]
+	}
+	return res, nil
+}
+
+// Delete — удаляет подписку; при version > 0 — только если версия совпадает
+func (r *SubscriptionRepo) Delete(ctx context.Context, id uuid.UUID, version int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.get(ctx, id)
+	if !ok || (version > 0 && s.Version != version) {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.subs, id)
+	return nil
+}
+
+// Update — обновляет поля подписки (ключи — имена колонок) и увеличивает версию;
+// при version > 0 — только если версия совпадает
+func (r *SubscriptionRepo) Update(ctx context.Context, id uuid.UUID, version int, fields map[string]any) (*models.Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.get(ctx, id)
+	if !ok || (version > 0 && s.Version != version) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	for k, v := range fields {
+		switch k {
+		case "service_name":
+			s.ServiceName = v.(string)
+		case "price":
+			s.Price = v.(int)
+		case "user_id":
+			s.UserID = v.(uuid.UUID)
+		case "start_date":
+			s.StartDate = v.(time.Time)
+		case "end_date":
+			if end, ok := v.(time.Time); ok {
+				s.EndDate = &end
+			} else {
+				s.EndDate = nil
+			}
+		case "category":
+			s.Category = v.(string)
+		}
+	}
+	if r.existsOverlap(ctx, s.UserID, s.ServiceName, s.StartDate, s.EndDate, &id) {
+		return nil, service.ErrOverlap
+	}
+	s.Version++
+	s.UpdatedAt = r.now().UTC()
+	r.subs[id] = s
+	return &s, nil
+}
+
+// FindActiveInPeriod — подписки, которые пересекают период [from, to]
+func (r *SubscriptionRepo) FindActiveInPeriod(ctx context.Context, from, to time.Time, f models.ListFilters) ([]models.Subscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var res []models.Subscription
+	for _, s := range r.subs {
+		if r.match(ctx, s, f) && overlaps(s, from, &to) {
+			res = append(res, s)
+		}
+	}
+	return res, nil
+}
+
+// ExistsOverlap — проверяет, есть ли пересечение по (user_id, service_name) с данным периодом
+func (r *SubscriptionRepo) ExistsOverlap(ctx context.Context, userID uuid.UUID, serviceName string, start time.Time, end *time.Time, excludeID *uuid.UUID) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.existsOverlap(ctx, userID, serviceName, start, end, excludeID), nil
+}
+
+// existsOverlap — ExistsOverlap для вызова под блокировкой
+func (r *SubscriptionRepo) existsOverlap(ctx context.Context, userID uuid.UUID, serviceName string, start time.Time, end *time.Time, excludeID *uuid.UUID) bool {
+	tenantID := tenant.FromContext(ctx)
+	for _, s := range r.subs {
+		if s.TenantID != tenantID || s.UserID != userID || (excludeID != nil && s.ID == *excludeID) {
+			continue
+		}
+		if strings.EqualFold(s.ServiceName, serviceName) && overlaps(s, start, end) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *SubscriptionRepo) get(ctx context.Context, id uuid.UUID) (models.Subscription, bool) {
+	s, ok := r.subs[id]
+	if !ok || s.TenantID != tenant.FromContext(ctx) {
+		return models.Subscription{}, false
+	}
+	return s, true
+}
+
+// match — фильтры арендатора, пользователя и подстроки названия (без учёта регистра, как ILIKE)
+func (r *SubscriptionRepo) match(ctx context.Context, s models.Subscription, f models.ListFilters) bool {
+	if s.TenantID != tenant.FromContext(ctx) {
+		return false
+	}
+	if f.UserID != nil && s.UserID != *f.UserID {
+		return false
+	}
+	return f.ServiceName == "" || strings.Contains(strings.ToLower(s.ServiceName), strings.ToLower(f.ServiceName))
+}
+
+// overlaps — пересекается ли период подписки с [start, end]; end == nil — без окончания
+func overlaps(s models.Subscription, start time.Time, end *time.Time) bool {
+	if end != nil && s.StartDate.After(*end) {
+		return false
+	}
+	return s.EndDate == nil || !s.EndDate.Before(start)
+}
+
+func containsID(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
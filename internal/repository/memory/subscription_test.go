@@ -0,0 +1,34 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/olesia8novoselova/Subscriptions/internal/repository/memory"
+	"github.com/olesia8novoselova/Subscriptions/internal/repository/repotest"
+)
+
+// TestSubscriptionRepo_Contract - тестирует соответствие общему контракту хранилищ
+func TestSubscriptionRepo_Contract(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Backend {
+		return repotest.Backend{Repo: memory.New()}
+	})
+}
+
+// TestSubscriptionRepo_DuplicateID - тестирует отказ при повторном создании подписки с тем же ID
+func TestSubscriptionRepo_DuplicateID(t *testing.T) {
+	repo := memory.New()
+	sub := &models.Subscription{
+		ID: uuid.New(), ServiceName: "Okko", Price: 199, UserID: uuid.New(),
+		StartDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if err := repo.Create(context.Background(), sub); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Create(context.Background(), sub); err == nil {
+		t.Error("duplicate ID accepted")
+	}
+}
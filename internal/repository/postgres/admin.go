@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"gorm.io/gorm"
+)
+
+// Обслуживающие операции (cmd/subsadmin). При dryRun изменения выполняются и откатываются,
+// так что возвращается точное число строк, которые были бы затронуты.
+
+// errDryRun — откатывает транзакцию пробного запуска
+var errDryRun = errors.New("dry run")
+
+// affected — выполняет fn в транзакции арендатора и возвращает число затронутых строк
+func affected(ctx context.Context, db *gorm.DB, dryRun bool, fn func(tx *gorm.DB, tenantID uuid.UUID) *gorm.DB) (int64, error) {
+	var n int64
+	err := withTenant(ctx, db, func(tx *gorm.DB, tenantID uuid.UUID) error {
+		res := fn(tx, tenantID)
+		if res.Error != nil {
+			return res.Error
+		}
+		n = res.RowsAffected
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, errDryRun) {
+		err = nil
+	}
+	return n, err
+}
+
+// FindOverlaps — пары подписок одного пользователя (владельца или участника) на один сервис
+// с пересекающимися периодами
+func (r *SubscriptionRepo) FindOverlaps(ctx context.Context) ([]models.OverlapConflict, error) {
+	var res []models.OverlapConflict
+	err := withTenant(ctx, r.db, func(tx *gorm.DB, tenantID uuid.UUID) error {
+		return tx.Raw(`
+WITH holders AS (
+    SELECT id AS subscription_id, user_id FROM subscriptions WHERE tenant_id = @tenant
+    UNION
+    SELECT m.subscription_id, m.user_id
+    FROM subscription_members m JOIN subscriptions s ON s.id = m.subscription_id
+    WHERE s.tenant_id = @tenant
+)
+SELECT ha.user_id, a.service_name,
+       a.id AS first_id, a.start_date AS first_start, a.end_date AS first_end,
+       b.id AS second_id, b.start_date AS second_start, b.end_date AS second_end
+FROM holders ha
+JOIN holders hb ON hb.user_id = ha.user_id AND hb.subscription_id > ha.subscription_id
+JOIN subscriptions a ON a.id = ha.subscription_id
+JOIN subscriptions b ON b.id = hb.subscription_id
+WHERE lower(a.service_name) = lower(b.service_name)
+  AND a.start_date <= COALESCE(b.end_date, 'infinity'::date)
+  AND b.start_date <= COALESCE(a.end_date, 'infinity'::date)
+ORDER BY ha.user_id, a.start_date, b.start_date`,
+			map[string]any{"tenant": tenantID}).Scan(&res).Error
+	})
+	return res, err
+}
+
+// EndService — завершает месяцем end все подписки на сервис (без учёта регистра),
+// которые начались не позже end и ещё активны после него. Возвращает ID изменённых подписок.
+func (r *SubscriptionRepo) EndService(ctx context.Context, serviceName string, end time.Time, dryRun bool) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	_, err := affected(ctx, r.db, dryRun, func(tx *gorm.DB, tenantID uuid.UUID) *gorm.DB {
+		return tx.Raw(`
+UPDATE subscriptions SET end_date = @end, version = version + 1, updated_at = now()
+WHERE tenant_id = @tenant AND lower(service_name) = lower(@service)
+  AND start_date <= @end AND (end_date IS NULL OR end_date > @end)
+RETURNING id`,
+			map[string]any{"tenant": tenantID, "service": serviceName, "end": end}).Scan(&ids)
+	})
+	return ids, err
+}
+
+// PurgeEnded — удаляет подписки, завершившиеся раньше месяца before, и возвращает их ID.
+// Запланированные изменения и участники удаляются каскадно.
+func (r *SubscriptionRepo) PurgeEnded(ctx context.Context, before time.Time, dryRun bool) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	_, err := affected(ctx, r.db, dryRun, func(tx *gorm.DB, tenantID uuid.UUID) *gorm.DB {
+		return tx.Raw(`DELETE FROM subscriptions WHERE tenant_id = ? AND end_date < ? RETURNING id`,
+			tenantID, before).Scan(&ids)
+	})
+	return ids, err
+}
+
+// PurgeExpired — удаляет просроченные ключи идемпотентности
+func (r *IdempotencyRepo) PurgeExpired(ctx context.Context, now time.Time, dryRun bool) (int64, error) {
+	return affected(ctx, r.db, dryRun, func(tx *gorm.DB, tenantID uuid.UUID) *gorm.DB {
+		return tx.Where("tenant_id = ? AND expires_at <= ?", tenantID, now).Delete(&models.IdempotencyKey{})
+	})
+}
+
+// PurgeRevoked — удаляет API-ключи, отозванные раньше before. Ключи не привязаны к политикам
+// арендаторов, поэтому удаляются по всем организациям.
+func (r *APIKeyRepo) PurgeRevoked(ctx context.Context, before time.Time, dryRun bool) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("revoked_at IS NOT NULL AND revoked_at < ?", before).Delete(&models.APIKey{})
+		if res.Error != nil {
+			return res.Error
+		}
+		n = res.RowsAffected
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, errDryRun) {
+		err = nil
+	}
+	return n, err
+}
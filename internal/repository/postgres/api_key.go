@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"gorm.io/gorm"
+)
+
+type APIKeyRepo struct {
+	db  *gorm.DB
+	log *slog.Logger
+}
+
+func NewAPIKeyRepo(db *gorm.DB, log *slog.Logger) *APIKeyRepo {
+	return &APIKeyRepo{db: db, log: log}
+}
+
+func (r *APIKeyRepo) Create(ctx context.Context, k *models.APIKey) error {
+	return r.db.WithContext(ctx).Create(k).Error
+}
+
+// FindByHash — действующий (не отозванный) ключ по хешу
+func (r *APIKeyRepo) FindByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.db.WithContext(ctx).
+		Where("key_hash = ? AND revoked_at IS NULL", hash).
+		First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &key, err
+}
+
+// Revoke — отзывает действующий ключ; уже отозванный или несуществующий ключ — gorm.ErrRecordNotFound
+func (r *APIKeyRepo) Revoke(ctx context.Context, id uuid.UUID, at time.Time) error {
+	res := r.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
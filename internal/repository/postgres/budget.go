@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"gorm.io/gorm"
+)
+
+type BudgetRepo struct {
+	db  *gorm.DB
+	log *slog.Logger
+}
+
+func NewBudgetRepo(db *gorm.DB, log *slog.Logger) *BudgetRepo {
+	return &BudgetRepo{db: db, log: log}
+}
+
+func (r *BudgetRepo) Create(ctx context.Context, b *models.Budget) error {
+	return withTenant(ctx, r.db, func(tx *gorm.DB, tenantID uuid.UUID) error {
+		b.TenantID = tenantID
+		return tx.Create(b).Error
+	})
+}
+
+// ListByUser — все бюджеты пользователя
+func (r *BudgetRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Budget, error) {
+	var res []models.Budget
+	err := withTenant(ctx, r.db, func(tx *gorm.DB, tenantID uuid.UUID) error {
+		return tx.Where("tenant_id = ? AND user_id = ?", tenantID, userID).
+			Order("created_at ASC").
+			Find(&res).Error
+	})
+	return res, err
+}
+
+// Delete — удаляет бюджет пользователя; чужой бюджет считается не найденным
+func (r *BudgetRepo) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	return withTenant(ctx, r.db, func(tx *gorm.DB, tenantID uuid.UUID) error {
+		res := tx.Delete(&models.Budget{}, "id = ? AND user_id = ? AND tenant_id = ?", id, userID, tenantID)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
@@ -0,0 +1,56 @@
+package postgres_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/olesia8novoselova/Subscriptions/internal/repository/postgres"
+	"github.com/olesia8novoselova/Subscriptions/internal/repository/repotest"
+	"github.com/olesia8novoselova/Subscriptions/migrations"
+)
+
+// TestSubscriptionRepo_Contract - тестирует соответствие общему контракту хранилищ на реальной PostgreSQL.
+// Запускается, только если задан TEST_POSTGRES_DSN; миграции применяются автоматически,
+// данные каждого подтеста создаются в отдельном арендаторе.
+func TestSubscriptionRepo_Contract(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+	db, err := postgres.Open(dsn, postgres.Pool{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	list, err := postgres.LoadMigrations(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := postgres.NewMigrator(db, list, log).Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	users := postgres.NewUserRepo(db, log)
+	repotest.Run(t, func(t *testing.T) repotest.Backend {
+		return repotest.Backend{
+			Repo: postgres.New(db),
+			CreateUser: func(ctx context.Context, id uuid.UUID) error {
+				return users.Create(ctx, &models.User{ID: id})
+			},
+		}
+	})
+
+	// функция из миграции доступна и считает подписки всех арендаторов
+	if _, err := postgres.New(db).CountActive(context.Background(), time.Now()); err != nil {
+		t.Errorf("CountActive: %v", err)
+	}
+}
@@ -0,0 +1,38 @@
+package postgres
+
+import (
+	"fmt"
+	"time"
+
+	gormpg "gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// Pool — размер пула соединений; 0 в MaxOpenConns — без ограничения
+type Pool struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// Open — подключение к PostgreSQL с проверкой доступности БД
+func Open(dsn string, pool Pool) (*gorm.DB, error) {
+	// TranslateError: нарушение уникальности возвращается как gorm.ErrDuplicatedKey, как в других хранилищах
+	db, err := gorm.Open(gormpg.Open(dsn), &gorm.Config{TranslateError: true})
+	if err != nil {
+		return nil, fmt.Errorf("gorm open: %w", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("get sql db: %w", err)
+	}
+	sqlDB.SetMaxOpenConns(pool.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(pool.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+	if err := sqlDB.Ping(); err != nil {
+		return nil, fmt.Errorf("ping db: %w", err)
+	}
+	return db, nil
+}
@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepo struct {
+	db  *gorm.DB
+	log *slog.Logger
+}
+
+func NewIdempotencyRepo(db *gorm.DB, log *slog.Logger) *IdempotencyRepo {
+	return &IdempotencyRepo{db: db, log: log}
+}
+
+// Reserve — вставляет ключ в состоянии «выполняется»; просроченная запись с тем же ключом
+// предварительно удаляется. Если действующий ключ уже есть, возвращает его.
+func (r *IdempotencyRepo) Reserve(ctx context.Context, rec *models.IdempotencyKey) (*models.IdempotencyKey, error) {
+	var existing *models.IdempotencyKey
+	err := withTenant(ctx, r.db, func(tx *gorm.DB, tenantID uuid.UUID) error {
+		rec.TenantID = tenantID
+		if err := tx.Delete(&models.IdempotencyKey{},
+			"tenant_id = ? AND scope = ? AND key = ? AND expires_at <= ?",
+			tenantID, rec.Scope, rec.Key, time.Now()).Error; err != nil {
+			return err
+		}
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(rec)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected > 0 {
+			return nil
+		}
+		var e models.IdempotencyKey
+		if err := tx.First(&e, "tenant_id = ? AND scope = ? AND key = ?", tenantID, rec.Scope, rec.Key).Error; err != nil {
+			return err
+		}
+		existing = &e
+		return nil
+	})
+	return existing, err
+}
+
+// Complete — сохраняет ответ на запрос
+func (r *IdempotencyRepo) Complete(ctx context.Context, rec *models.IdempotencyKey) error {
+	return withTenant(ctx, r.db, func(tx *gorm.DB, tenantID uuid.UUID) error {
+		return tx.Model(&models.IdempotencyKey{}).
+			Where("tenant_id = ? AND scope = ? AND key = ?", tenantID, rec.Scope, rec.Key).
+			Updates(map[string]any{
+				"status_code":      rec.StatusCode,
+				"response_body":    rec.ResponseBody,
+				"response_headers": rec.ResponseHeaders,
+			}).Error
+	})
+}
+
+// Release — удаляет незавершённый ключ
+func (r *IdempotencyRepo) Release(ctx context.Context, rec *models.IdempotencyKey) error {
+	return withTenant(ctx, r.db, func(tx *gorm.DB, tenantID uuid.UUID) error {
+		return tx.Delete(&models.IdempotencyKey{},
+			"tenant_id = ? AND scope = ? AND key = ? AND status_code = 0",
+			tenantID, rec.Scope, rec.Key).Error
+	})
+}
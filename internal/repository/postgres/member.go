@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"gorm.io/gorm"
+)
+
+type MemberRepo struct {
+	db  *gorm.DB
+	log *slog.Logger
+}
+
+func NewMemberRepo(db *gorm.DB, log *slog.Logger) *MemberRepo {
+	return &MemberRepo{db: db, log: log}
+}
+
+// Участники принадлежат арендатору через подписку, как и запланированные изменения
+
+func (r *MemberRepo) Create(ctx context.Context, m *models.SubscriptionMember) error {
+	return withTenant(ctx, r.db, func(tx *gorm.DB, tenantID uuid.UUID) error {
+		m.TenantID = tenantID
+		return tx.Create(m).Error
+	})
+}
+
+// ListBySubscriptions — участники набора подписок в порядке добавления
+func (r *MemberRepo) ListBySubscriptions(ctx context.Context, ids []uuid.UUID) ([]models.SubscriptionMember, error) {
+	var res []models.SubscriptionMember
+	if len(ids) == 0 {
+		return res, nil
+	}
+	err := withTenant(ctx, r.db, func(tx *gorm.DB, tenantID uuid.UUID) error {
+		return tx.
+			Where("subscription_id IN (?)", tenantSubscriptions(tx).
+				Where("tenant_id = ? AND id IN ?", tenantID, ids)).
+			Order("created_at ASC, id ASC").
+			Find(&res).Error
+	})
+	return res, err
+}
+
+// Delete — удаляет участника конкретной подписки
+func (r *MemberRepo) Delete(ctx context.Context, subscriptionID, id uuid.UUID) error {
+	return withTenant(ctx, r.db, func(tx *gorm.DB, tenantID uuid.UUID) error {
+		res := tx.Delete(&models.SubscriptionMember{},
+			"id = ? AND subscription_id = ? AND subscription_id IN (?)", id, subscriptionID,
+			tenantSubscriptions(tx).Where("tenant_id = ?", tenantID))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// memberSubscriptions — подзапрос подписок, в которых userID — участник
+func memberSubscriptions(tx *gorm.DB, userID uuid.UUID) *gorm.DB {
+	return tx.Session(&gorm.Session{NewDB: true}).
+		Model(&models.SubscriptionMember{}).
+		Select("subscription_id").
+		Where("user_id = ?", userID)
+}
@@ -0,0 +1,245 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Migration — пара файлов NNN_name.up.sql / NNN_name.down.sql
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+// String — имя миграции в виде NNN_name
+func (mg Migration) String() string {
+	return fmt.Sprintf("%03d_%s", mg.Version, mg.Name)
+}
+
+// LoadMigrations — миграции из каталога files, упорядоченные по версии.
+// У каждой миграции должны быть оба файла, версии не повторяются.
+func LoadMigrations(files fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(files, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[uint]*Migration)
+	for _, name := range names {
+		base, direction, ok := strings.Cut(strings.TrimSuffix(name, ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("migration %s: name must be NNN_name.up.sql or NNN_name.down.sql", name)
+		}
+		num, title, _ := strings.Cut(base, "_")
+		v, err := strconv.ParseUint(num, 10, 64)
+		if err != nil || v == 0 {
+			return nil, fmt.Errorf("migration %s: version must be a positive number", name)
+		}
+
+		m := byVersion[uint(v)]
+		if m == nil {
+			m = &Migration{Version: uint(v), Name: title}
+			byVersion[uint(v)] = m
+		} else if m.Name != title {
+			return nil, fmt.Errorf("migration %s: version %d is used by %q", name, v, m.Name)
+		}
+
+		body, err := fs.ReadFile(files, name)
+		if err != nil {
+			return nil, err
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	res := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %s: both up and down files are required", m)
+		}
+		res = append(res, *m)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Version < res[j].Version })
+	return res, nil
+}
+
+// ErrDirty — предыдущая миграция завершилась с ошибкой, схему нужно исправить вручную
+var ErrDirty = errors.New("database schema is dirty")
+
+// migrationLockID — ключ advisory-блокировки, под которой применяются миграции
+const migrationLockID = 0x5375627363 // "Subsc"
+
+// Migrator — применяет SQL-миграции. Текущая версия хранится в таблице schema_migrations
+// в том же формате, что у migrate/migrate, поэтому инструменты взаимозаменяемы.
+// Каждая миграция выполняется в отдельной транзакции под advisory-блокировкой вместе
+// с обновлением версии, поэтому несколько реплик, стартующих одновременно, не мешают друг другу:
+// остальные ждут блокировку и видят уже обновлённую версию.
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+	log        *slog.Logger
+}
+
+func NewMigrator(db *gorm.DB, migrations []Migration, log *slog.Logger) *Migrator {
+	return &Migrator{db: db, migrations: migrations, log: log}
+}
+
+// Version — применённая версия схемы; 0, если миграций ещё не было
+func (m *Migrator) Version(ctx context.Context) (version uint, dirty bool, err error) {
+	return readVersion(m.db.WithContext(ctx))
+}
+
+// Pending — миграции новее текущей версии
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	version, _, err := m.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var res []Migration
+	for _, mg := range m.migrations {
+		if mg.Version > version {
+			res = append(res, mg)
+		}
+	}
+	return res, nil
+}
+
+// Up — применяет все неприменённые миграции, возвращает их количество.
+// Если схема новее известных миграций (её обновила более новая версия приложения), ничего не делает.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	applied := 0
+	for _, mg := range m.migrations {
+		done := false
+		err := m.locked(ctx, func(tx *gorm.DB, version uint) error {
+			if mg.Version <= version {
+				return nil
+			}
+			// Без аргументов запрос уходит простым протоколом и может содержать несколько команд
+			if err := tx.Exec(mg.Up).Error; err != nil {
+				return err
+			}
+			done = true
+			return setVersion(tx, mg.Version)
+		})
+		if err != nil {
+			return applied, fmt.Errorf("migration %s up: %w", mg, err)
+		}
+		if done {
+			m.log.Info("migration applied", "version", mg.Version, "name", mg.Name)
+			applied++
+		}
+	}
+	return applied, nil
+}
+
+// Down — откатывает steps последних применённых миграций
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	reverted := 0
+	for reverted < steps {
+		var mg *Migration
+		err := m.locked(ctx, func(tx *gorm.DB, version uint) error {
+			if version == 0 {
+				return nil
+			}
+			i := m.index(version)
+			if i < 0 {
+				return fmt.Errorf("database version %d has no migration files", version)
+			}
+			mg = &m.migrations[i]
+			if err := tx.Exec(mg.Down).Error; err != nil {
+				return fmt.Errorf("migration %s down: %w", mg, err)
+			}
+			prev := uint(0)
+			if i > 0 {
+				prev = m.migrations[i-1].Version
+			}
+			return setVersion(tx, prev)
+		})
+		if err != nil {
+			return reverted, err
+		}
+		if mg == nil {
+			break
+		}
+		m.log.Info("migration reverted", "version", mg.Version, "name", mg.Name)
+		reverted++
+	}
+	return reverted, nil
+}
+
+// locked — выполняет fn в транзакции под advisory-блокировкой с текущей версией схемы
+func (m *Migrator) locked(ctx context.Context, fn func(tx *gorm.DB, version uint) error) error {
+	return m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationLockID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(
+			"CREATE TABLE IF NOT EXISTS schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)",
+		).Error; err != nil {
+			return err
+		}
+		version, dirty, err := readVersion(tx)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("%w at version %d", ErrDirty, version)
+		}
+		return fn(tx, version)
+	})
+}
+
+func (m *Migrator) index(version uint) int {
+	for i, mg := range m.migrations {
+		if mg.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+func readVersion(db *gorm.DB) (uint, bool, error) {
+	var exists bool
+	if err := db.Raw("SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists).Error; err != nil {
+		return 0, false, err
+	}
+	if !exists {
+		return 0, false, nil
+	}
+	var row struct {
+		Version int64
+		Dirty   bool
+	}
+	res := db.Raw("SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&row)
+	if res.Error != nil {
+		return 0, false, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return 0, false, nil
+	}
+	return uint(row.Version), row.Dirty, nil
+}
+
+// setVersion — записывает версию схемы; 0 — схема пуста
+func setVersion(tx *gorm.DB, version uint) error {
+	if err := tx.Exec("DELETE FROM schema_migrations").Error; err != nil {
+		return err
+	}
+	if version == 0 {
+		return nil
+	}
+	return tx.Exec("INSERT INTO schema_migrations (version, dirty) VALUES (?, false)", int64(version)).Error
+}
@@ -0,0 +1,59 @@
+package postgres_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/olesia8novoselova/Subscriptions/internal/repository/postgres"
+	"github.com/olesia8novoselova/Subscriptions/migrations"
+)
+
+// TestLoadMigrations - тестирует разбор и порядок файлов миграций
+func TestLoadMigrations(t *testing.T) {
+	files := fstest.MapFS{
+		"010_keys.up.sql":   {Data: []byte("CREATE TABLE keys ();")},
+		"010_keys.down.sql": {Data: []byte("DROP TABLE keys;")},
+		"002_b.up.sql":      {Data: []byte("SELECT 2;")},
+		"002_b.down.sql":    {Data: []byte("SELECT -2;")},
+		"001_a.up.sql":      {Data: []byte("SELECT 1;")},
+		"001_a.down.sql":    {Data: []byte("SELECT -1;")},
+		"README.md":         {Data: []byte("not a migration")},
+	}
+	got, err := postgres.LoadMigrations(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %d migrations, want 3", len(got))
+	}
+	for i, want := range []string{"001_a", "002_b", "010_keys"} {
+		if got[i].String() != want {
+			t.Errorf("migration %d = %s, want %s", i, got[i], want)
+		}
+	}
+	if got[2].Up != "CREATE TABLE keys ();" || got[2].Down != "DROP TABLE keys;" {
+		t.Errorf("migration 010 = %+v", got[2])
+	}
+
+	for name, files := range map[string]fstest.MapFS{
+		"missing down": {"001_a.up.sql": {Data: []byte("SELECT 1;")}},
+		"bad version":  {"x_a.up.sql": {}, "x_a.down.sql": {}},
+		"duplicate":    {"001_a.up.sql": {}, "001_a.down.sql": {}, "001_b.up.sql": {}, "001_b.down.sql": {}},
+		"bad suffix":   {"001_a.sql": {}},
+	} {
+		if _, err := postgres.LoadMigrations(files); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+// TestLoadMigrations_Embedded - тестирует, что встроенные миграции загружаются без ошибок
+func TestLoadMigrations_Embedded(t *testing.T) {
+	got, err := postgres.LoadMigrations(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) == 0 || got[0].Version != 1 {
+		t.Errorf("unexpected migrations: %v", got)
+	}
+}
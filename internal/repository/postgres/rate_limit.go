@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/olesia8novoselova/Subscriptions/internal/ratelimit"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// rateLimitSweepInterval — как часто реплика удаляет наполнившиеся корзины
+const rateLimitSweepInterval = time.Minute
+
+// RateLimitRepo — корзины ограничения частоты запросов в таблице rate_limit_buckets.
+// Корзина читается и обновляется под блокировкой строки, поэтому реплики расходуют токены согласованно.
+type RateLimitRepo struct {
+	db  *gorm.DB
+	log *slog.Logger
+	// swept — время последней очистки (UnixNano)
+	swept atomic.Int64
+}
+
+func NewRateLimitRepo(db *gorm.DB, log *slog.Logger) *RateLimitRepo {
+	r := &RateLimitRepo{db: db, log: log}
+	r.swept.Store(time.Now().UnixNano())
+	return r
+}
+
+// Take — расходует токен корзины key. Отсутствующая корзина сначала вставляется полной
+// (ON CONFLICT DO NOTHING), затем строка блокируется SELECT … FOR UPDATE и обновляется, так что
+// параллельные первые запросы одного клиента с разных реплик не перезаписывают расход друг друга.
+func (r *RateLimitRepo) Take(ctx context.Context, key string, l ratelimit.Limit) (ratelimit.Result, error) {
+	now := time.Now()
+	var res ratelimit.Result
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		full := models.RateLimitBucket{Key: key, Tokens: float64(l.Requests), RefilledAt: now, ExpiresAt: now}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&full).Error; err != nil {
+			return err
+		}
+
+		var row models.RateLimitBucket
+		q := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", key).Limit(1).Find(&row)
+		if q.Error != nil {
+			return q.Error
+		}
+		var prev *ratelimit.Bucket
+		if q.RowsAffected > 0 {
+			prev = &ratelimit.Bucket{Tokens: row.Tokens, Refilled: row.RefilledAt}
+		}
+
+		var next ratelimit.Bucket
+		next, res = ratelimit.Take(prev, l, now)
+		bucket := models.RateLimitBucket{
+			Key:        key,
+			Tokens:     next.Tokens,
+			RefilledAt: next.Refilled,
+			ExpiresAt:  now.Add(res.Reset),
+		}
+		if prev == nil {
+			// полную корзину удалила очистка другой реплики между вставкой и блокировкой
+			return tx.Create(&bucket).Error
+		}
+		return tx.Model(&models.RateLimitBucket{}).Where("key = ?", key).Updates(map[string]any{
+			"tokens":      bucket.Tokens,
+			"refilled_at": bucket.RefilledAt,
+			"expires_at":  bucket.ExpiresAt,
+		}).Error
+	})
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+	r.sweep(ctx, now)
+	return res, nil
+}
+
+// sweep — раз в rateLimitSweepInterval удаляет корзины, которые уже наполнились
+func (r *RateLimitRepo) sweep(ctx context.Context, now time.Time) {
+	last := r.swept.Load()
+	if now.Sub(time.Unix(0, last)) < rateLimitSweepInterval || !r.swept.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+	res := r.db.WithContext(ctx).Where("expires_at < ?", now).Delete(&models.RateLimitBucket{})
+	if res.Error != nil {
+		r.log.WarnContext(ctx, "rate limit sweep failed", "error", res.Error)
+		return
+	}
+	r.log.DebugContext(ctx, "rate limit buckets swept", "deleted", res.RowsAffected)
+}
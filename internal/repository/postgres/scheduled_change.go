@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"gorm.io/gorm"
+)
+
+type ScheduledChangeRepo struct {
+	db  *gorm.DB
+	log *slog.Logger
+}
+
+func NewScheduledChangeRepo(db *gorm.DB, log *slog.Logger) *ScheduledChangeRepo {
+	return &ScheduledChangeRepo{db: db, log: log}
+}
+
+// Изменения принадлежат арендатору через подписку: запросы выполняются в withTenant,
+// а политика RLS на scheduled_changes проверяет подписку
+
+func (r *ScheduledChangeRepo) Create(ctx context.Context, c *models.ScheduledChange) error {
+	return withTenant(ctx, r.db, func(tx *gorm.DB, _ uuid.UUID) error {
+		return tx.Create(c).Error
+	})
+}
+
+// ListBySubscriptions — изменения для набора подписок, упорядоченные по дате вступления в силу
+func (r *ScheduledChangeRepo) ListBySubscriptions(ctx context.Context, ids []uuid.UUID) ([]models.ScheduledChange, error) {
+	var res []models.ScheduledChange
+	if len(ids) == 0 {
+		return res, nil
+	}
+	err := withTenant(ctx, r.db, func(tx *gorm.DB, tenantID uuid.UUID) error {
+		return tx.
+			Where("subscription_id IN (?)", tenantSubscriptions(tx).
+				Where("tenant_id = ? AND id IN ?", tenantID, ids)).
+			Order("effective_date ASC, created_at ASC").
+			Find(&res).Error
+	})
+	return res, err
+}
+
+// Delete — удаляет изменение конкретной подписки
+func (r *ScheduledChangeRepo) Delete(ctx context.Context, subscriptionID, id uuid.UUID) error {
+	return withTenant(ctx, r.db, func(tx *gorm.DB, tenantID uuid.UUID) error {
+		res := tx.Delete(&models.ScheduledChange{},
+			"id = ? AND subscription_id = ? AND subscription_id IN (?)", id, subscriptionID,
+			tenantSubscriptions(tx).Where("tenant_id = ?", tenantID))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// tenantSubscriptions — подзапрос идентификаторов подписок в отдельном statement
+func tenantSubscriptions(tx *gorm.DB) *gorm.DB {
+	return tx.Session(&gorm.Session{NewDB: true}).Model(&models.Subscription{}).Select("id")
+}
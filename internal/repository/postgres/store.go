@@ -3,31 +3,54 @@ package postgres
 import (
 	"context"
 	"errors"
-	"log/slog"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/olesia8novoselova/Subscriptions/internal/service"
+	"github.com/olesia8novoselova/Subscriptions/pkg/logging"
 	"gorm.io/gorm"
 )
 
+// SubscriptionRepo — хранилище подписок; логирует через логгер запроса из контекста (logging.FromContext)
 type SubscriptionRepo struct {
-	db  *gorm.DB
-	log *slog.Logger
+	db *gorm.DB
 }
 
-func New(db *gorm.DB, log *slog.Logger) *SubscriptionRepo {
-	return &SubscriptionRepo{db: db, log: log}
+func New(db *gorm.DB) *SubscriptionRepo {
+	return &SubscriptionRepo{db: db}
 }
 
+// Все запросы ограничены арендатором из контекста (см. withTenant)
+
 func (r *SubscriptionRepo) Create(ctx context.Context, s *models.Subscription) error {
-	return r.db.WithContext(ctx).Create(s).Error
+	err := withTenant(ctx, r.db, func(tx *gorm.DB, tenantID uuid.UUID) error {
+		s.TenantID = tenantID
+		return tx.Create(s).Error
+	})
+	return overlapError(err)
+}
+
+// exclusionViolation — код ошибки PostgreSQL при нарушении ограничения EXCLUDE
+const exclusionViolation = "23P01"
+
+// overlapError — нарушение uniq_user_service_period (параллельная запись прошла ExistsOverlap раньше)
+// возвращается как service.ErrOverlap
+func overlapError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == exclusionViolation {
+		return service.ErrOverlap
+	}
+	return err
 }
 
 func (r *SubscriptionRepo) FindByID(ctx context.Context, id uuid.UUID) (*models.Subscription, error) {
 	var sub models.Subscription
-	err := r.db.WithContext(ctx).First(&sub, "id = ?", id).Error
+	err := withTenant(ctx, r.db, func(tx *gorm.DB, tenantID uuid.UUID) error {
+		return tx.First(&sub, "id = ? AND tenant_id = ?", id, tenantID).Error
+	})
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, gorm.ErrRecordNotFound
 	}
@@ -35,89 +58,141 @@ func (r *SubscriptionRepo) FindByID(ctx context.Context, id uuid.UUID) (*models.
 }
 
 func (r *SubscriptionRepo) List(ctx context.Context, f models.ListFilters) ([]models.Subscription, error) {
-	q := r.db.WithContext(ctx).Model(&models.Subscription{})
-
-	if f.UserID != nil {
-		q = q.Where("user_id = ?", *f.UserID)
-	}
-	if f.ServiceName != "" {
-		q = q.Where("service_name ILIKE ?", "%"+f.ServiceName+"%")
-	}
-
 	var res []models.Subscription
-	err := q.Order("start_date DESC, created_at DESC").
-		Limit(f.Limit).Offset(f.Offset).
-		Find(&res).Error
+	err := withTenant(ctx, r.db, func(tx *gorm.DB, tenantID uuid.UUID) error {
+		q := tx.Model(&models.Subscription{}).Where("tenant_id = ?", tenantID)
+
+		if f.UserID != nil {
+			q = q.Where("user_id = ?", *f.UserID)
+		}
+		if len(f.UserIDs) > 0 {
+			q = q.Where("user_id IN ?", f.UserIDs)
+		}
+		if f.ServiceName != "" {
+			q = q.Where("service_name ILIKE ?", "%"+f.ServiceName+"%")
+		}
+
+		return q.Order("start_date DESC, created_at DESC").
+			Limit(f.Limit).Offset(f.Offset).
+			Find(&res).Error
+	})
 	return res, err
 }
 
-func (r *SubscriptionRepo) Delete(ctx context.Context, id uuid.UUID) error {
-	res := r.db.WithContext(ctx).Delete(&models.Subscription{}, "id = ?", id)
-	if res.Error != nil {
-		return res.Error
-	}
-	if res.RowsAffected == 0 {
-		return gorm.ErrRecordNotFound
-	}
-	return nil
+// Delete — удаляет подписку; при version > 0 — только если версия совпадает
+func (r *SubscriptionRepo) Delete(ctx context.Context, id uuid.UUID, version int) error {
+	return withTenant(ctx, r.db, func(tx *gorm.DB, tenantID uuid.UUID) error {
+		q := tx.Where("id = ? AND tenant_id = ?", id, tenantID)
+		if version > 0 {
+			q = q.Where("version = ?", version)
+		}
+		res := q.Delete(&models.Subscription{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			logNotAffected(ctx, "delete", id, version)
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
 }
 
-func (r *SubscriptionRepo) Update(ctx context.Context, id uuid.UUID, fields map[string]any) (*models.Subscription, error) {
-	tx := r.db.WithContext(ctx).Model(&models.Subscription{}).Where("id = ?", id).Updates(fields)
-	if tx.Error != nil {
-		return nil, tx.Error
-	}
-	if tx.RowsAffected == 0 {
-		return nil, gorm.ErrRecordNotFound
-	}
+// Update — применяет изменения и увеличивает версию; при version > 0 — только если версия совпадает
+func (r *SubscriptionRepo) Update(ctx context.Context, id uuid.UUID, version int, fields map[string]any) (*models.Subscription, error) {
 	var sub models.Subscription
-	if err := r.db.WithContext(ctx).First(&sub, "id = ?", id).Error; err != nil {
-		return nil, err
+	err := withTenant(ctx, r.db, func(tx *gorm.DB, tenantID uuid.UUID) error {
+		q := tx.Model(&models.Subscription{}).Where("id = ? AND tenant_id = ?", id, tenantID)
+		if version > 0 {
+			q = q.Where("version = ?", version)
+		}
+		updates := make(map[string]any, len(fields)+1)
+		for k, v := range fields {
+			updates[k] = v
+		}
+		updates["version"] = gorm.Expr("version + 1")
+		res := q.Updates(updates)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			logNotAffected(ctx, "update", id, version)
+			return gorm.ErrRecordNotFound
+		}
+		return tx.First(&sub, "id = ? AND tenant_id = ?", id, tenantID).Error
+	})
+	if err != nil {
+		return nil, overlapError(err)
 	}
 	return &sub, nil
 }
 
 // FindActiveInPeriod — подписки, которые пересекают период [from, to].
 func (r *SubscriptionRepo) FindActiveInPeriod(ctx context.Context, from, to time.Time, f models.ListFilters) ([]models.Subscription, error) {
-	q := r.db.WithContext(ctx).Model(&models.Subscription{})
-
-	if f.UserID != nil {
-		q = q.Where("user_id = ?", *f.UserID)
-	}
-	if f.ServiceName != "" {
-		q = q.Where("service_name ILIKE ?", "%"+f.ServiceName+"%")
-	}
-
-	// Пересечение интервалов
-	q = q.Where("start_date <= ?", to).
-		Where("(end_date IS NULL OR end_date >= ?)", from)
-
 	var res []models.Subscription
-	if err := q.Find(&res).Error; err != nil {
+	err := withTenant(ctx, r.db, func(tx *gorm.DB, tenantID uuid.UUID) error {
+		q := tx.Model(&models.Subscription{}).Where("tenant_id = ?", tenantID)
+
+		switch {
+		case f.UserID != nil && f.IncludeShared:
+			q = q.Where("(user_id = ? OR id IN (?))", *f.UserID, memberSubscriptions(tx, *f.UserID))
+		case f.UserID != nil:
+			q = q.Where("user_id = ?", *f.UserID)
+		}
+		if f.ServiceName != "" {
+			q = q.Where("service_name ILIKE ?", "%"+f.ServiceName+"%")
+		}
+
+		// Пересечение интервалов
+		q = q.Where("start_date <= ?", to).
+			Where("(end_date IS NULL OR end_date >= ?)", from)
+
+		return q.Find(&res).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 	return res, nil
 }
 
-// ExistsOverlap — проверяет, есть ли пересечение по (user_id, service_name) с данным периодом
+// ExistsOverlap — проверяет, есть ли пересечение по (user_id, service_name) с данным периодом.
+// Учитываются и совместные подписки, в которых пользователь — участник.
 func (r *SubscriptionRepo) ExistsOverlap(ctx context.Context, userID uuid.UUID, serviceName string, start time.Time, end *time.Time, excludeID *uuid.UUID) (bool, error) {
 	var count int64
-	q := r.db.WithContext(ctx).Model(&models.Subscription{}).
-		Where("user_id = ?", userID).
-		Where("lower(service_name) = ?", strings.ToLower(serviceName)).
-		Where("start_date <= ?", coalesceEnd(end)).
-		Where("(end_date IS NULL OR end_date >= ?)", start)
-
-	if excludeID != nil {
-		q = q.Where("id <> ?", *excludeID)
-	}
-
-	if err := q.Count(&count).Error; err != nil {
+	err := withTenant(ctx, r.db, func(tx *gorm.DB, tenantID uuid.UUID) error {
+		q := tx.Model(&models.Subscription{}).
+			Where("tenant_id = ?", tenantID).
+			Where("(user_id = ? OR id IN (?))", userID, memberSubscriptions(tx, userID)).
+			Where("lower(service_name) = ?", strings.ToLower(serviceName)).
+			Where("start_date <= ?", coalesceEnd(end)).
+			Where("(end_date IS NULL OR end_date >= ?)", start)
+
+		if excludeID != nil {
+			q = q.Where("id <> ?", *excludeID)
+		}
+
+		return q.Count(&count).Error
+	})
+	if err != nil {
 		return false, err
 	}
 	return count > 0, nil
 }
 
+// CountActive — число подписок всех арендаторов, активных в месяце at
+func (r *SubscriptionRepo) CountActive(ctx context.Context, at time.Time) (int64, error) {
+	var n int64
+	month := time.Date(at.Year(), at.Month(), 1, 0, 0, 0, 0, time.UTC)
+	err := r.db.WithContext(ctx).Raw("SELECT count_active_subscriptions(?)", month).Scan(&n).Error
+	return n, err
+}
+
+// logNotAffected — подписка не найдена или её версия уже изменилась (конкурентная запись)
+func logNotAffected(ctx context.Context, op string, id uuid.UUID, version int) {
+	logging.FromContext(ctx).DebugContext(ctx, "subscription not affected",
+		"op", op, "subscription_id", id, "expected_version", version)
+}
+
 func coalesceEnd(end *time.Time) time.Time {
 	if end == nil {
 		// далеко в будущем, чтобы условие start_date <= end выполнялось для всех
@@ -0,0 +1,22 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/tenant"
+	"gorm.io/gorm"
+)
+
+// withTenant — выполняет fn в транзакции, где установлен app.tenant_id для политик
+// row-level security. Запросы внутри fn дополнительно фильтруются по tenantID явно,
+// чтобы изоляция не зависела от роли, под которой подключено приложение.
+func withTenant(ctx context.Context, db *gorm.DB, fn func(tx *gorm.DB, tenantID uuid.UUID) error) error {
+	tenantID := tenant.FromContext(ctx)
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT set_config('app.tenant_id', ?, true)", tenantID.String()).Error; err != nil {
+			return err
+		}
+		return fn(tx, tenantID)
+	})
+}
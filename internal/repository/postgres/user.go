@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type UserRepo struct {
+	db  *gorm.DB
+	log *slog.Logger
+}
+
+func NewUserRepo(db *gorm.DB, log *slog.Logger) *UserRepo {
+	return &UserRepo{db: db, log: log}
+}
+
+func (r *UserRepo) Create(ctx context.Context, u *models.User) error {
+	return withTenant(ctx, r.db, func(tx *gorm.DB, tenantID uuid.UUID) error {
+		u.TenantID = tenantID
+		return tx.Create(u).Error
+	})
+}
+
+// Provision — создает пользователя без имени, если его ещё нет
+func (r *UserRepo) Provision(ctx context.Context, id uuid.UUID) error {
+	return withTenant(ctx, r.db, func(tx *gorm.DB, tenantID uuid.UUID) error {
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.User{ID: id, TenantID: tenantID}).Error
+	})
+}
+
+func (r *UserRepo) FindByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	var u models.User
+	err := withTenant(ctx, r.db, func(tx *gorm.DB, tenantID uuid.UUID) error {
+		return tx.First(&u, "id = ? AND tenant_id = ?", id, tenantID).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &u, err
+}
+
+func (r *UserRepo) List(ctx context.Context, limit, offset int) ([]models.User, error) {
+	var res []models.User
+	err := withTenant(ctx, r.db, func(tx *gorm.DB, tenantID uuid.UUID) error {
+		return tx.Where("tenant_id = ?", tenantID).
+			Order("name ASC, created_at ASC").
+			Limit(limit).Offset(offset).
+			Find(&res).Error
+	})
+	return res, err
+}
+
+func (r *UserRepo) Update(ctx context.Context, id uuid.UUID, fields map[string]any) (*models.User, error) {
+	var u models.User
+	err := withTenant(ctx, r.db, func(tx *gorm.DB, tenantID uuid.UUID) error {
+		res := tx.Model(&models.User{}).Where("id = ? AND tenant_id = ?", id, tenantID).Updates(fields)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.First(&u, "id = ? AND tenant_id = ?", id, tenantID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// Delete — удаляет пользователя; бюджеты и участие в чужих подписках удаляются внешним ключом (ON DELETE CASCADE).
+// При политике block оставшиеся подписки не дают удалить пользователя (ON DELETE RESTRICT).
+// Пересечение с подписками нового владельца при reassign возвращается как service.ErrOverlap.
+func (r *UserRepo) Delete(ctx context.Context, id uuid.UUID, policy string, reassignTo uuid.UUID) error {
+	err := withTenant(ctx, r.db, func(tx *gorm.DB, tenantID uuid.UUID) error {
+		subs := tx.Model(&models.Subscription{}).Where("user_id = ? AND tenant_id = ?", id, tenantID)
+		switch policy {
+		case models.UserDeleteCascade:
+			if err := subs.Delete(&models.Subscription{}).Error; err != nil {
+				return err
+			}
+		case models.UserDeleteReassign:
+			// новый владелец перестаёт быть участником переданных ему подписок
+			owned := tenantSubscriptions(tx).Where("user_id = ? AND tenant_id = ?", id, tenantID)
+			if err := tx.Delete(&models.SubscriptionMember{}, "user_id = ? AND subscription_id IN (?)", reassignTo, owned).Error; err != nil {
+				return err
+			}
+			if err := subs.Updates(map[string]any{"user_id": reassignTo, "version": gorm.Expr("version + 1")}).Error; err != nil {
+				return err
+			}
+		}
+
+		res := tx.Delete(&models.User{}, "id = ? AND tenant_id = ?", id, tenantID)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	return overlapError(err)
+}
@@ -0,0 +1,438 @@
+// Package repotest — общий набор тестов, которому должна соответствовать любая реализация
+// service.SubscriptionRepository: семантика периодов, пересечений, версий и изоляции арендаторов.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/olesia8novoselova/Subscriptions/internal/service"
+	"github.com/olesia8novoselova/Subscriptions/internal/tenant"
+	"gorm.io/gorm"
+)
+
+// Backend — хранилище для одного теста
+type Backend struct {
+	Repo service.SubscriptionRepository
+	// CreateUser — создаёт владельца подписок, если хранилище проверяет внешний ключ user_id; nil — не нужно
+	CreateUser func(ctx context.Context, id uuid.UUID) error
+}
+
+// Run — запускает набор тестов. Каждый подтест работает в собственном арендаторе,
+// поэтому хранилище может быть общим для всех подтестов.
+func Run(t *testing.T, newBackend func(t *testing.T) Backend) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, f *fixture)
+	}{
+		{"CreateAndFind", testCreateAndFind},
+		{"TenantIsolation", testTenantIsolation},
+		{"Update", testUpdate},
+		{"Delete", testDelete},
+		{"List", testList},
+		{"FindActiveInPeriod", testFindActiveInPeriod},
+		{"ExistsOverlap", testExistsOverlap},
+		{"OverlapOnWrite", testOverlapOnWrite},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBackend(t)
+			f := &fixture{
+				t:      t,
+				ctx:    tenant.WithTenant(context.Background(), uuid.New()),
+				repo:   b.Repo,
+				create: b.CreateUser,
+			}
+			tt.fn(t, f)
+		})
+	}
+}
+
+type fixture struct {
+	t      *testing.T
+	ctx    context.Context
+	repo   service.SubscriptionRepository
+	create func(ctx context.Context, id uuid.UUID) error
+}
+
+// user — новый пользователь арендатора теста
+func (f *fixture) user() uuid.UUID {
+	f.t.Helper()
+	id := uuid.New()
+	if f.create != nil {
+		if err := f.create(f.ctx, id); err != nil {
+			f.t.Fatalf("create user: %v", err)
+		}
+	}
+	return id
+}
+
+// add — сохраняет подписку; start и end — месяцы в формате MM-YYYY, пустой end — без окончания
+func (f *fixture) add(userID uuid.UUID, serviceName, start, end string) *models.Subscription {
+	f.t.Helper()
+	s := &models.Subscription{
+		ID:          uuid.New(),
+		ServiceName: serviceName,
+		Price:       100,
+		UserID:      userID,
+		StartDate:   month(f.t, start),
+	}
+	if end != "" {
+		e := month(f.t, end)
+		s.EndDate = &e
+	}
+	if err := f.repo.Create(f.ctx, s); err != nil {
+		f.t.Fatalf("create %s %s—%s: %v", serviceName, start, end, err)
+	}
+	return s
+}
+
+func month(t *testing.T, s string) time.Time {
+	t.Helper()
+	m, err := time.Parse("01-2006", s)
+	if err != nil {
+		t.Fatalf("bad month %q", s)
+	}
+	return m
+}
+
+func monthPtr(t *testing.T, s string) *time.Time {
+	if s == "" {
+		return nil
+	}
+	m := month(t, s)
+	return &m
+}
+
+func ids(subs []models.Subscription) []uuid.UUID {
+	res := make([]uuid.UUID, len(subs))
+	for i, s := range subs {
+		res[i] = s.ID
+	}
+	return res
+}
+
+func sameIDs(got []models.Subscription, want ...*models.Subscription) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	seen := make(map[uuid.UUID]bool, len(got))
+	for _, s := range got {
+		seen[s.ID] = true
+	}
+	for _, s := range want {
+		if !seen[s.ID] {
+			return false
+		}
+	}
+	return true
+}
+
+func testCreateAndFind(t *testing.T, f *fixture) {
+	owner := f.user()
+	sub := f.add(owner, "Yandex Plus", "07-2025", "12-2025")
+	sub2 := f.add(owner, "Netflix", "01-2025", "")
+
+	got, err := f.repo.FindByID(f.ctx, sub.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ServiceName != "Yandex Plus" || got.Price != 100 || got.UserID != owner || got.Version != 1 {
+		t.Errorf("got %+v", got)
+	}
+	if !got.StartDate.Equal(month(t, "07-2025")) || got.EndDate == nil || !got.EndDate.Equal(month(t, "12-2025")) {
+		t.Errorf("period = %v—%v", got.StartDate, got.EndDate)
+	}
+
+	got, err = f.repo.FindByID(f.ctx, sub2.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.EndDate != nil {
+		t.Errorf("end_date = %v, want nil", got.EndDate)
+	}
+
+	if _, err := f.repo.FindByID(f.ctx, uuid.New()); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("missing subscription: err = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func testTenantIsolation(t *testing.T, f *fixture) {
+	owner := f.user()
+	sub := f.add(owner, "Okko", "01-2025", "")
+	other := tenant.WithTenant(context.Background(), uuid.New())
+
+	if _, err := f.repo.FindByID(other, sub.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("FindByID from another tenant: err = %v", err)
+	}
+	if list, err := f.repo.List(other, models.ListFilters{Limit: -1}); err != nil || len(list) != 0 {
+		t.Errorf("List from another tenant = %v, %v", ids(list), err)
+	}
+	if list, err := f.repo.FindActiveInPeriod(other, month(t, "01-2025"), month(t, "12-2025"), models.ListFilters{}); err != nil || len(list) != 0 {
+		t.Errorf("FindActiveInPeriod from another tenant = %v, %v", ids(list), err)
+	}
+	if ok, err := f.repo.ExistsOverlap(other, owner, "Okko", month(t, "01-2025"), nil, nil); err != nil || ok {
+		t.Errorf("ExistsOverlap from another tenant = %v, %v", ok, err)
+	}
+	if _, err := f.repo.Update(other, sub.ID, 0, map[string]any{"price": 1}); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Update from another tenant: err = %v", err)
+	}
+	if err := f.repo.Delete(other, sub.ID, 0); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Delete from another tenant: err = %v", err)
+	}
+	// ID подписки уникален глобально: занятый в другом арендаторе ID не создаётся повторно
+	if f.create != nil {
+		if err := f.create(other, owner); err != nil {
+			t.Fatalf("create user in another tenant: %v", err)
+		}
+	}
+	dup := &models.Subscription{ID: sub.ID, ServiceName: "Okko", Price: 100, UserID: owner, StartDate: month(t, "01-2025")}
+	if err := f.repo.Create(other, dup); !errors.Is(err, gorm.ErrDuplicatedKey) {
+		t.Errorf("Create with ID taken in another tenant: err = %v", err)
+	}
+	if _, err := f.repo.FindByID(f.ctx, sub.ID); err != nil {
+		t.Errorf("subscription changed by another tenant: %v", err)
+	}
+}
+
+func testUpdate(t *testing.T, f *fixture) {
+	sub := f.add(f.user(), "Spotify", "01-2025", "06-2025")
+
+	got, err := f.repo.Update(f.ctx, sub.ID, 1, map[string]any{
+		"service_name": "Spotify Family",
+		"price":        250,
+		"start_date":   month(t, "02-2025"),
+		"end_date":     nil,
+		"category":     "music",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ServiceName != "Spotify Family" || got.Price != 250 || got.Category != "music" || got.Version != 2 {
+		t.Errorf("updated %+v", got)
+	}
+	if !got.StartDate.Equal(month(t, "02-2025")) || got.EndDate != nil {
+		t.Errorf("updated period = %v—%v", got.StartDate, got.EndDate)
+	}
+
+	if _, err := f.repo.Update(f.ctx, sub.ID, 1, map[string]any{"price": 300}); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("stale version: err = %v, want ErrRecordNotFound", err)
+	}
+
+	// version 0 — без проверки версии
+	got, err = f.repo.Update(f.ctx, sub.ID, 0, map[string]any{"end_date": month(t, "12-2025")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != 3 || got.EndDate == nil || !got.EndDate.Equal(month(t, "12-2025")) || got.Price != 250 {
+		t.Errorf("updated %+v", got)
+	}
+
+	stored, err := f.repo.FindByID(f.ctx, sub.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Version != 3 || stored.Price != 250 {
+		t.Errorf("stored %+v", stored)
+	}
+
+	if _, err := f.repo.Update(f.ctx, uuid.New(), 0, map[string]any{"price": 1}); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("missing subscription: err = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func testDelete(t *testing.T, f *fixture) {
+	owner := f.user()
+	a := f.add(owner, "Okko", "01-2025", "")
+	b := f.add(owner, "Ivi", "01-2025", "")
+
+	if err := f.repo.Delete(f.ctx, a.ID, 2); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("stale version: err = %v, want ErrRecordNotFound", err)
+	}
+	if err := f.repo.Delete(f.ctx, a.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.repo.Delete(f.ctx, b.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.repo.Delete(f.ctx, a.ID, 0); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("second delete: err = %v, want ErrRecordNotFound", err)
+	}
+	if _, err := f.repo.FindByID(f.ctx, a.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("deleted subscription found: %v", err)
+	}
+}
+
+func testList(t *testing.T, f *fixture) {
+	alice, bob, carol := f.user(), f.user(), f.user()
+	a1 := f.add(alice, "Yandex Plus", "01-2025", "")
+	a2 := f.add(alice, "Netflix", "03-2025", "")
+	a3 := f.add(alice, "yandex music", "02-2025", "")
+	b1 := f.add(bob, "Yandex Plus", "05-2025", "")
+	c1 := f.add(carol, "Okko", "04-2025", "")
+
+	// порядок — по start_date от новых к старым
+	got, err := f.repo.List(f.ctx, models.ListFilters{Limit: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []uuid.UUID{b1.ID, c1.ID, a2.ID, a3.ID, a1.ID}
+	if len(got) != len(want) {
+		t.Fatalf("List = %v, want %v", ids(got), want)
+	}
+	for i := range want {
+		if got[i].ID != want[i] {
+			t.Fatalf("List order = %v, want %v", ids(got), want)
+		}
+	}
+
+	page, err := f.repo.List(f.ctx, models.ListFilters{Limit: 2, Offset: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 2 || page[0].ID != c1.ID || page[1].ID != a2.ID {
+		t.Errorf("page = %v, want [%s %s]", ids(page), c1.ID, a2.ID)
+	}
+
+	tests := []struct {
+		name string
+		f    models.ListFilters
+		want []*models.Subscription
+	}{
+		{"user", models.ListFilters{UserID: &alice}, []*models.Subscription{a1, a2, a3}},
+		{"service substring ignores case", models.ListFilters{ServiceName: "YANDEX"}, []*models.Subscription{a1, a3, b1}},
+		{"user and service", models.ListFilters{UserID: &alice, ServiceName: "plus"}, []*models.Subscription{a1}},
+		{"user ids", models.ListFilters{UserIDs: []uuid.UUID{bob, carol}}, []*models.Subscription{b1, c1}},
+		{"no match", models.ListFilters{ServiceName: "spotify"}, nil},
+	}
+	for _, tt := range tests {
+		tt.f.Limit = -1
+		got, err := f.repo.List(f.ctx, tt.f)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !sameIDs(got, tt.want...) {
+			t.Errorf("%s: List = %v", tt.name, ids(got))
+		}
+	}
+}
+
+func testFindActiveInPeriod(t *testing.T, f *fixture) {
+	alice, bob := f.user(), f.user()
+	f.add(alice, "A", "01-2025", "03-2025")
+	endsAtFrom := f.add(alice, "B", "01-2025", "04-2025")
+	inside := f.add(alice, "C", "05-2025", "05-2025")
+	startsAtTo := f.add(alice, "D", "06-2025", "")
+	f.add(alice, "E", "07-2025", "")
+	openEnded := f.add(bob, "F", "01-2024", "")
+
+	from, to := month(t, "04-2025"), month(t, "06-2025")
+	got, err := f.repo.FindActiveInPeriod(f.ctx, from, to, models.ListFilters{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// границы периода включаются: месяц окончания и месяц начала считаются оплаченными
+	if !sameIDs(got, endsAtFrom, inside, startsAtTo, openEnded) {
+		t.Errorf("FindActiveInPeriod = %v", ids(got))
+	}
+
+	got, err = f.repo.FindActiveInPeriod(f.ctx, from, to, models.ListFilters{UserID: &bob})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameIDs(got, openEnded) {
+		t.Errorf("FindActiveInPeriod(user) = %v", ids(got))
+	}
+
+	got, err = f.repo.FindActiveInPeriod(f.ctx, from, to, models.ListFilters{ServiceName: "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameIDs(got, inside) {
+		t.Errorf("FindActiveInPeriod(service) = %v", ids(got))
+	}
+}
+
+func testExistsOverlap(t *testing.T, f *fixture) {
+	alice, bob := f.user(), f.user()
+	closed := f.add(alice, "Yandex Plus", "03-2025", "05-2025")
+	open := f.add(alice, "Netflix", "06-2025", "")
+
+	tests := []struct {
+		name       string
+		user       uuid.UUID
+		service    string
+		start, end string
+		exclude    *uuid.UUID
+		want       bool
+	}{
+		{"same period", alice, "Yandex Plus", "03-2025", "05-2025", nil, true},
+		{"inside", alice, "Yandex Plus", "04-2025", "04-2025", nil, true},
+		{"touches start month", alice, "Yandex Plus", "01-2025", "03-2025", nil, true},
+		{"touches end month", alice, "Yandex Plus", "05-2025", "", nil, true},
+		{"ends before", alice, "Yandex Plus", "01-2025", "02-2025", nil, false},
+		{"starts after", alice, "Yandex Plus", "06-2025", "", nil, false},
+		{"open-ended new covers old", alice, "Yandex Plus", "01-2024", "", nil, true},
+		{"case-insensitive name", alice, "YANDEX PLUS", "04-2025", "", nil, true},
+		{"different service", alice, "Yandex Music", "04-2025", "", nil, false},
+		{"different user", bob, "Yandex Plus", "04-2025", "", nil, false},
+		{"excluded itself", alice, "Yandex Plus", "04-2025", "", &closed.ID, false},
+		{"open-ended existing", alice, "Netflix", "01-2030", "01-2030", nil, true},
+		{"before open-ended existing", alice, "Netflix", "01-2025", "05-2025", nil, false},
+		{"open-ended excluded itself", alice, "Netflix", "07-2025", "", &open.ID, false},
+	}
+	for _, tt := range tests {
+		got, err := f.repo.ExistsOverlap(f.ctx, tt.user, tt.service, month(t, tt.start), monthPtr(t, tt.end), tt.exclude)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: ExistsOverlap = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// testOverlapOnWrite — Create и Update сами отклоняют пересечение (service.ErrOverlap),
+// поэтому из параллельных запросов, прошедших ExistsOverlap, сохраняется только один
+func testOverlapOnWrite(t *testing.T, f *fixture) {
+	owner := f.user()
+	start := month(t, "01-2025")
+
+	const n = 8
+	errs := make(chan error, n)
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- f.repo.Create(f.ctx, &models.Subscription{ID: uuid.New(), ServiceName: "Kion", Price: 100, UserID: owner, StartDate: start})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	created := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case !errors.Is(err, service.ErrOverlap):
+			t.Errorf("concurrent Create: err = %v", err)
+		}
+	}
+	if created != 1 {
+		t.Errorf("concurrent Create: %d subscriptions created, want 1", created)
+	}
+
+	earlier := f.add(owner, "Kion", "01-2024", "06-2024")
+	if _, err := f.repo.Update(f.ctx, earlier.ID, 0, map[string]any{"end_date": month(t, "03-2025")}); !errors.Is(err, service.ErrOverlap) {
+		t.Errorf("Update into overlap: err = %v", err)
+	}
+	if got, err := f.repo.FindByID(f.ctx, earlier.ID); err != nil || got.Version != 1 {
+		t.Errorf("rejected Update changed subscription: %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,243 @@
+// Package sqlite — хранилище подписок в SQLite для локальной разработки и тестов без PostgreSQL.
+package sqlite
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	gosqlite "github.com/glebarez/go-sqlite"
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/olesia8novoselova/Subscriptions/internal/service"
+	"github.com/olesia8novoselova/Subscriptions/internal/tenant"
+	"gorm.io/gorm"
+)
+
+// lower() в SQLite меняет регистр только у ASCII, поэтому для названий сервисов
+// (в том числе кириллических) используется casefold на основе strings.ToLower
+func init() {
+	gosqlite.MustRegisterDeterministicScalarFunction("casefold", 1, func(_ *gosqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		switch v := args[0].(type) {
+		case string:
+			return strings.ToLower(v), nil
+		case []byte:
+			return strings.ToLower(string(v)), nil
+		}
+		return args[0], nil
+	})
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS subscriptions (
+    id TEXT PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    service_name TEXT NOT NULL,
+    price INTEGER NOT NULL CHECK (price > 0),
+    user_id TEXT NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NULL,
+    category TEXT NOT NULL DEFAULT '',
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_subscriptions_tenant_user ON subscriptions (tenant_id, user_id);
+`
+
+// Open — открывает (или создаёт) базу SQLite и таблицу подписок; ":memory:" — база в памяти.
+// Даты хранятся текстом в UTC, поэтому строки сравниваются в хронологическом порядке;
+// тип колонок DATE/DATETIME нужен драйверу, чтобы читать их как time.Time.
+func Open(path string) (*gorm.DB, error) {
+	// _txlock=immediate: транзакция сразу берёт блокировку записи, поэтому проверка пересечений
+	// и запись в Create и Update не чередуются с другими транзакциями
+	dsn := path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		NowFunc:        func() time.Time { return time.Now().UTC() },
+		TranslateError: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gorm open: %w", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("get sql db: %w", err)
+	}
+	if path == ":memory:" {
+		// у каждого соединения своя база в памяти
+		sqlDB.SetMaxOpenConns(1)
+	}
+	if err := db.Exec(schema).Error; err != nil {
+		return nil, fmt.Errorf("create schema: %w", err)
+	}
+	return db, nil
+}
+
+type SubscriptionRepo struct {
+	db  *gorm.DB
+	log *slog.Logger
+}
+
+func New(db *gorm.DB, log *slog.Logger) *SubscriptionRepo {
+	return &SubscriptionRepo{db: db, log: log}
+}
+
+// Все запросы ограничены арендатором из контекста
+
+func (r *SubscriptionRepo) Create(ctx context.Context, s *models.Subscription) error {
+	now := time.Now().UTC()
+	s.TenantID = tenant.FromContext(ctx)
+	s.Version = 1
+	// у колонок в модели default:now(), поэтому нулевые значения GORM не передал бы
+	s.CreatedAt, s.UpdatedAt = now, now
+	s.StartDate = s.StartDate.UTC()
+	if s.EndDate != nil {
+		end := s.EndDate.UTC()
+		s.EndDate = &end
+	}
+	// пересечение проверяется в той же транзакции, как ограничение EXCLUDE в PostgreSQL
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		overlap, err := existsOverlap(tx, s.TenantID, s.UserID, s.ServiceName, s.StartDate, s.EndDate, nil)
+		if err != nil {
+			return err
+		}
+		if overlap {
+			return service.ErrOverlap
+		}
+		return tx.Create(s).Error
+	})
+}
+
+func (r *SubscriptionRepo) FindByID(ctx context.Context, id uuid.UUID) (*models.Subscription, error) {
+	var sub models.Subscription
+	err := r.db.WithContext(ctx).First(&sub, "id = ? AND tenant_id = ?", id, tenant.FromContext(ctx)).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &sub, err
+}
+
+func (r *SubscriptionRepo) List(ctx context.Context, f models.ListFilters) ([]models.Subscription, error) {
+	var res []models.Subscription
+	q := r.filtered(ctx, f)
+	if len(f.UserIDs) > 0 {
+		q = q.Where("user_id IN ?", f.UserIDs)
+	}
+	err := q.Order("start_date DESC, created_at DESC").
+		Limit(f.Limit).Offset(f.Offset).
+		Find(&res).Error
+	return res, err
+}
+
+// Delete — удаляет подписку; при version > 0 — только если версия совпадает
+func (r *SubscriptionRepo) Delete(ctx context.Context, id uuid.UUID, version int) error {
+	q := r.db.WithContext(ctx).Where("id = ? AND tenant_id = ?", id, tenant.FromContext(ctx))
+	if version > 0 {
+		q = q.Where("version = ?", version)
+	}
+	res := q.Delete(&models.Subscription{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Update — обновляет поля подписки и увеличивает версию; при version > 0 — только если версия совпадает
+func (r *SubscriptionRepo) Update(ctx context.Context, id uuid.UUID, version int, fields map[string]any) (*models.Subscription, error) {
+	var sub models.Subscription
+	tenantID := tenant.FromContext(ctx)
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		q := tx.Model(&models.Subscription{}).Where("id = ? AND tenant_id = ?", id, tenantID)
+		if version > 0 {
+			q = q.Where("version = ?", version)
+		}
+		updates := make(map[string]any, len(fields)+1)
+		for k, v := range fields {
+			if t, ok := v.(time.Time); ok {
+				v = t.UTC()
+			}
+			updates[k] = v
+		}
+		updates["version"] = gorm.Expr("version + 1")
+		res := q.Updates(updates)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.First(&sub, "id = ? AND tenant_id = ?", id, tenantID).Error; err != nil {
+			return err
+		}
+		overlap, err := existsOverlap(tx, tenantID, sub.UserID, sub.ServiceName, sub.StartDate, sub.EndDate, &id)
+		if err != nil {
+			return err
+		}
+		if overlap {
+			return service.ErrOverlap
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// FindActiveInPeriod — подписки, которые пересекают период [from, to]
+func (r *SubscriptionRepo) FindActiveInPeriod(ctx context.Context, from, to time.Time, f models.ListFilters) ([]models.Subscription, error) {
+	var res []models.Subscription
+	err := r.filtered(ctx, f).
+		Where("start_date <= ?", to.UTC()).
+		Where("(end_date IS NULL OR end_date >= ?)", from.UTC()).
+		Find(&res).Error
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ExistsOverlap — проверяет, есть ли пересечение по (user_id, service_name) с данным периодом.
+// Участники совместных подписок в SQLite не хранятся, поэтому учитывается только владелец.
+func (r *SubscriptionRepo) ExistsOverlap(ctx context.Context, userID uuid.UUID, serviceName string, start time.Time, end *time.Time, excludeID *uuid.UUID) (bool, error) {
+	return existsOverlap(r.db.WithContext(ctx), tenant.FromContext(ctx), userID, serviceName, start, end, excludeID)
+}
+
+// existsOverlap — ExistsOverlap в транзакции tx
+func existsOverlap(tx *gorm.DB, tenantID, userID uuid.UUID, serviceName string, start time.Time, end *time.Time, excludeID *uuid.UUID) (bool, error) {
+	var count int64
+	q := tx.Model(&models.Subscription{}).
+		Where("tenant_id = ? AND user_id = ?", tenantID, userID).
+		Where("casefold(service_name) = ?", strings.ToLower(serviceName)).
+		Where("(end_date IS NULL OR end_date >= ?)", start.UTC())
+	if end != nil {
+		q = q.Where("start_date <= ?", end.UTC())
+	}
+	if excludeID != nil {
+		q = q.Where("id <> ?", *excludeID)
+	}
+	if err := q.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// filtered — запрос подписок арендатора с фильтрами пользователя и подстроки названия (как ILIKE)
+func (r *SubscriptionRepo) filtered(ctx context.Context, f models.ListFilters) *gorm.DB {
+	q := r.db.WithContext(ctx).Model(&models.Subscription{}).Where("tenant_id = ?", tenant.FromContext(ctx))
+	if f.UserID != nil {
+		q = q.Where("user_id = ?", *f.UserID)
+	}
+	if f.ServiceName != "" {
+		q = q.Where("casefold(service_name) LIKE ?", "%"+strings.ToLower(f.ServiceName)+"%")
+	}
+	return q
+}
@@ -0,0 +1,95 @@
+package sqlite_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/olesia8novoselova/Subscriptions/internal/repository/repotest"
+	"github.com/olesia8novoselova/Subscriptions/internal/repository/sqlite"
+	"github.com/olesia8novoselova/Subscriptions/internal/tenant"
+)
+
+func newRepo(t *testing.T) *sqlite.SubscriptionRepo {
+	t.Helper()
+	return openRepo(t, ":memory:")
+}
+
+func openRepo(t *testing.T, path string) *sqlite.SubscriptionRepo {
+	t.Helper()
+	db, err := sqlite.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	return sqlite.New(db, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func month(y int, m time.Month) time.Time {
+	return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// TestSubscriptionRepo_RoundTrip - тестирует сохранение дат, версий и UUID в SQLite
+func TestSubscriptionRepo_RoundTrip(t *testing.T) {
+	repo := newRepo(t)
+	ctx := context.Background()
+	end := month(2025, 12)
+	sub := &models.Subscription{
+		ID: uuid.New(), ServiceName: "Кинопоиск", Price: 299, UserID: uuid.New(),
+		StartDate: month(2025, 7), EndDate: &end, Category: "video",
+	}
+	if err := repo.Create(ctx, sub); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := repo.FindByID(ctx, sub.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.UserID != sub.UserID || !got.StartDate.Equal(sub.StartDate) || got.EndDate == nil || !got.EndDate.Equal(end) || got.Version != 1 {
+		t.Errorf("got %+v", got)
+	}
+
+	updated, err := repo.Update(ctx, sub.ID, 1, map[string]any{"end_date": nil, "price": 349})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.EndDate != nil || updated.Price != 349 || updated.Version != 2 {
+		t.Errorf("updated %+v", updated)
+	}
+
+	// регистр кириллицы не учитывается ни в пересечениях, ни в фильтре списка
+	overlap, err := repo.ExistsOverlap(ctx, sub.UserID, "КИНОПОИСК", month(2030, 1), nil, nil)
+	if err != nil || !overlap {
+		t.Errorf("ExistsOverlap = %v, %v; want true", overlap, err)
+	}
+	list, err := repo.List(ctx, models.ListFilters{ServiceName: "поиск", Limit: -1})
+	if err != nil || len(list) != 1 {
+		t.Errorf("List = %d, %v; want 1", len(list), err)
+	}
+
+	// другой арендатор подписку не видит
+	other := tenant.WithTenant(ctx, uuid.New())
+	if _, err := repo.FindByID(other, sub.ID); err == nil {
+		t.Error("subscription is visible to another tenant")
+	}
+}
+
+// TestSubscriptionRepo_Contract - тестирует соответствие общему контракту хранилищ
+func TestSubscriptionRepo_Contract(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Backend {
+		return repotest.Backend{Repo: newRepo(t)}
+	})
+	// в файле несколько соединений: проверка пересечений должна выдерживать параллельную запись
+	t.Run("File", func(t *testing.T) {
+		repotest.Run(t, func(t *testing.T) repotest.Backend {
+			return repotest.Backend{Repo: openRepo(t, filepath.Join(t.TempDir(), "subscriptions.db"))}
+		})
+	})
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/auth"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"gorm.io/gorm"
+)
+
+var ErrForbidden = errors.New("forbidden")
+
+// restricted — principal обычного пользователя, доступ которого ограничен его собственными данными.
+// Для администратора и запросов без аутентификации (AUTH_DISABLED) возвращает false.
+func restricted(ctx context.Context) (*auth.Principal, bool) {
+	p, ok := auth.FromContext(ctx)
+	if !ok || p.IsAdmin() {
+		return nil, false
+	}
+	return p, true
+}
+
+// scopeUserID — user_id, которым ограничен запрос.
+// Для обычного пользователя фильтр становится неявным: пустой user_id заменяется его UUID,
+// а чужой user_id запрещён. Некорректный UUID возвращается как есть и отклоняется валидацией.
+func scopeUserID(ctx context.Context, requested string) (string, error) {
+	p, ok := restricted(ctx)
+	if !ok {
+		return requested, nil
+	}
+	if p.UserID == uuid.Nil {
+		return "", fmt.Errorf("%w: caller is not bound to a user", ErrForbidden)
+	}
+	if requested == "" {
+		return p.UserID.String(), nil
+	}
+	uid, err := uuid.Parse(requested)
+	if err != nil {
+		return requested, nil
+	}
+	if uid != p.UserID {
+		return "", fmt.Errorf("%w: access to another user's data", ErrForbidden)
+	}
+	return requested, nil
+}
+
+// canAccess — может ли вызывающая сторона работать с данными пользователя ownerID
+func canAccess(ctx context.Context, ownerID uuid.UUID) bool {
+	p, ok := restricted(ctx)
+	return !ok || p.UserID == ownerID
+}
+
+// findOwned — подписка по ID; чужая подписка считается не найденной, чтобы не раскрывать её существование
+func findOwned(ctx context.Context, repo SubscriptionRepository, id uuid.UUID) (*models.Subscription, error) {
+	sub, err := repo.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	if !canAccess(ctx, sub.UserID) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return sub, nil
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"gorm.io/gorm"
+)
+
+var ErrBudgetExceeded = errors.New("monthly budget exceeded")
+
+// budgetHorizonMonths — сколько месяцев проверяем для бессрочной подписки
+const budgetHorizonMonths = 12
+
+type BudgetRepository interface {
+	Create(ctx context.Context, b *models.Budget) error
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Budget, error)
+	Delete(ctx context.Context, userID, id uuid.UUID) error
+}
+
+type BudgetService struct {
+	budgets BudgetRepository
+	subs    SubscriptionRepository
+	members MemberRepository
+	log     *slog.Logger
+}
+
+// NewBudgetService — сервис бюджетов; members == nil — совместные подписки не учитываются,
+// и расходы считаются по полной цене подписок пользователя
+func NewBudgetService(budgets BudgetRepository, subs SubscriptionRepository, members MemberRepository, log *slog.Logger) *BudgetService {
+	return &BudgetService{budgets: budgets, subs: subs, members: members, log: log}
+}
+
+// Create — создает бюджет пользователя
+// Бюджет может ограничивать все подписки, один сервис или одну категорию
+func (s *BudgetService) Create(ctx context.Context, userIDStr string, req models.CreateBudgetRequest) (*models.Budget, error) {
+	userIDStr, err := scopeUserID(ctx, userIDStr)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: user_id must be UUID", errValid)
+	}
+	if req.MonthlyLimit <= 0 {
+		return nil, fmt.Errorf("%w: monthly_limit must be positive integer", errValid)
+	}
+	if req.ServiceName != nil && *req.ServiceName == "" {
+		return nil, fmt.Errorf("%w: service_name cannot be empty", errValid)
+	}
+	if req.Category != nil && *req.Category == "" {
+		return nil, fmt.Errorf("%w: category cannot be empty", errValid)
+	}
+	if req.ServiceName != nil && req.Category != nil {
+		return nil, fmt.Errorf("%w: budget can limit either service_name or category, not both", errValid)
+	}
+
+	mode := req.Mode
+	if mode == "" {
+		mode = models.BudgetModeWarn
+	}
+	if mode != models.BudgetModeWarn && mode != models.BudgetModeReject {
+		return nil, fmt.Errorf("%w: mode must be warn or reject", errValid)
+	}
+
+	b := &models.Budget{
+		ID:           uuid.New(),
+		UserID:       userID,
+		MonthlyLimit: req.MonthlyLimit,
+		ServiceName:  req.ServiceName,
+		Category:     req.Category,
+		Mode:         mode,
+	}
+	if err := s.budgets.Create(ctx, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// List — бюджеты пользователя
+func (s *BudgetService) List(ctx context.Context, userIDStr string) ([]models.Budget, error) {
+	userIDStr, err := scopeUserID(ctx, userIDStr)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: user_id must be UUID", errValid)
+	}
+	return s.budgets.ListByUser(ctx, userID)
+}
+
+// Delete — удаляет бюджет пользователя
+func (s *BudgetService) Delete(ctx context.Context, userIDStr, idStr string) error {
+	userIDStr, err := scopeUserID(ctx, userIDStr)
+	if err != nil {
+		return err
+	}
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return fmt.Errorf("%w: user_id must be UUID", errValid)
+	}
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return fmt.Errorf("%w: id must be UUID", errValid)
+	}
+	if err := s.budgets.Delete(ctx, userID, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return gorm.ErrRecordNotFound
+		}
+		return fmt.Errorf("db error: %w", err)
+	}
+	return nil
+}
+
+// Alerts — месяцы периода [fromStr; toStr], в которых расходы пользователя превышают его бюджеты
+func (s *BudgetService) Alerts(ctx context.Context, userIDStr, fromStr, toStr string) ([]models.BudgetAlert, error) {
+	userIDStr, err := scopeUserID(ctx, userIDStr)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: user_id must be UUID", errValid)
+	}
+	from, err := parseMonthYear(fromStr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: from must be MM-YYYY", errValid)
+	}
+	to, err := parseMonthYear(toStr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: to must be MM-YYYY", errValid)
+	}
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: to must be >= from", errValid)
+	}
+
+	budgets, err := s.budgets.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("db error: %w", err)
+	}
+	if len(budgets) == 0 {
+		return []models.BudgetAlert{}, nil
+	}
+
+	// расходы — доля пользователя, как в TotalCost
+	subs, err := s.subs.FindActiveInPeriod(ctx, from, to, models.ListFilters{UserID: &userID, IncludeShared: s.members != nil})
+	if err != nil {
+		return nil, fmt.Errorf("db error: %w", err)
+	}
+	prices, err := userPrices(ctx, s.members, subs, userID)
+	if err != nil {
+		return nil, fmt.Errorf("db error: %w", err)
+	}
+	return evaluateBudgets(budgets, subs, prices, from, to), nil
+}
+
+// checkBudgets — проверяет, не превысит ли сохранение candidate бюджеты пользователя.
+// excludeID — прежняя версия подписки (при изменении), которая заменяется кандидатом.
+// Возвращает ErrBudgetExceeded, если превышен бюджет в режиме reject, иначе — список предупреждений.
+func (s *SubscriptionService) checkBudgets(ctx context.Context, candidate *models.Subscription, excludeID *uuid.UUID) ([]models.BudgetAlert, error) {
+	if s.budgets == nil {
+		return nil, nil
+	}
+
+	all, err := s.budgets.ListByUser(ctx, candidate.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("db error: %w", err)
+	}
+	// интересуют только бюджеты, на которые влияет кандидат
+	budgets := make([]models.Budget, 0, len(all))
+	for _, b := range all {
+		if budgetMatches(b, candidate) {
+			budgets = append(budgets, b)
+		}
+	}
+	if len(budgets) == 0 {
+		return nil, nil
+	}
+
+	from := candidate.StartDate
+	to := from.AddDate(0, budgetHorizonMonths-1, 0)
+	if candidate.EndDate != nil {
+		to = *candidate.EndDate
+	}
+
+	subs, err := s.repo.FindActiveInPeriod(ctx, from, to, models.ListFilters{UserID: &candidate.UserID, IncludeShared: s.members != nil})
+	if err != nil {
+		return nil, fmt.Errorf("db error: %w", err)
+	}
+	merged := make([]models.Subscription, 0, len(subs)+1)
+	for _, sub := range subs {
+		if excludeID != nil && sub.ID == *excludeID {
+			continue
+		}
+		merged = append(merged, sub)
+	}
+	merged = append(merged, *candidate)
+	prices, err := userPrices(ctx, s.members, merged, candidate.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("db error: %w", err)
+	}
+
+	alerts := evaluateBudgets(budgets, merged, prices, from, to)
+	for _, a := range alerts {
+		if a.Mode == models.BudgetModeReject {
+			return nil, fmt.Errorf("%w: %d of %d in %s", ErrBudgetExceeded, a.Spent, a.MonthlyLimit, a.Month)
+		}
+	}
+	return alerts, nil
+}
+
+// evaluateBudgets — помесячно сравнивает расходы с бюджетами.
+// Расходы считаются так же, как в TotalCost: prices — месячная цена подписки для пользователя (userPrices).
+func evaluateBudgets(budgets []models.Budget, subs []models.Subscription, prices []int, from, to time.Time) []models.BudgetAlert {
+	alerts := []models.BudgetAlert{}
+	for m := from; !m.After(to); m = m.AddDate(0, 1, 0) {
+		for _, b := range budgets {
+			spent := 0
+			for i := range subs {
+				if budgetMatches(b, &subs[i]) {
+					spent += billedMonths(subs[i], m, m) * prices[i]
+				}
+			}
+			if spent > b.MonthlyLimit {
+				alerts = append(alerts, models.BudgetAlert{
+					BudgetID:     b.ID,
+					Month:        m.Format("01-2006"),
+					MonthlyLimit: b.MonthlyLimit,
+					Spent:        spent,
+					Mode:         b.Mode,
+				})
+			}
+		}
+	}
+	return alerts
+}
+
+// budgetMatches — относится ли подписка к бюджету
+func budgetMatches(b models.Budget, sub *models.Subscription) bool {
+	if b.ServiceName != nil && !strings.EqualFold(*b.ServiceName, sub.ServiceName) {
+		return false
+	}
+	if b.Category != nil && !strings.EqualFold(*b.Category, sub.Category) {
+		return false
+	}
+	return true
+}
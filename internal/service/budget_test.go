@@ -0,0 +1,210 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/olesia8novoselova/Subscriptions/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockBudgetRepo struct {
+	mock.Mock
+}
+
+func (m *mockBudgetRepo) Create(ctx context.Context, b *models.Budget) error {
+	args := m.Called(ctx, b)
+	return args.Error(0)
+}
+
+func (m *mockBudgetRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Budget, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.Budget), args.Error(1)
+}
+
+func (m *mockBudgetRepo) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+// TestBudgetCreate_ServiceAndCategory - бюджет не может ограничивать и сервис, и категорию одновременно
+func TestBudgetCreate_ServiceAndCategory(t *testing.T) {
+	svc := service.NewBudgetService(new(mockBudgetRepo), new(mockRepo), nil, nil)
+
+	req := models.CreateBudgetRequest{
+		MonthlyLimit: 1000,
+		ServiceName:  strPtr("Spotify"),
+		Category:     strPtr("music"),
+	}
+	b, err := svc.Create(context.Background(), uuid.New().String(), req)
+	assert.Nil(t, b)
+	assert.ErrorContains(t, err, "validation error")
+}
+
+// TestBudgetCreate_DefaultMode - по умолчанию бюджет только предупреждает
+func TestBudgetCreate_DefaultMode(t *testing.T) {
+	budgets := new(mockBudgetRepo)
+	svc := service.NewBudgetService(budgets, new(mockRepo), nil, nil)
+
+	budgets.On("Create", mock.Anything, mock.AnythingOfType("*models.Budget")).Return(nil)
+
+	b, err := svc.Create(context.Background(), uuid.New().String(), models.CreateBudgetRequest{MonthlyLimit: 1000})
+	assert.NoError(t, err)
+	assert.Equal(t, models.BudgetModeWarn, b.Mode)
+}
+
+// TestCreate_BudgetReject - создание подписки, превышающей бюджет в режиме reject, запрещено
+func TestCreate_BudgetReject(t *testing.T) {
+	repo := new(mockRepo)
+	budgets := new(mockBudgetRepo)
+	svc := service.NewSubscriptionService(repo, service.WithBudgets(budgets))
+
+	userID := uuid.New()
+	start := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+	existing := []models.Subscription{
+		{ID: uuid.New(), ServiceName: "Netflix", Price: 400, UserID: userID, StartDate: start},
+	}
+
+	repo.On("ExistsOverlap", mock.Anything, userID, "Spotify", start, &end, (*uuid.UUID)(nil)).Return(false, nil)
+	budgets.On("ListByUser", mock.Anything, userID).Return([]models.Budget{
+		{ID: uuid.New(), UserID: userID, MonthlyLimit: 600, Mode: models.BudgetModeReject},
+	}, nil)
+	repo.On("FindActiveInPeriod", mock.Anything, start, end, mock.Anything).Return(existing, nil)
+
+	req := models.CreateSubscriptionRequest{
+		ServiceName: "Spotify",
+		Price:       300,
+		UserID:      userID.String(),
+		StartDate:   "07-2025",
+		EndDate:     strPtr("08-2025"),
+	}
+	sub, err := svc.Create(context.Background(), req)
+
+	assert.Nil(t, sub)
+	assert.ErrorIs(t, err, service.ErrBudgetExceeded)
+	repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+// TestCreate_BudgetSharedSplit - в бюджет входит только доля пользователя в совместной подписке:
+// полная цена превысила бы лимит, доля — нет
+func TestCreate_BudgetSharedSplit(t *testing.T) {
+	repo := new(mockRepo)
+	budgets := new(mockBudgetRepo)
+	members := new(mockMemberRepo)
+	svc := service.NewSubscriptionService(repo, service.WithBudgets(budgets), service.WithMembers(members))
+
+	userID, friend := uuid.New(), uuid.New()
+	start := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+	family := models.Subscription{ID: uuid.New(), ServiceName: "Netflix", Price: 400, UserID: userID, StartDate: start}
+
+	repo.On("ExistsOverlap", mock.Anything, userID, "Spotify", start, &end, (*uuid.UUID)(nil)).Return(false, nil)
+	budgets.On("ListByUser", mock.Anything, userID).Return([]models.Budget{
+		{ID: uuid.New(), UserID: userID, MonthlyLimit: 600, Mode: models.BudgetModeReject},
+	}, nil)
+	repo.On("FindActiveInPeriod", mock.Anything, start, end,
+		mock.MatchedBy(func(f models.ListFilters) bool { return f.IncludeShared })).
+		Return([]models.Subscription{family}, nil)
+	members.On("ListBySubscriptions", mock.Anything, mock.Anything).Return([]models.SubscriptionMember{
+		{SubscriptionID: family.ID, UserID: friend, ShareType: models.ShareTypePercent, ShareValue: 50},
+	}, nil)
+	repo.On("Create", mock.Anything, mock.AnythingOfType("*models.Subscription")).Return(nil)
+
+	// 200 (половина Netflix) + 300 = 500 <= 600; по полной цене было бы 700
+	sub, err := svc.Create(context.Background(), models.CreateSubscriptionRequest{
+		ServiceName: "Spotify",
+		Price:       300,
+		UserID:      userID.String(),
+		StartDate:   "07-2025",
+		EndDate:     strPtr("08-2025"),
+	})
+	assert.NoError(t, err)
+	if assert.NotNil(t, sub) {
+		assert.Empty(t, sub.BudgetAlerts)
+	}
+
+	// в Alerts доля участника тоже относится к нему, а не к владельцу
+	bsvc := service.NewBudgetService(budgets, repo, members, nil)
+	budgets.On("ListByUser", mock.Anything, friend).Return([]models.Budget{
+		{ID: uuid.New(), UserID: friend, MonthlyLimit: 150, Mode: models.BudgetModeWarn},
+	}, nil)
+	alerts, err := bsvc.Alerts(context.Background(), friend.String(), "07-2025", "08-2025")
+	assert.NoError(t, err)
+	if assert.Len(t, alerts, 2) {
+		assert.Equal(t, 200, alerts[0].Spent)
+	}
+	alerts, err = bsvc.Alerts(context.Background(), userID.String(), "07-2025", "08-2025")
+	assert.NoError(t, err)
+	assert.Empty(t, alerts, "owner pays 200 of 400")
+}
+
+// TestCreate_BudgetWarn - в режиме warn подписка создаётся, а превышения возвращаются предупреждениями
+func TestCreate_BudgetWarn(t *testing.T) {
+	repo := new(mockRepo)
+	budgets := new(mockBudgetRepo)
+	svc := service.NewSubscriptionService(repo, service.WithBudgets(budgets))
+
+	userID := uuid.New()
+	start := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+	existing := []models.Subscription{
+		{ID: uuid.New(), ServiceName: "Yandex Music", Price: 400, UserID: userID, StartDate: start, Category: "music"},
+		{ID: uuid.New(), ServiceName: "Netflix", Price: 900, UserID: userID, StartDate: start, Category: "video"},
+	}
+
+	repo.On("ExistsOverlap", mock.Anything, userID, "Spotify", start, &end, (*uuid.UUID)(nil)).Return(false, nil)
+	budgets.On("ListByUser", mock.Anything, userID).Return([]models.Budget{
+		{ID: uuid.New(), UserID: userID, MonthlyLimit: 600, Category: strPtr("music"), Mode: models.BudgetModeWarn},
+	}, nil)
+	repo.On("FindActiveInPeriod", mock.Anything, start, end, mock.Anything).Return(existing, nil)
+	repo.On("Create", mock.Anything, mock.AnythingOfType("*models.Subscription")).Return(nil)
+
+	req := models.CreateSubscriptionRequest{
+		ServiceName: "Spotify",
+		Price:       300,
+		UserID:      userID.String(),
+		StartDate:   "07-2025",
+		EndDate:     strPtr("08-2025"),
+		Category:    "music",
+	}
+	sub, err := svc.Create(context.Background(), req)
+
+	assert.NoError(t, err)
+	if assert.Len(t, sub.BudgetAlerts, 2) {
+		assert.Equal(t, "07-2025", sub.BudgetAlerts[0].Month)
+		assert.Equal(t, 700, sub.BudgetAlerts[0].Spent)
+	}
+}
+
+// TestBudgetAlerts - помесячные превышения бюджета за период
+func TestBudgetAlerts(t *testing.T) {
+	repo := new(mockRepo)
+	budgets := new(mockBudgetRepo)
+	svc := service.NewBudgetService(budgets, repo, nil, nil)
+
+	userID := uuid.New()
+	from := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	aug := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+	subs := []models.Subscription{
+		{ServiceName: "Netflix", Price: 500, UserID: userID, StartDate: from},
+		{ServiceName: "Okko", Price: 300, UserID: userID, StartDate: aug, EndDate: ptrTime(aug)},
+	}
+
+	budgets.On("ListByUser", mock.Anything, userID).Return([]models.Budget{
+		{ID: uuid.New(), UserID: userID, MonthlyLimit: 600, Mode: models.BudgetModeWarn},
+	}, nil)
+	repo.On("FindActiveInPeriod", mock.Anything, from, to, mock.Anything).Return(subs, nil)
+
+	alerts, err := svc.Alerts(context.Background(), userID.String(), "07-2025", "09-2025")
+	assert.NoError(t, err)
+	if assert.Len(t, alerts, 1) {
+		assert.Equal(t, "08-2025", alerts[0].Month)
+		assert.Equal(t, 800, alerts[0].Spent)
+	}
+}
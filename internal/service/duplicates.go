@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+)
+
+// nameSimilarityThreshold — минимальная похожесть названий, начиная с которой
+// подписки считаются дублями
+const nameSimilarityThreshold = 0.8
+
+// planWords — слова тарифов, которые не различают сервисы ("Spotify Premium" и "Spotify Family")
+var planWords = map[string]bool{
+	"plus": true, "premium": true, "family": true, "basic": true, "standard": true,
+	"pro": true, "duo": true, "individual": true, "student": true, "subscription": true,
+}
+
+// domainWords — части доменных имён, которые не различают сервисы ("Netflix" и "Netflix.com")
+var domainWords = map[string]bool{
+	"www": true, "com": true, "ru": true, "net": true, "org": true, "io": true, "app": true,
+}
+
+// Duplicates — ищет среди подписок пользователя, активных в периоде [fromStr; toStr],
+// пары с похожими названиями или одной категорией, которые оплачиваются в одни и те же месяцы.
+// По умолчанию период — 12 месяцев начиная с текущего.
+func (s *SubscriptionService) Duplicates(ctx context.Context, userIDStr, fromStr, toStr string) (_ *models.DuplicateReport, err error) {
+	ctx, span := startSpan(ctx, "SubscriptionService.Duplicates")
+	defer func() { endSpan(span, err) }()
+
+	userIDStr, err = scopeUserID(ctx, userIDStr)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: user_id must be UUID", errValid)
+	}
+
+	now := s.now().UTC()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if fromStr != "" {
+		if from, err = parseMonthYear(fromStr); err != nil {
+			return nil, fmt.Errorf("%w: from must be MM-YYYY", errValid)
+		}
+	}
+	to := from.AddDate(0, 11, 0)
+	if toStr != "" {
+		if to, err = parseMonthYear(toStr); err != nil {
+			return nil, fmt.Errorf("%w: to must be MM-YYYY", errValid)
+		}
+	}
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: to must be >= from", errValid)
+	}
+
+	subs, err := s.repo.FindActiveInPeriod(ctx, from, to, models.ListFilters{UserID: &userID})
+	if err != nil {
+		return nil, fmt.Errorf("db error: %w", err)
+	}
+
+	return &models.DuplicateReport{
+		UserID:   userID,
+		From:     from.Format("01-2006"),
+		To:       to.Format("01-2006"),
+		Findings: findDuplicates(subs, from, to),
+	}, nil
+}
+
+// findDuplicates — попарное сравнение подписок, активных в периоде [from; to]
+func findDuplicates(subs []models.Subscription, from, to time.Time) []models.DuplicateFinding {
+	findings := []models.DuplicateFinding{}
+	for i := 0; i < len(subs); i++ {
+		for j := i + 1; j < len(subs); j++ {
+			a, b := subs[i], subs[j]
+
+			reason := ""
+			similarity := nameSimilarity(a.ServiceName, b.ServiceName)
+			switch {
+			case similarity >= nameSimilarityThreshold:
+				reason = models.DuplicateReasonSimilarName
+			case a.Category != "" && strings.EqualFold(a.Category, b.Category):
+				reason = models.DuplicateReasonSameCategory
+			default:
+				continue
+			}
+
+			// месяцы, в которые оплачиваются обе подписки
+			start := maxDate(maxDate(a.StartDate, b.StartDate), from)
+			end := to
+			if a.EndDate != nil && a.EndDate.Before(end) {
+				end = *a.EndDate
+			}
+			if b.EndDate != nil && b.EndDate.Before(end) {
+				end = *b.EndDate
+			}
+			if end.Before(start) {
+				continue
+			}
+
+			f := models.DuplicateFinding{
+				Reason:        reason,
+				Similarity:    math.Round(similarity*100) / 100,
+				OverlapFrom:   start.Format("01-2006"),
+				OverlapTo:     end.Format("01-2006"),
+				OverlapMonths: monthsInclusive(start, end),
+			}
+			for _, sub := range []models.Subscription{a, b} {
+				savings := sumCost([]models.Subscription{sub}, start, end)
+				if savings > f.MaxSavings {
+					f.MaxSavings = savings
+				}
+				f.Subscriptions = append(f.Subscriptions, models.DuplicateCandidate{
+					SubscriptionID: sub.ID,
+					ServiceName:    sub.ServiceName,
+					Price:          sub.Price,
+					Category:       sub.Category,
+					Savings:        savings,
+				})
+			}
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+// nameSimilarity — похожесть названий сервисов от 0 до 1 по редакционному расстоянию.
+// Названия нормализуются: регистр, пунктуация, слова тарифов и части доменов не учитываются.
+// Вхождение одного названия в другое похожестью не считается: "Yandex Plus" и "Yandex Music" —
+// разные сервисы одного бренда.
+func nameSimilarity(a, b string) float64 {
+	na, nb := normalizeServiceName(a), normalizeServiceName(b)
+	if na == "" || nb == "" {
+		return 0
+	}
+	if na == nb {
+		return 1
+	}
+	maxLen := max(len([]rune(na)), len([]rune(nb)))
+	return 1 - float64(levenshtein(na, nb))/float64(maxLen)
+}
+
+func normalizeServiceName(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var sb strings.Builder
+	for _, w := range words {
+		if planWords[w] || domainWords[w] {
+			continue
+		}
+		sb.WriteString(w)
+	}
+	return sb.String()
+}
+
+// levenshtein — редакционное расстояние между строками (по рунам)
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,63 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/olesia8novoselova/Subscriptions/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestDuplicates - тестирует поиск похожих названий и пересечений по категории
+func TestDuplicates(t *testing.T) {
+	repo := new(mockRepo)
+	svc := service.NewSubscriptionService(repo)
+
+	userID := uuid.New()
+	from := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+	netflix := models.Subscription{ID: uuid.New(), ServiceName: "Netflix", Price: 500, UserID: userID, StartDate: from, Category: "video"}
+	netflixPremium := models.Subscription{
+		ID: uuid.New(), ServiceName: "NETFLIX Premium", Price: 800, UserID: userID,
+		StartDate: time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC), EndDate: ptrTime(time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)),
+	}
+	spotify := models.Subscription{ID: uuid.New(), ServiceName: "Spotify", Price: 300, UserID: userID, StartDate: from, Category: "music"}
+	yandexMusic := models.Subscription{ID: uuid.New(), ServiceName: "Яндекс Музыка", Price: 200, UserID: userID, StartDate: from, Category: "Music"}
+	okko := models.Subscription{ID: uuid.New(), ServiceName: "Okko", Price: 400, UserID: userID, StartDate: from}
+
+	repo.On("FindActiveInPeriod", mock.Anything, from, to, mock.Anything).
+		Return([]models.Subscription{netflix, netflixPremium, spotify, yandexMusic, okko}, nil)
+
+	report, err := svc.Duplicates(context.Background(), userID.String(), "07-2025", "12-2025")
+	assert.NoError(t, err)
+	if !assert.Len(t, report.Findings, 2) {
+		return
+	}
+
+	byName := report.Findings[0]
+	assert.Equal(t, models.DuplicateReasonSimilarName, byName.Reason)
+	assert.Equal(t, "09-2025", byName.OverlapFrom)
+	assert.Equal(t, "10-2025", byName.OverlapTo)
+	assert.Equal(t, 2, byName.OverlapMonths)
+	assert.Equal(t, 1000, byName.Subscriptions[0].Savings)
+	assert.Equal(t, 1600, byName.Subscriptions[1].Savings)
+	assert.Equal(t, 1600, byName.MaxSavings)
+
+	byCategory := report.Findings[1]
+	assert.Equal(t, models.DuplicateReasonSameCategory, byCategory.Reason)
+	assert.Equal(t, 6, byCategory.OverlapMonths)
+	assert.Equal(t, 1800, byCategory.MaxSavings)
+}
+
+// TestDuplicates_InvalidUser - тестирует валидацию user_id
+func TestDuplicates_InvalidUser(t *testing.T) {
+	svc := service.NewSubscriptionService(new(mockRepo))
+
+	report, err := svc.Duplicates(context.Background(), "not-a-uuid", "", "")
+	assert.Nil(t, report)
+	assert.ErrorContains(t, err, "validation error")
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultForecastMonths = 12
+	maxForecastMonths     = 60
+)
+
+type ScheduledChangeRepository interface {
+	Create(ctx context.Context, c *models.ScheduledChange) error
+	ListBySubscriptions(ctx context.Context, ids []uuid.UUID) ([]models.ScheduledChange, error)
+	Delete(ctx context.Context, subscriptionID, id uuid.UUID) error
+}
+
+// WithScheduledChanges — учитывать запланированные изменения цены и отмены в прогнозе
+func WithScheduledChanges(changes ScheduledChangeRepository) Option {
+	return func(s *SubscriptionService) {
+		s.changes = changes
+	}
+}
+
+// Forecast — помесячный прогноз расходов на months месяцев начиная с fromStr
+// (по умолчанию — текущий месяц). Расходы по подпискам с датой окончания
+// считаются гарантированными (committed), по бессрочным — ожидаемыми (projected).
+func (s *SubscriptionService) Forecast(ctx context.Context, fromStr string, months int, userIDStr, serviceName string) (_ *models.ForecastResponse, err error) {
+	ctx, span := startSpan(ctx, "SubscriptionService.Forecast")
+	defer func() { endSpan(span, err) }()
+
+	var from time.Time
+	if fromStr == "" {
+		now := s.now().UTC()
+		from = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	} else {
+		var err error
+		from, err = parseMonthYear(fromStr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: from must be MM-YYYY", errValid)
+		}
+	}
+	if months == 0 {
+		months = defaultForecastMonths
+	}
+	if months < 0 || months > maxForecastMonths {
+		return nil, fmt.Errorf("%w: months must be between 1 and %d", errValid, maxForecastMonths)
+	}
+	to := from.AddDate(0, months-1, 0)
+
+	userIDStr, err = scopeUserID(ctx, userIDStr)
+	if err != nil {
+		return nil, err
+	}
+	var userIDPtr *uuid.UUID
+	if userIDStr != "" {
+		uid, err := uuid.Parse(userIDStr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: user_id must be UUID", errValid)
+		}
+		userIDPtr = &uid
+	}
+
+	subs, err := s.repo.FindActiveInPeriod(ctx, from, to, models.ListFilters{UserID: userIDPtr, ServiceName: serviceName})
+	if err != nil {
+		return nil, fmt.Errorf("db error: %w", err)
+	}
+
+	bySub := make(map[uuid.UUID][]models.ScheduledChange)
+	if s.changes != nil && len(subs) > 0 {
+		ids := make([]uuid.UUID, 0, len(subs))
+		for _, sub := range subs {
+			ids = append(ids, sub.ID)
+		}
+		changes, err := s.changes.ListBySubscriptions(ctx, ids)
+		if err != nil {
+			return nil, fmt.Errorf("db error: %w", err)
+		}
+		for _, c := range changes {
+			bySub[c.SubscriptionID] = append(bySub[c.SubscriptionID], c)
+		}
+	}
+
+	return forecast(subs, bySub, from, to), nil
+}
+
+// forecast — расчёт прогноза. Изменения каждой подписки должны быть упорядочены по EffectiveDate.
+func forecast(subs []models.Subscription, changes map[uuid.UUID][]models.ScheduledChange, from, to time.Time) *models.ForecastResponse {
+	resp := &models.ForecastResponse{Months: []models.ForecastMonth{}}
+	for m := from; !m.After(to); m = m.AddDate(0, 1, 0) {
+		fm := models.ForecastMonth{Month: m.Format("01-2006")}
+		for _, sub := range subs {
+			price, committed, active := priceInMonth(sub, changes[sub.ID], m)
+			if !active {
+				continue
+			}
+			if committed {
+				fm.Committed += price
+			} else {
+				fm.Projected += price
+			}
+		}
+		fm.Total = fm.Committed + fm.Projected
+		resp.Months = append(resp.Months, fm)
+		resp.Committed += fm.Committed
+		resp.Projected += fm.Projected
+	}
+	resp.Total = resp.Committed + resp.Projected
+	return resp
+}
+
+// priceInMonth — цена подписки в месяце m с учётом запланированных изменений.
+// committed — подписка в этом месяце ограничена датой окончания или запланированной отменой.
+func priceInMonth(sub models.Subscription, changes []models.ScheduledChange, m time.Time) (price int, committed, active bool) {
+	if m.Before(sub.StartDate) || (sub.EndDate != nil && m.After(*sub.EndDate)) {
+		return 0, false, false
+	}
+	price = sub.Price
+	committed = sub.EndDate != nil
+	for _, c := range changes {
+		if c.Cancel {
+			// отмена в будущем делает оставшиеся месяцы гарантированными
+			if !m.Before(c.EffectiveDate) {
+				return 0, false, false
+			}
+			committed = true
+			continue
+		}
+		if c.NewPrice != nil && !m.Before(c.EffectiveDate) {
+			price = *c.NewPrice
+		}
+	}
+	return price, committed, true
+}
+
+type ScheduledChangeService struct {
+	changes ScheduledChangeRepository
+	subs    SubscriptionRepository
+	log     *slog.Logger
+}
+
+func NewScheduledChangeService(changes ScheduledChangeRepository, subs SubscriptionRepository, log *slog.Logger) *ScheduledChangeService {
+	return &ScheduledChangeService{changes: changes, subs: subs, log: log}
+}
+
+// Create — планирует изменение цены или отмену подписки
+func (s *ScheduledChangeService) Create(ctx context.Context, subIDStr string, req models.CreateScheduledChangeRequest) (*models.ScheduledChange, error) {
+	subID, err := uuid.Parse(subIDStr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: id must be UUID", errValid)
+	}
+	effective, err := parseMonthYear(req.EffectiveDate)
+	if err != nil {
+		return nil, fmt.Errorf("%w: effective_date must be MM-YYYY", errValid)
+	}
+	if req.Cancel == (req.NewPrice != nil) {
+		return nil, fmt.Errorf("%w: exactly one of new_price or cancel must be set", errValid)
+	}
+	if req.NewPrice != nil && *req.NewPrice <= 0 {
+		return nil, fmt.Errorf("%w: new_price must be positive integer", errValid)
+	}
+
+	sub, err := findOwned(ctx, s.subs, subID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return nil, fmt.Errorf("db error: %w", err)
+	}
+	if effective.Before(sub.StartDate) {
+		return nil, fmt.Errorf("%w: effective_date must not be before start_date", errValid)
+	}
+	if sub.EndDate != nil && effective.After(*sub.EndDate) {
+		return nil, fmt.Errorf("%w: effective_date must not be after end_date", errValid)
+	}
+
+	c := &models.ScheduledChange{
+		ID:             uuid.New(),
+		SubscriptionID: subID,
+		EffectiveDate:  effective,
+		NewPrice:       req.NewPrice,
+		Cancel:         req.Cancel,
+	}
+	if err := s.changes.Create(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// List — запланированные изменения подписки
+func (s *ScheduledChangeService) List(ctx context.Context, subIDStr string) ([]models.ScheduledChange, error) {
+	subID, err := uuid.Parse(subIDStr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: id must be UUID", errValid)
+	}
+	if _, err := findOwned(ctx, s.subs, subID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return nil, fmt.Errorf("db error: %w", err)
+	}
+	return s.changes.ListBySubscriptions(ctx, []uuid.UUID{subID})
+}
+
+// Delete — отменяет запланированное изменение
+func (s *ScheduledChangeService) Delete(ctx context.Context, subIDStr, idStr string) error {
+	subID, err := uuid.Parse(subIDStr)
+	if err != nil {
+		return fmt.Errorf("%w: id must be UUID", errValid)
+	}
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return fmt.Errorf("%w: change_id must be UUID", errValid)
+	}
+	if _, err := findOwned(ctx, s.subs, subID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return gorm.ErrRecordNotFound
+		}
+		return fmt.Errorf("db error: %w", err)
+	}
+	if err := s.changes.Delete(ctx, subID, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return gorm.ErrRecordNotFound
+		}
+		return fmt.Errorf("db error: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/olesia8novoselova/Subscriptions/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockChangeRepo struct {
+	mock.Mock
+}
+
+func (m *mockChangeRepo) Create(ctx context.Context, c *models.ScheduledChange) error {
+	args := m.Called(ctx, c)
+	return args.Error(0)
+}
+
+func (m *mockChangeRepo) ListBySubscriptions(ctx context.Context, ids []uuid.UUID) ([]models.ScheduledChange, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).([]models.ScheduledChange), args.Error(1)
+}
+
+func (m *mockChangeRepo) Delete(ctx context.Context, subscriptionID, id uuid.UUID) error {
+	args := m.Called(ctx, subscriptionID, id)
+	return args.Error(0)
+}
+
+// TestForecast_CommittedAndProjected - тестирует разделение гарантированных и ожидаемых расходов
+// с учётом запланированных изменений цены и отмен
+func TestForecast_CommittedAndProjected(t *testing.T) {
+	repo := new(mockRepo)
+	changes := new(mockChangeRepo)
+	svc := service.NewSubscriptionService(repo, service.WithScheduledChanges(changes))
+
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	fixed := models.Subscription{ID: uuid.New(), Price: 100, StartDate: from, EndDate: ptrTime(time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC))}
+	open := models.Subscription{ID: uuid.New(), Price: 200, StartDate: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)}
+	cancelled := models.Subscription{ID: uuid.New(), Price: 50, StartDate: from}
+
+	repo.On("FindActiveInPeriod", mock.Anything, from, to, mock.Anything).Return([]models.Subscription{fixed, open, cancelled}, nil)
+	newPrice := 300
+	changes.On("ListBySubscriptions", mock.Anything, mock.Anything).Return([]models.ScheduledChange{
+		{SubscriptionID: open.ID, EffectiveDate: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), NewPrice: &newPrice},
+		{SubscriptionID: cancelled.ID, EffectiveDate: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), Cancel: true},
+	}, nil)
+
+	got, err := svc.Forecast(context.Background(), "01-2026", 4, "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []models.ForecastMonth{
+		{Month: "01-2026", Committed: 150, Projected: 200, Total: 350},
+		{Month: "02-2026", Committed: 150, Projected: 200, Total: 350},
+		{Month: "03-2026", Committed: 0, Projected: 300, Total: 300},
+		{Month: "04-2026", Committed: 0, Projected: 300, Total: 300},
+	}, got.Months)
+	assert.Equal(t, 300, got.Committed)
+	assert.Equal(t, 1000, got.Projected)
+	assert.Equal(t, 1300, got.Total)
+}
+
+// TestForecast_InvalidMonths - тестирует ограничение горизонта прогноза
+func TestForecast_InvalidMonths(t *testing.T) {
+	svc := service.NewSubscriptionService(new(mockRepo))
+
+	got, err := svc.Forecast(context.Background(), "01-2026", 61, "", "")
+	assert.Nil(t, got)
+	assert.ErrorContains(t, err, "validation error")
+}
+
+// TestScheduledChangeCreate_BothSet - нельзя одновременно сменить цену и отменить подписку
+func TestScheduledChangeCreate_BothSet(t *testing.T) {
+	svc := service.NewScheduledChangeService(new(mockChangeRepo), new(mockRepo), nil)
+
+	price := 100
+	req := models.CreateScheduledChangeRequest{EffectiveDate: "01-2026", NewPrice: &price, Cancel: true}
+	c, err := svc.Create(context.Background(), uuid.New().String(), req)
+	assert.Nil(t, c)
+	assert.ErrorContains(t, err, "validation error")
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrUnknownUser — участник ссылается на пользователя, которого нет в организации
+var ErrUnknownUser = errors.New("user not found")
+
+type MemberRepository interface {
+	Create(ctx context.Context, m *models.SubscriptionMember) error
+	ListBySubscriptions(ctx context.Context, ids []uuid.UUID) ([]models.SubscriptionMember, error)
+	Delete(ctx context.Context, subscriptionID, id uuid.UUID) error
+}
+
+// WithMembers — включает учёт долей участников совместных подписок в TotalCost
+func WithMembers(members MemberRepository) Option {
+	return func(s *SubscriptionService) {
+		s.members = members
+	}
+}
+
+type MemberService struct {
+	members MemberRepository
+	subs    SubscriptionRepository
+	metrics Metrics
+	log     *slog.Logger
+}
+
+// NewMemberService — сервис участников; metrics может быть nil
+func NewMemberService(members MemberRepository, subs SubscriptionRepository, metrics Metrics, log *slog.Logger) *MemberService {
+	return &MemberService{members: members, subs: subs, metrics: metrics, log: log}
+}
+
+// Add — добавляет участника подписки с долей в процентах или фиксированной суммой.
+// Сумма долей при текущей цене не может превышать цену подписки.
+func (s *MemberService) Add(ctx context.Context, subIDStr string, req models.AddMemberRequest) (*models.SubscriptionMember, error) {
+	subID, err := uuid.Parse(subIDStr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: id must be UUID", errValid)
+	}
+	userID, err := uuid.Parse(req.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: user_id must be UUID", errValid)
+	}
+	switch req.ShareType {
+	case models.ShareTypePercent:
+		if req.ShareValue <= 0 || req.ShareValue > 100 {
+			return nil, fmt.Errorf("%w: percent share_value must be in 1..100", errValid)
+		}
+	case models.ShareTypeFixed:
+		if req.ShareValue <= 0 {
+			return nil, fmt.Errorf("%w: fixed share_value must be positive integer", errValid)
+		}
+	default:
+		return nil, fmt.Errorf("%w: share_type must be percent or fixed", errValid)
+	}
+
+	sub, err := findOwned(ctx, s.subs, subID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return nil, fmt.Errorf("db error: %w", err)
+	}
+	if userID == sub.UserID {
+		return nil, fmt.Errorf("%w: owner cannot be a member of own subscription", errValid)
+	}
+
+	existing, err := s.members.ListBySubscriptions(ctx, []uuid.UUID{subID})
+	if err != nil {
+		return nil, fmt.Errorf("db error: %w", err)
+	}
+	m := &models.SubscriptionMember{
+		ID:             uuid.New(),
+		SubscriptionID: subID,
+		UserID:         userID,
+		ShareType:      req.ShareType,
+		ShareValue:     req.ShareValue,
+	}
+	shared := memberAmount(*m, sub.Price)
+	for _, e := range existing {
+		if e.UserID == userID {
+			return nil, fmt.Errorf("%w: user is already a member", errValid)
+		}
+		shared += memberAmount(e, sub.Price)
+	}
+	if shared > sub.Price {
+		return nil, fmt.Errorf("%w: members' shares (%d) exceed price (%d)", errValid, shared, sub.Price)
+	}
+
+	// участник не должен уже платить за тот же сервис в те же месяцы
+	overlap, err := s.subs.ExistsOverlap(ctx, userID, sub.ServiceName, sub.StartDate, sub.EndDate, &sub.ID)
+	if err != nil {
+		return nil, fmt.Errorf("db error: %w", err)
+	}
+	if overlap {
+		return nil, rejectOverlap(ctx, s.metrics, userID, sub.ServiceName)
+	}
+
+	if err := s.members.Create(ctx, m); err != nil {
+		// внешний ключ (tenant_id, user_id) → users
+		if errors.Is(err, gorm.ErrForeignKeyViolated) {
+			return nil, ErrUnknownUser
+		}
+		return nil, fmt.Errorf("db error: %w", err)
+	}
+	m.Amount = memberAmount(*m, sub.Price)
+	return m, nil
+}
+
+// List — участники подписки с их долями при текущей цене
+func (s *MemberService) List(ctx context.Context, subIDStr string) ([]models.SubscriptionMember, error) {
+	subID, err := uuid.Parse(subIDStr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: id must be UUID", errValid)
+	}
+	sub, err := findOwned(ctx, s.subs, subID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return nil, fmt.Errorf("db error: %w", err)
+	}
+	list, err := s.members.ListBySubscriptions(ctx, []uuid.UUID{subID})
+	if err != nil {
+		return nil, fmt.Errorf("db error: %w", err)
+	}
+	for i := range list {
+		list[i].Amount = memberAmount(list[i], sub.Price)
+	}
+	return list, nil
+}
+
+// Remove — удаляет участника подписки
+func (s *MemberService) Remove(ctx context.Context, subIDStr, idStr string) error {
+	subID, err := uuid.Parse(subIDStr)
+	if err != nil {
+		return fmt.Errorf("%w: id must be UUID", errValid)
+	}
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return fmt.Errorf("%w: member_id must be UUID", errValid)
+	}
+	if _, err := findOwned(ctx, s.subs, subID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return gorm.ErrRecordNotFound
+		}
+		return fmt.Errorf("db error: %w", err)
+	}
+	if err := s.members.Delete(ctx, subID, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return gorm.ErrRecordNotFound
+		}
+		return fmt.Errorf("db error: %w", err)
+	}
+	return nil
+}
+
+// memberAmount — доля участника в месяц при цене price
+func memberAmount(m models.SubscriptionMember, price int) int {
+	if m.ShareType == models.ShareTypePercent {
+		return price * m.ShareValue / 100
+	}
+	return min(m.ShareValue, price)
+}
+
+// userShare — часть месячной цены подписки, которую оплачивает userID.
+// Доли участников не превышают цену (при её снижении фиксированные доли урезаются по порядку),
+// остаток оплачивает владелец.
+func userShare(sub models.Subscription, members []models.SubscriptionMember, userID uuid.UUID) int {
+	rest := sub.Price
+	share := 0
+	for _, m := range members {
+		a := min(memberAmount(m, sub.Price), rest)
+		rest -= a
+		if m.UserID == userID {
+			share += a
+		}
+	}
+	if sub.UserID == userID {
+		share += rest
+	}
+	return share
+}
+
+// sumUserCost — стоимость для пользователя userID за период [from; to] с учётом его долей в совместных подписках
+func (s *SubscriptionService) sumUserCost(ctx context.Context, subs []models.Subscription, userID uuid.UUID, from, to time.Time) (int, error) {
+	prices, err := userPrices(ctx, s.members, subs, userID)
+	if err != nil {
+		return 0, err
+	}
+	return sumPriced(subs, prices, from, to), nil
+}
+
+// userPrices — месячная цена каждой из subs (по тем же индексам) для userID: его доля (userShare),
+// если участники включены (members != nil), иначе полная цена
+func userPrices(ctx context.Context, members MemberRepository, subs []models.Subscription, userID uuid.UUID) ([]int, error) {
+	prices := make([]int, len(subs))
+	if members == nil {
+		for i, sub := range subs {
+			prices[i] = sub.Price
+		}
+		return prices, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(subs))
+	for _, sub := range subs {
+		ids = append(ids, sub.ID)
+	}
+	list, err := members.ListBySubscriptions(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	bySub := make(map[uuid.UUID][]models.SubscriptionMember, len(list))
+	for _, m := range list {
+		bySub[m.SubscriptionID] = append(bySub[m.SubscriptionID], m)
+	}
+	for i, sub := range subs {
+		prices[i] = userShare(sub, bySub[sub.ID], userID)
+	}
+	return prices, nil
+}
+
+// sumPriced — стоимость подписок за период [from; to] по месячным ценам prices (см. userPrices)
+func sumPriced(subs []models.Subscription, prices []int, from, to time.Time) int {
+	total := 0
+	for i, sub := range subs {
+		total += billedMonths(sub, from, to) * prices[i]
+	}
+	return total
+}
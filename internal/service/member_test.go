@@ -0,0 +1,129 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/olesia8novoselova/Subscriptions/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+type mockMemberRepo struct {
+	mock.Mock
+}
+
+func (m *mockMemberRepo) Create(ctx context.Context, mem *models.SubscriptionMember) error {
+	args := m.Called(ctx, mem)
+	return args.Error(0)
+}
+
+func (m *mockMemberRepo) ListBySubscriptions(ctx context.Context, ids []uuid.UUID) ([]models.SubscriptionMember, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).([]models.SubscriptionMember), args.Error(1)
+}
+
+func (m *mockMemberRepo) Delete(ctx context.Context, subscriptionID, id uuid.UUID) error {
+	args := m.Called(ctx, subscriptionID, id)
+	return args.Error(0)
+}
+
+// TestTotalCost_SharedSubscription - по пользователю считается его доля в совместной подписке
+func TestTotalCost_SharedSubscription(t *testing.T) {
+	repo := new(mockRepo)
+	members := new(mockMemberRepo)
+	svc := service.NewSubscriptionService(repo, service.WithMembers(members))
+
+	owner, member := uuid.New(), uuid.New()
+	family := models.Subscription{ID: uuid.New(), ServiceName: "Spotify Family", Price: 400, UserID: owner,
+		StartDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	shares := []models.SubscriptionMember{
+		{SubscriptionID: family.ID, UserID: member, ShareType: models.ShareTypePercent, ShareValue: 25},
+		{SubscriptionID: family.ID, UserID: uuid.New(), ShareType: models.ShareTypeFixed, ShareValue: 100},
+	}
+
+	repo.On("FindActiveInPeriod", mock.Anything, mock.Anything, mock.Anything,
+		mock.MatchedBy(func(f models.ListFilters) bool { return f.IncludeShared })).
+		Return([]models.Subscription{family}, nil)
+	members.On("ListBySubscriptions", mock.Anything, []uuid.UUID{family.ID}).Return(shares, nil)
+
+	total, err := svc.TotalCost(context.Background(), "07-2025", "08-2025", member.String(), "")
+	assert.NoError(t, err)
+	assert.Equal(t, 200, total) // 25% от 400 за 2 месяца
+
+	total, err = svc.TotalCost(context.Background(), "07-2025", "08-2025", owner.String(), "")
+	assert.NoError(t, err)
+	assert.Equal(t, 400, total) // остаток 400-100-100 за 2 месяца
+}
+
+// TestMemberAdd_SharesExceedPrice - сумма долей участников не может превышать цену подписки
+func TestMemberAdd_SharesExceedPrice(t *testing.T) {
+	repo := new(mockRepo)
+	members := new(mockMemberRepo)
+	svc := service.NewMemberService(members, repo, nil, nil)
+
+	sub := &models.Subscription{ID: uuid.New(), ServiceName: "Spotify Family", Price: 400, UserID: uuid.New()}
+	repo.On("FindByID", mock.Anything, sub.ID).Return(sub, nil)
+	members.On("ListBySubscriptions", mock.Anything, []uuid.UUID{sub.ID}).Return([]models.SubscriptionMember{
+		{SubscriptionID: sub.ID, UserID: uuid.New(), ShareType: models.ShareTypePercent, ShareValue: 75},
+	}, nil)
+
+	m, err := svc.Add(context.Background(), sub.ID.String(), models.AddMemberRequest{
+		UserID:     uuid.New().String(),
+		ShareType:  models.ShareTypeFixed,
+		ShareValue: 150,
+	})
+	assert.Nil(t, m)
+	assert.ErrorContains(t, err, "exceed price")
+	members.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+// TestMemberAdd_Overlap - участник не может платить за тот же сервис в те же месяцы дважды, отказ учитывается в метриках
+func TestMemberAdd_Overlap(t *testing.T) {
+	repo := new(mockRepo)
+	members := new(mockMemberRepo)
+	counter := &overlapCounter{}
+	svc := service.NewMemberService(members, repo, counter, nil)
+
+	userID := uuid.New()
+	sub := &models.Subscription{ID: uuid.New(), ServiceName: "Spotify", Price: 400, UserID: uuid.New(),
+		StartDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	repo.On("FindByID", mock.Anything, sub.ID).Return(sub, nil)
+	members.On("ListBySubscriptions", mock.Anything, []uuid.UUID{sub.ID}).Return([]models.SubscriptionMember{}, nil)
+	repo.On("ExistsOverlap", mock.Anything, userID, "Spotify", sub.StartDate, (*time.Time)(nil), &sub.ID).Return(true, nil)
+
+	_, err := svc.Add(context.Background(), sub.ID.String(), models.AddMemberRequest{
+		UserID:     userID.String(),
+		ShareType:  models.ShareTypePercent,
+		ShareValue: 25,
+	})
+	assert.ErrorIs(t, err, service.ErrOverlap)
+	assert.Equal(t, 1, counter.n)
+}
+
+// TestMemberAdd_UnknownUser - участник с несуществующим user_id отклоняется доменной ошибкой, а не ошибкой БД
+func TestMemberAdd_UnknownUser(t *testing.T) {
+	repo := new(mockRepo)
+	members := new(mockMemberRepo)
+	svc := service.NewMemberService(members, repo, nil, nil)
+
+	userID := uuid.New()
+	sub := &models.Subscription{ID: uuid.New(), ServiceName: "Spotify", Price: 400, UserID: uuid.New(),
+		StartDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	repo.On("FindByID", mock.Anything, sub.ID).Return(sub, nil)
+	members.On("ListBySubscriptions", mock.Anything, []uuid.UUID{sub.ID}).Return([]models.SubscriptionMember{}, nil)
+	repo.On("ExistsOverlap", mock.Anything, userID, "Spotify", sub.StartDate, (*time.Time)(nil), &sub.ID).Return(false, nil)
+	members.On("Create", mock.Anything, mock.Anything).Return(gorm.ErrForeignKeyViolated)
+
+	_, err := svc.Add(context.Background(), sub.ID.String(), models.AddMemberRequest{
+		UserID:     userID.String(),
+		ShareType:  models.ShareTypePercent,
+		ShareValue: 25,
+	})
+	assert.ErrorIs(t, err, service.ErrUnknownUser)
+	assert.NotContains(t, err.Error(), "db error")
+}
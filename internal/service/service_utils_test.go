@@ -77,3 +77,29 @@ func TestMaxDate(t *testing.T) {
 func date(year int, month time.Month) time.Time {
 	return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
 }
+
+// TestNameSimilarity - тестирует нечёткое сравнение названий сервисов
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		similar bool
+	}{
+		{"case and punctuation", "Netflix", "netflix.com", true},
+		{"plan words ignored", "Spotify Premium", "Spotify Family", true},
+		{"typo", "Kinopoisk", "Kinopoisck", true},
+		{"different services", "Spotify", "Netflix", false},
+		{"short names not contained", "TV", "Apple TV", false},
+		{"same brand, different services", "Yandex Plus", "Yandex Music", false},
+		{"same brand, extra word", "YouTube", "YouTube Music", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nameSimilarity(tt.a, tt.b)
+			if (got >= nameSimilarityThreshold) != tt.similar {
+				t.Errorf("nameSimilarity(%q, %q) = %v, want similar=%v", tt.a, tt.b, got, tt.similar)
+			}
+		})
+	}
+}
@@ -4,41 +4,189 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log/slog"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/olesia8novoselova/Subscriptions/pkg/logging"
+	"go.opentelemetry.io/otel/attribute"
 	"gorm.io/gorm"
 )
 
 var (
-	errValid   = errors.New("validation error")
-	ErrOverlap = errors.New("overlapping subscription")
+	// ErrValidation — некорректные входные данные
+	ErrValidation = errors.New("validation error")
+	errValid      = ErrValidation
+	ErrOverlap    = errors.New("overlapping subscription")
+	// ErrPreconditionFailed — версия подписки не совпадает с ожидаемой (If-Match)
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrIDConflict — ID, переданный в PUT, уже занят подпиской, недоступной вызывающему (например, в другой организации)
+	ErrIDConflict = errors.New("subscription id is already in use")
 )
 
 type SubscriptionRepository interface {
 	Create(ctx context.Context, s *models.Subscription) error
 	FindByID(ctx context.Context, id uuid.UUID) (*models.Subscription, error)
 	List(ctx context.Context, f models.ListFilters) ([]models.Subscription, error)
-	Delete(ctx context.Context, id uuid.UUID) error
-	Update(ctx context.Context, id uuid.UUID, fields map[string]any) (*models.Subscription, error)
+	// Delete и Update с version > 0 затрагивают подписку, только если её версия совпадает;
+	// иначе возвращают gorm.ErrRecordNotFound. Update увеличивает версию.
+	Delete(ctx context.Context, id uuid.UUID, version int) error
+	Update(ctx context.Context, id uuid.UUID, version int, fields map[string]any) (*models.Subscription, error)
 	FindActiveInPeriod(ctx context.Context, from, to time.Time, f models.ListFilters) ([]models.Subscription, error)
 	ExistsOverlap(ctx context.Context, userID uuid.UUID, serviceName string, start time.Time, end *time.Time, excludeID *uuid.UUID) (bool, error)
 }
 
 type SubscriptionService struct {
-	repo SubscriptionRepository
-	log  *slog.Logger
+	repo    SubscriptionRepository
+	fresh   SubscriptionRepository
+	budgets BudgetRepository
+	users   UserRepository
+	members MemberRepository
+	changes ScheduledChangeRepository
+	metrics Metrics
+	now     func() time.Time
+
+	pagination atomic.Pointer[Pagination]
 }
 
-func NewSubscriptionService(repo SubscriptionRepository, log *slog.Logger) *SubscriptionService {
-	return &SubscriptionService{repo: repo, log: log}
+// Pagination — размер страницы List, если limit не задан, и его максимум
+type Pagination struct {
+	DefaultLimit int
+	MaxLimit     int
+}
+
+// DefaultPagination — пагинация, если WithPagination не задана
+var DefaultPagination = Pagination{DefaultLimit: 20, MaxLimit: 100}
+
+// PaginationSource — текущая пагинация списков; SubscriptionService отдаёт заданную через SetPagination
+type PaginationSource interface {
+	Pagination() Pagination
+}
+
+// Pagination — неизменяемый PaginationSource с этой пагинацией
+func (p Pagination) Pagination() Pagination {
+	return p
+}
+
+// clamp — limit и offset, приведённые к допустимым: limit <= 0 заменяется размером по умолчанию
+func (p Pagination) clamp(limit, offset int) (int, int) {
+	if limit <= 0 {
+		limit = p.DefaultLimit
+	}
+	if limit > p.MaxLimit {
+		limit = p.MaxLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+// WithPagination — размер страницы List по умолчанию и максимальный
+func WithPagination(p Pagination) Option {
+	return func(s *SubscriptionService) {
+		s.SetPagination(p)
+	}
+}
+
+// Option — дополнительная настройка SubscriptionService
+type Option func(*SubscriptionService)
+
+// WithBudgets — включает проверку бюджетов при создании и изменении подписок
+func WithBudgets(budgets BudgetRepository) Option {
+	return func(s *SubscriptionService) {
+		s.budgets = budgets
+	}
+}
+
+// WithUncachedReads — хранилище без кеша для чтения подписки перед PATCH, PUT и DELETE.
+// Версия из кеша может отставать, и запись отклонялась бы с 412, хотя подписку никто не менял
+func WithUncachedReads(repo SubscriptionRepository) Option {
+	return func(s *SubscriptionService) {
+		s.fresh = repo
+	}
+}
+
+// Metrics — счётчики доменных событий сервиса
+type Metrics interface {
+	// OverlapRejected — подписка отклонена из-за пересечения периодов
+	OverlapRejected()
+}
+
+// WithMetrics — включает учёт доменных событий
+func WithMetrics(m Metrics) Option {
+	return func(s *SubscriptionService) {
+		s.metrics = m
+	}
+}
+
+// NewSubscriptionService — сервис подписок; логирует через логгер запроса из контекста (logging.FromContext)
+func NewSubscriptionService(repo SubscriptionRepository, opts ...Option) *SubscriptionService {
+	s := &SubscriptionService{repo: repo, fresh: repo, now: time.Now}
+	s.SetPagination(DefaultPagination)
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// SetPagination — заменяет пагинацию List; безопасно вызывать во время обработки запросов
+func (s *SubscriptionService) SetPagination(p Pagination) {
+	s.pagination.Store(&p)
+}
+
+// Pagination — текущая пагинация List
+func (s *SubscriptionService) Pagination() Pagination {
+	return *s.pagination.Load()
 }
 
 // Create — создает новую подписку
 // Проверяет пересечения с существующими подписками пользователя
-func (s *SubscriptionService) Create(ctx context.Context, req models.CreateSubscriptionRequest) (*models.Subscription, error) {
+func (s *SubscriptionService) Create(ctx context.Context, req models.CreateSubscriptionRequest) (_ *models.Subscription, err error) {
+	ctx, span := startSpan(ctx, "SubscriptionService.Create")
+	defer func() { endSpan(span, err) }()
+
+	sub, err := s.newSubscription(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	sub.ID = uuid.New()
+	return s.create(ctx, sub)
+}
+
+// create — сохраняет новую подписку после проверки пересечений и бюджетов
+func (s *SubscriptionService) create(ctx context.Context, sub *models.Subscription) (*models.Subscription, error) {
+	overlap, err := s.repo.ExistsOverlap(ctx, sub.UserID, sub.ServiceName, sub.StartDate, sub.EndDate, nil)
+	if err != nil {
+		return nil, err
+	}
+	if overlap {
+		return nil, rejectOverlap(ctx, s.metrics, sub.UserID, sub.ServiceName)
+	}
+
+	alerts, err := s.checkBudgets(ctx, sub, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, sub); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrDuplicatedKey):
+			return nil, ErrIDConflict
+		case errors.Is(err, ErrOverlap):
+			// пересекающаяся подписка записана параллельно после ExistsOverlap
+			return nil, rejectOverlap(ctx, s.metrics, sub.UserID, sub.ServiceName)
+		}
+		return nil, err
+	}
+	logChange(ctx, "subscription created", sub)
+	sub.BudgetAlerts = alerts
+	return sub, nil
+}
+
+// newSubscription — проверяет тело запроса и собирает из него подписку без ID
+func (s *SubscriptionService) newSubscription(ctx context.Context, req models.CreateSubscriptionRequest) (*models.Subscription, error) {
 	// Валидация
 	if req.ServiceName == "" {
 		return nil, fmt.Errorf("%w: service_name is required", errValid)
@@ -46,7 +194,11 @@ func (s *SubscriptionService) Create(ctx context.Context, req models.CreateSubsc
 	if req.Price <= 0 {
 		return nil, fmt.Errorf("%w: price must be positive integer", errValid)
 	}
-	userID, err := uuid.Parse(req.UserID)
+	userIDStr, err := scopeUserID(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
 		return nil, fmt.Errorf("%w: user_id must be UUID", errValid)
 	}
@@ -56,6 +208,10 @@ func (s *SubscriptionService) Create(ctx context.Context, req models.CreateSubsc
 		return nil, fmt.Errorf("%w: start_date format must be MM-YYYY or YYYY-MM", errValid)
 	}
 
+	if err := s.ensureUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
 	var endPtr *time.Time
 	if req.EndDate != nil && *req.EndDate != "" {
 		end, err := parseMonthYear(*req.EndDate)
@@ -68,27 +224,37 @@ func (s *SubscriptionService) Create(ctx context.Context, req models.CreateSubsc
 		endPtr = &end
 	}
 
-	sub := &models.Subscription{
-		ID:          uuid.New(),
+	return &models.Subscription{
+		Version:     1,
 		ServiceName: req.ServiceName,
 		Price:       req.Price,
 		UserID:      userID,
 		StartDate:   start,
 		EndDate:     endPtr,
-	}
+		Category:    req.Category,
+	}, nil
+}
 
-	overlap, err := s.repo.ExistsOverlap(ctx, sub.UserID, sub.ServiceName, sub.StartDate, sub.EndDate, nil)
-	if err != nil {
-		return nil, err
+// ensureUser — владелец подписки должен существовать. Обычному пользователю scopeUserID
+// разрешает только собственный ID, поэтому его запись создаётся при первом обращении,
+// как и для пользователей, перенесённых миграцией; администратор указывает существующего
+func (s *SubscriptionService) ensureUser(ctx context.Context, userID uuid.UUID) error {
+	if s.users == nil {
+		return nil
 	}
-	if overlap {
-		return nil, ErrOverlap
+	if _, ok := restricted(ctx); ok {
+		if err := s.users.Provision(ctx, userID); err != nil {
+			return fmt.Errorf("db error: %w", err)
+		}
+		return nil
 	}
-
-	if err := s.repo.Create(ctx, sub); err != nil {
-		return nil, err
+	if _, err := s.users.FindByID(ctx, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: user not found", errValid)
+		}
+		return fmt.Errorf("db error: %w", err)
 	}
-	return sub, nil
+	return nil
 }
 
 // Принимает "MM-YYYY" или "YYYY-MM", возвращает 1-е число месяца в UTC
@@ -106,12 +272,15 @@ func parseMonthYear(s string) (time.Time, error) {
 }
 
 // GetByID — получает подписку по ID
-func (s *SubscriptionService) GetByID(ctx context.Context, idStr string) (*models.Subscription, error) {
+func (s *SubscriptionService) GetByID(ctx context.Context, idStr string) (_ *models.Subscription, err error) {
+	ctx, span := startSpan(ctx, "SubscriptionService.GetByID")
+	defer func() { endSpan(span, err) }()
+
 	id, err := uuid.Parse(idStr)
 	if err != nil {
 		return nil, fmt.Errorf("%w: id must be UUID", errValid)
 	}
-	sub, err := s.repo.FindByID(ctx, id)
+	sub, err := findOwned(ctx, s.repo, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, err
@@ -122,17 +291,16 @@ func (s *SubscriptionService) GetByID(ctx context.Context, idStr string) (*model
 }
 
 // List — получает список подписок с фильтрами и пагинацией
-func (s *SubscriptionService) List(ctx context.Context, userIDStr, serviceName string, limit, offset int) ([]models.Subscription, error) {
-	if limit <= 0 {
-		limit = 20
-	}
-	if limit > 100 {
-		limit = 100
-	}
-	if offset < 0 {
-		offset = 0
-	}
+func (s *SubscriptionService) List(ctx context.Context, userIDStr, serviceName string, limit, offset int) (_ []models.Subscription, err error) {
+	ctx, span := startSpan(ctx, "SubscriptionService.List")
+	defer func() { endSpan(span, err) }()
+
+	limit, offset = s.Pagination().clamp(limit, offset)
 
+	userIDStr, err = scopeUserID(ctx, userIDStr)
+	if err != nil {
+		return nil, err
+	}
 	var userIDPtr *uuid.UUID
 	if userIDStr != "" {
 		uid, err := uuid.Parse(userIDStr)
@@ -151,25 +319,80 @@ func (s *SubscriptionService) List(ctx context.Context, userIDStr, serviceName s
 	return s.repo.List(ctx, f)
 }
 
+// ListByUsers — подписки нескольких пользователей одним запросом, сгруппированные по user_id.
+// Обычный пользователь получает только свои подписки.
+func (s *SubscriptionService) ListByUsers(ctx context.Context, userIDs []uuid.UUID) (_ map[uuid.UUID][]models.Subscription, err error) {
+	ctx, span := startSpan(ctx, "SubscriptionService.ListByUsers")
+	defer func() { endSpan(span, err) }()
+
+	res := make(map[uuid.UUID][]models.Subscription, len(userIDs))
+	if p, ok := restricted(ctx); ok {
+		own := userIDs[:0:0]
+		for _, id := range userIDs {
+			if id == p.UserID {
+				own = append(own, id)
+			}
+		}
+		userIDs = own
+	}
+	if len(userIDs) == 0 {
+		return res, nil
+	}
+
+	list, err := s.repo.List(ctx, models.ListFilters{UserIDs: userIDs, Limit: -1})
+	if err != nil {
+		return nil, fmt.Errorf("db error: %w", err)
+	}
+	for _, sub := range list {
+		res[sub.UserID] = append(res[sub.UserID], sub)
+	}
+	return res, nil
+}
+
 // Delete — удаляет подписку по ID
-func (s *SubscriptionService) Delete(ctx context.Context, idStr string) error {
+// ifMatch — ожидаемая версия подписки (If-Match), 0 — без проверки
+func (s *SubscriptionService) Delete(ctx context.Context, idStr string, ifMatch int) (err error) {
+	ctx, span := startSpan(ctx, "SubscriptionService.Delete")
+	defer func() { endSpan(span, err) }()
+
 	id, err := uuid.Parse(idStr)
 	if err != nil {
 		return fmt.Errorf("%w: id must be UUID", errValid)
 	}
-	if err := s.repo.Delete(ctx, id); err != nil {
+	if _, ok := restricted(ctx); ok || ifMatch != 0 {
+		existing, err := findOwned(ctx, s.fresh, id)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return gorm.ErrRecordNotFound
+			}
+			return fmt.Errorf("db error: %w", err)
+		}
+		if ifMatch != 0 && existing.Version != ifMatch {
+			return fmt.Errorf("%w: version is %d", ErrPreconditionFailed, existing.Version)
+		}
+	}
+	if err := s.repo.Delete(ctx, id, ifMatch); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if ifMatch != 0 {
+				// подписку изменили или удалили после проверки версии
+				return ErrPreconditionFailed
+			}
 			return gorm.ErrRecordNotFound
 		}
 		return fmt.Errorf("db error: %w", err)
 	}
+	logging.FromContext(ctx).InfoContext(ctx, "subscription deleted", "subscription_id", id)
 	return nil
 }
 
 // Patch — обновляет подписку по ID
 // Проверяет пересечения с существующими подписками пользователя
 // Если поле пустое — не обновляет его
-func (s *SubscriptionService) Patch(ctx context.Context, idStr string, req models.UpdateSubscriptionRequest) (*models.Subscription, error) {
+// ifMatch — ожидаемая версия подписки (If-Match), 0 — без проверки
+func (s *SubscriptionService) Patch(ctx context.Context, idStr string, req models.UpdateSubscriptionRequest, ifMatch int) (_ *models.Subscription, err error) {
+	ctx, span := startSpan(ctx, "SubscriptionService.Patch")
+	defer func() { endSpan(span, err) }()
+
 	id, err := uuid.Parse(idStr)
 	if err != nil {
 		return nil, fmt.Errorf("%w: id must be UUID", errValid)
@@ -216,13 +439,17 @@ func (s *SubscriptionService) Patch(ctx context.Context, idStr string, req model
 		}
 	}
 
+	if req.Category != nil {
+		fields["category"] = *req.Category
+	}
+
 	if len(fields) == 0 {
 		return nil, fmt.Errorf("%w: no fields to update", errValid)
 	}
 
 	// если задают только end_date — убедимся, что он не раньше текущего start_date
 	if req.EndDate != nil && *req.EndDate != "" && fields["start_date"] == nil {
-		existing, err := s.repo.FindByID(ctx, id)
+		existing, err := findOwned(ctx, s.fresh, id)
 		if err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
 				return nil, gorm.ErrRecordNotFound
@@ -235,13 +462,16 @@ func (s *SubscriptionService) Patch(ctx context.Context, idStr string, req model
 		}
 	}
 
-	existing, err := s.repo.FindByID(ctx, id)
+	existing, err := findOwned(ctx, s.fresh, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, gorm.ErrRecordNotFound
 		}
 		return nil, err
 	}
+	if ifMatch != 0 && existing.Version != ifMatch {
+		return nil, fmt.Errorf("%w: version is %d", ErrPreconditionFailed, existing.Version)
+	}
 	newStart := existing.StartDate
 	if v, ok := fields["start_date"].(time.Time); ok {
 		newStart = v
@@ -263,14 +493,150 @@ func (s *SubscriptionService) Patch(ctx context.Context, idStr string, req model
 		return nil, err
 	}
 	if overlap {
-		return nil, ErrOverlap
+		return nil, rejectOverlap(ctx, s.metrics, existing.UserID, existing.ServiceName)
+	}
+
+	// кандидат — подписка в том виде, в котором она будет сохранена
+	candidate := *existing
+	candidate.StartDate = newStart
+	candidate.EndDate = newEnd
+	if v, ok := fields["service_name"].(string); ok {
+		candidate.ServiceName = v
+	}
+	if v, ok := fields["price"].(int); ok {
+		candidate.Price = v
+	}
+	if v, ok := fields["category"].(string); ok {
+		candidate.Category = v
+	}
+	_, datesChanged := fields["start_date"]
+	if _, ok := fields["end_date"]; ok {
+		datesChanged = true
+	}
+	if _, ok := fields["service_name"]; ok || datesChanged {
+		if err := s.checkMemberOverlap(ctx, &candidate); err != nil {
+			return nil, err
+		}
+	}
+	alerts, err := s.checkBudgets(ctx, &candidate, &id)
+	if err != nil {
+		return nil, err
+	}
+
+	// обновляем только ту версию, которую проверяли: параллельное изменение не будет перезаписано
+	sub, err := s.repo.Update(ctx, id, existing.Version, fields)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return nil, fmt.Errorf("%w: subscription was modified concurrently", ErrPreconditionFailed)
+		case errors.Is(err, ErrOverlap):
+			return nil, rejectOverlap(ctx, s.metrics, existing.UserID, existing.ServiceName)
+		}
+		return nil, err
+	}
+	logChange(ctx, "subscription updated", sub)
+	sub.BudgetAlerts = alerts
+	return sub, nil
+}
+
+// Replace — полностью заменяет подписку по ID (PUT): необязательные поля, которых нет в запросе, очищаются.
+// Проверки те же, что при создании. Если подписки нет и ifMatch не задан, она создаётся с этим ID;
+// created сообщает, что подписка создана. Чужая подписка считается не найденной, а ID,
+// занятый в другой организации, даёт ErrIDConflict.
+// ifMatch — ожидаемая версия подписки (If-Match), 0 — без проверки
+func (s *SubscriptionService) Replace(ctx context.Context, idStr string, req models.CreateSubscriptionRequest, ifMatch int) (sub *models.Subscription, created bool, err error) {
+	ctx, span := startSpan(ctx, "SubscriptionService.Replace")
+	defer func() { endSpan(span, err) }()
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: id must be UUID", errValid)
+	}
+	sub, err = s.newSubscription(ctx, req)
+	if err != nil {
+		return nil, false, err
+	}
+	sub.ID = id
+
+	existing, err := s.fresh.FindByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if ifMatch != 0 {
+			return nil, false, fmt.Errorf("%w: subscription does not exist", ErrPreconditionFailed)
+		}
+		sub, err = s.create(ctx, sub)
+		return sub, err == nil, err
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("db error: %w", err)
+	}
+	if !canAccess(ctx, existing.UserID) {
+		return nil, false, gorm.ErrRecordNotFound
+	}
+	if ifMatch != 0 && existing.Version != ifMatch {
+		return nil, false, fmt.Errorf("%w: version is %d", ErrPreconditionFailed, existing.Version)
+	}
+
+	// новый владелец не может оставаться участником своей подписки
+	if sub.UserID != existing.UserID && s.members != nil {
+		members, err := s.members.ListBySubscriptions(ctx, []uuid.UUID{id})
+		if err != nil {
+			return nil, false, fmt.Errorf("db error: %w", err)
+		}
+		for _, m := range members {
+			if m.UserID == sub.UserID {
+				return nil, false, fmt.Errorf("%w: new owner is a member of the subscription", errValid)
+			}
+		}
+	}
+
+	overlap, err := s.repo.ExistsOverlap(ctx, sub.UserID, sub.ServiceName, sub.StartDate, sub.EndDate, &id)
+	if err != nil {
+		return nil, false, err
+	}
+	if overlap {
+		return nil, false, rejectOverlap(ctx, s.metrics, sub.UserID, sub.ServiceName)
+	}
+	if err := s.checkMemberOverlap(ctx, sub); err != nil {
+		return nil, false, err
 	}
 
-	return s.repo.Update(ctx, id, fields)
+	alerts, err := s.checkBudgets(ctx, sub, &id)
+	if err != nil {
+		return nil, false, err
+	}
+
+	fields := map[string]any{
+		"service_name": sub.ServiceName,
+		"price":        sub.Price,
+		"user_id":      sub.UserID,
+		"start_date":   sub.StartDate,
+		"end_date":     nil,
+		"category":     sub.Category,
+	}
+	if sub.EndDate != nil {
+		fields["end_date"] = *sub.EndDate
+	}
+	userID, serviceName := sub.UserID, sub.ServiceName
+	sub, err = s.repo.Update(ctx, id, existing.Version, fields)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return nil, false, fmt.Errorf("%w: subscription was modified concurrently", ErrPreconditionFailed)
+		case errors.Is(err, ErrOverlap):
+			return nil, false, rejectOverlap(ctx, s.metrics, userID, serviceName)
+		}
+		return nil, false, err
+	}
+	logChange(ctx, "subscription updated", sub)
+	sub.BudgetAlerts = alerts
+	return sub, false, nil
 }
 
 // TotalCost — суммарная стоимость за период [fromStr; toStr] c фильтрами
-func (s *SubscriptionService) TotalCost(ctx context.Context, fromStr, toStr, userIDStr, serviceName string) (int, error) {
+func (s *SubscriptionService) TotalCost(ctx context.Context, fromStr, toStr, userIDStr, serviceName string) (_ int, err error) {
+	ctx, span := startSpan(ctx, "SubscriptionService.TotalCost")
+	defer func() { endSpan(span, err) }()
+
 	from, err := parseMonthYear(fromStr) // "01-2006"
 	if err != nil {
 		return 0, fmt.Errorf("%w: from must be MM-YYYY", errValid)
@@ -283,6 +649,10 @@ func (s *SubscriptionService) TotalCost(ctx context.Context, fromStr, toStr, use
 		return 0, fmt.Errorf("%w: to must be >= from", errValid)
 	}
 
+	userIDStr, err = scopeUserID(ctx, userIDStr)
+	if err != nil {
+		return 0, err
+	}
 	var userIDPtr *uuid.UUID
 	if userIDStr != "" {
 		uid, err := uuid.Parse(userIDStr)
@@ -298,28 +668,48 @@ func (s *SubscriptionService) TotalCost(ctx context.Context, fromStr, toStr, use
 		Limit:       0,
 		Offset:      0,
 	}
+	// по пользователю считается его доля, в том числе в чужих совместных подписках
+	shared := userIDPtr != nil && s.members != nil
+	f.IncludeShared = shared
 
 	subs, err := s.repo.FindActiveInPeriod(ctx, from, to, f)
 	if err != nil {
 		return 0, fmt.Errorf("db error: %w", err)
 	}
+	// время после выборки — расчёт в Go; число подписок помогает понять, откуда задержка
+	span.SetAttributes(attribute.Int("subscriptions.count", len(subs)), attribute.Bool("subscriptions.shared", shared))
 
+	if shared {
+		total, err := s.sumUserCost(ctx, subs, *userIDPtr, from, to)
+		if err != nil {
+			return 0, fmt.Errorf("db error: %w", err)
+		}
+		return total, nil
+	}
+	return sumCost(subs, from, to), nil
+}
+
+// sumCost — стоимость подписок за период [from; to] с учётом их границ
+func sumCost(subs []models.Subscription, from, to time.Time) int {
 	total := 0
 	for _, sub := range subs {
-		// нормализуем границы пересечения
-		overlapStart := maxDate(sub.StartDate, from)
-		overlapEnd := to
-		if sub.EndDate != nil && sub.EndDate.Before(overlapEnd) {
-			overlapEnd = *sub.EndDate
-		}
-		if overlapEnd.Before(overlapStart) {
-			continue
-		}
-		months := monthsInclusive(overlapStart, overlapEnd)
-		total += months * sub.Price
+		total += billedMonths(sub, from, to) * sub.Price
 	}
+	return total
+}
 
-	return total, nil
+// billedMonths — число оплачиваемых месяцев подписки в периоде [from; to]
+func billedMonths(sub models.Subscription, from, to time.Time) int {
+	// нормализуем границы пересечения
+	overlapStart := maxDate(sub.StartDate, from)
+	overlapEnd := to
+	if sub.EndDate != nil && sub.EndDate.Before(overlapEnd) {
+		overlapEnd = *sub.EndDate
+	}
+	if overlapEnd.Before(overlapStart) {
+		return 0
+	}
+	return monthsInclusive(overlapStart, overlapEnd)
 }
 
 // monthsInclusive — количество месяцев между датами
@@ -335,3 +725,41 @@ func maxDate(a, b time.Time) time.Time {
 	}
 	return b
 }
+
+// checkMemberOverlap — участники совместной подписки sub (в том виде, в котором она будет сохранена)
+// не должны платить за тот же сервис в те же месяцы дважды; то же проверяет MemberService.Add
+func (s *SubscriptionService) checkMemberOverlap(ctx context.Context, sub *models.Subscription) error {
+	if s.members == nil {
+		return nil
+	}
+	members, err := s.members.ListBySubscriptions(ctx, []uuid.UUID{sub.ID})
+	if err != nil {
+		return fmt.Errorf("db error: %w", err)
+	}
+	for _, m := range members {
+		overlap, err := s.repo.ExistsOverlap(ctx, m.UserID, sub.ServiceName, sub.StartDate, sub.EndDate, &sub.ID)
+		if err != nil {
+			return err
+		}
+		if overlap {
+			return fmt.Errorf("%w: member %s", rejectOverlap(ctx, s.metrics, m.UserID, sub.ServiceName), m.UserID)
+		}
+	}
+	return nil
+}
+
+// rejectOverlap — учитывает отказ из-за пересечения в m (если задан) и возвращает ErrOverlap
+func rejectOverlap(ctx context.Context, m Metrics, userID uuid.UUID, serviceName string) error {
+	if m != nil {
+		m.OverlapRejected()
+	}
+	logging.FromContext(ctx).InfoContext(ctx, "subscription rejected: overlapping period",
+		"user_id", userID, "service_name", serviceName)
+	return ErrOverlap
+}
+
+// logChange — запись об изменении подписки в логе запроса
+func logChange(ctx context.Context, msg string, sub *models.Subscription) {
+	logging.FromContext(ctx).InfoContext(ctx, msg,
+		"subscription_id", sub.ID, "user_id", sub.UserID, "version", sub.Version)
+}
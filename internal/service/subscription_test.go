@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/auth"
 	"github.com/olesia8novoselova/Subscriptions/internal/models"
 	"github.com/olesia8novoselova/Subscriptions/internal/service"
 	"github.com/stretchr/testify/assert"
@@ -33,13 +34,13 @@ func (m *mockRepo) List(ctx context.Context, f models.ListFilters) ([]models.Sub
 	return args.Get(0).([]models.Subscription), args.Error(1)
 }
 
-func (m *mockRepo) Delete(ctx context.Context, id uuid.UUID) error {
-	args := m.Called(ctx, id)
+func (m *mockRepo) Delete(ctx context.Context, id uuid.UUID, version int) error {
+	args := m.Called(ctx, id, version)
 	return args.Error(0)
 }
 
-func (m *mockRepo) Update(ctx context.Context, id uuid.UUID, fields map[string]any) (*models.Subscription, error) {
-	args := m.Called(ctx, id, fields)
+func (m *mockRepo) Update(ctx context.Context, id uuid.UUID, version int, fields map[string]any) (*models.Subscription, error) {
+	args := m.Called(ctx, id, version, fields)
 	sub, _ := args.Get(0).(*models.Subscription)
 	return sub, args.Error(1)
 }
@@ -57,7 +58,7 @@ func (m *mockRepo) ExistsOverlap(ctx context.Context, userID uuid.UUID, serviceN
 // TestCreate_Valid - тестирует корректное создание подписки
 func TestCreate_Valid(t *testing.T) {
 	repo := new(mockRepo)
-	svc := service.NewSubscriptionService(repo, nil)
+	svc := service.NewSubscriptionService(repo)
 
 	userID := uuid.New()
 	start, _ := time.Parse("01-2006", "07-2025")
@@ -81,7 +82,7 @@ func TestCreate_Valid(t *testing.T) {
 // TestCreate_InvalidDate - тестирует создание подписки с некорректной датой
 func TestCreate_InvalidDate(t *testing.T) {
 	repo := new(mockRepo)
-	svc := service.NewSubscriptionService(repo, nil)
+	svc := service.NewSubscriptionService(repo)
 
 	req := models.CreateSubscriptionRequest{
 		ServiceName: "Netflix",
@@ -96,10 +97,36 @@ func TestCreate_InvalidDate(t *testing.T) {
 	assert.Contains(t, err.Error(), "validation error")
 }
 
+type overlapCounter struct{ n int }
+
+func (c *overlapCounter) OverlapRejected() { c.n++ }
+
 // TestCreate_Overlap - тестирует создание подписки с пересечением
 func TestCreate_Overlap(t *testing.T) {
 	repo := new(mockRepo)
-	svc := service.NewSubscriptionService(repo, nil)
+	svc := service.NewSubscriptionService(repo)
+
+	userID := uuid.New()
+	start, _ := time.Parse("01-2006", "07-2025")
+	req := models.CreateSubscriptionRequest{
+		ServiceName: "Netflix",
+		Price:       500,
+		UserID:      userID.String(),
+		StartDate:   "07-2025",
+	}
+
+	repo.On("ExistsOverlap", mock.Anything, userID, "Netflix", start, (*time.Time)(nil), (*uuid.UUID)(nil)).Return(true, nil)
+
+	sub, err := svc.Create(context.Background(), req)
+	assert.Nil(t, sub)
+	assert.ErrorIs(t, err, service.ErrOverlap)
+}
+
+// TestCreate_OverlapCounted - отказ из-за пересечения учитывается в метриках, в том числе отказ самого хранилища
+func TestCreate_OverlapCounted(t *testing.T) {
+	repo := new(mockRepo)
+	counter := &overlapCounter{}
+	svc := service.NewSubscriptionService(repo, service.WithMetrics(counter))
 
 	userID := uuid.New()
 	start, _ := time.Parse("01-2006", "07-2025")
@@ -115,12 +142,24 @@ func TestCreate_Overlap(t *testing.T) {
 	sub, err := svc.Create(context.Background(), req)
 	assert.Nil(t, sub)
 	assert.ErrorIs(t, err, service.ErrOverlap)
+	assert.Equal(t, 1, counter.n)
+
+	// пересечение, записанное параллельно после проверки, отклоняет само хранилище
+	req.StartDate = "08-2025"
+	start = start.AddDate(0, 1, 0)
+	repo.On("ExistsOverlap", mock.Anything, userID, "Netflix", start, (*time.Time)(nil), (*uuid.UUID)(nil)).Return(false, nil)
+	repo.On("Create", mock.Anything, mock.Anything).Return(service.ErrOverlap)
+
+	sub, err = svc.Create(context.Background(), req)
+	assert.Nil(t, sub)
+	assert.ErrorIs(t, err, service.ErrOverlap)
+	assert.Equal(t, 2, counter.n)
 }
 
 // TestGetByID_Success - тестирует получение подписки по ID
 func TestGetByID_NotFound(t *testing.T) {
 	repo := new(mockRepo)
-	svc := service.NewSubscriptionService(repo, nil)
+	svc := service.NewSubscriptionService(repo)
 
 	id := uuid.New()
 	repo.On("FindByID", mock.Anything, id).Return(nil, gorm.ErrRecordNotFound)
@@ -133,7 +172,7 @@ func TestGetByID_NotFound(t *testing.T) {
 // TestList_DefaultLimitOffset - тестирует получение списка подписок со значениями по умолчанию для limit и offset
 func TestList_DefaultLimitOffset(t *testing.T) {
 	repo := new(mockRepo)
-	svc := service.NewSubscriptionService(repo, nil)
+	svc := service.NewSubscriptionService(repo)
 
 	expected := []models.Subscription{{ServiceName: "Test"}}
 	repo.On("List", mock.Anything, mock.Anything).Return(expected, nil)
@@ -143,22 +182,45 @@ func TestList_DefaultLimitOffset(t *testing.T) {
 	assert.Equal(t, expected, list)
 }
 
+// TestList_Pagination - тестирует настраиваемый размер страницы и его замену во время работы
+func TestList_Pagination(t *testing.T) {
+	repo := new(mockRepo)
+	svc := service.NewSubscriptionService(repo, service.WithPagination(service.Pagination{DefaultLimit: 5, MaxLimit: 10}))
+
+	limitIs := func(n int) any {
+		return mock.MatchedBy(func(f models.ListFilters) bool { return f.Limit == n })
+	}
+	repo.On("List", mock.Anything, limitIs(5)).Return([]models.Subscription{}, nil).Once()
+	repo.On("List", mock.Anything, limitIs(10)).Return([]models.Subscription{}, nil).Once()
+	repo.On("List", mock.Anything, limitIs(50)).Return([]models.Subscription{}, nil).Once()
+
+	_, err := svc.List(context.Background(), "", "", 0, 0)
+	assert.NoError(t, err)
+	_, err = svc.List(context.Background(), "", "", 500, 0)
+	assert.NoError(t, err)
+
+	svc.SetPagination(service.Pagination{DefaultLimit: 20, MaxLimit: 50})
+	_, err = svc.List(context.Background(), "", "", 500, 0)
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
 // TestDelete_Success - тестирует успешное удаление подписки
 func TestDelete_Success(t *testing.T) {
 	repo := new(mockRepo)
-	svc := service.NewSubscriptionService(repo, nil)
+	svc := service.NewSubscriptionService(repo)
 
 	id := uuid.New()
-	repo.On("Delete", mock.Anything, id).Return(nil)
+	repo.On("Delete", mock.Anything, id, 0).Return(nil)
 
-	err := svc.Delete(context.Background(), id.String())
+	err := svc.Delete(context.Background(), id.String(), 0)
 	assert.NoError(t, err)
 }
 
 // TestPatch_Overlap - тестирует обновление подписки с пересечением
 func TestPatch_Overlap(t *testing.T) {
 	repo := new(mockRepo)
-	svc := service.NewSubscriptionService(repo, nil)
+	svc := service.NewSubscriptionService(repo)
 
 	id := uuid.New()
 	start := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
@@ -174,16 +236,53 @@ func TestPatch_Overlap(t *testing.T) {
 	repo.On("ExistsOverlap", mock.Anything, existing.UserID, existing.ServiceName, start, (*time.Time)(nil), &id).Return(true, nil)
 
 	req := models.UpdateSubscriptionRequest{ServiceName: strPtr("NewName")}
-	sub, err := svc.Patch(context.Background(), id.String(), req)
+	sub, err := svc.Patch(context.Background(), id.String(), req, 0)
 
 	assert.Nil(t, sub)
 	assert.ErrorIs(t, err, service.ErrOverlap)
 }
 
+// TestPatch_MemberOverlap - продление совместной подписки не должно пересечься с подпиской участника
+// на тот же сервис, как и при добавлении участника; то же при замене (PUT)
+func TestPatch_MemberOverlap(t *testing.T) {
+	repo := new(mockRepo)
+	members := new(mockMemberRepo)
+	svc := service.NewSubscriptionService(repo, service.WithMembers(members))
+
+	id, member := uuid.New(), uuid.New()
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	later := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+	existing := &models.Subscription{ID: id, ServiceName: "Spotify", Price: 400, UserID: uuid.New(),
+		StartDate: start, EndDate: &end, Version: 1}
+
+	repo.On("FindByID", mock.Anything, id).Return(existing, nil)
+	repo.On("ExistsOverlap", mock.Anything, existing.UserID, "Spotify", start, &later, &id).Return(false, nil)
+	members.On("ListBySubscriptions", mock.Anything, []uuid.UUID{id}).Return([]models.SubscriptionMember{
+		{SubscriptionID: id, UserID: member, ShareType: models.ShareTypePercent, ShareValue: 50},
+	}, nil)
+	// у участника своя подписка на Spotify с 07-2025
+	repo.On("ExistsOverlap", mock.Anything, member, "Spotify", start, &later, &id).Return(true, nil)
+
+	sub, err := svc.Patch(context.Background(), id.String(), models.UpdateSubscriptionRequest{EndDate: strPtr("12-2025")}, 0)
+	assert.Nil(t, sub)
+	assert.ErrorIs(t, err, service.ErrOverlap)
+
+	_, _, err = svc.Replace(context.Background(), id.String(), models.CreateSubscriptionRequest{
+		ServiceName: "Spotify",
+		Price:       400,
+		UserID:      existing.UserID.String(),
+		StartDate:   "01-2025",
+		EndDate:     strPtr("12-2025"),
+	}, 0)
+	assert.ErrorIs(t, err, service.ErrOverlap)
+	repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
 // TestTotalCost_Calculation - тестирует корректный расчет суммарной стоимости подписок
 func TestTotalCost_Calculation(t *testing.T) {
 	repo := new(mockRepo)
-	svc := service.NewSubscriptionService(repo, nil)
+	svc := service.NewSubscriptionService(repo)
 
 	from, _ := time.Parse("01-2006", "07-2025")
 	to, _ := time.Parse("01-2006", "09-2025")
@@ -199,4 +298,161 @@ func TestTotalCost_Calculation(t *testing.T) {
 }
 
 func strPtr(s string) *string        { return &s }
+func intPtr(n int) *int              { return &n }
 func ptrTime(t time.Time) *time.Time { return &t }
+
+// TestPatch_PreconditionFailed - изменение по устаревшей версии (If-Match) отклоняется
+func TestPatch_PreconditionFailed(t *testing.T) {
+	repo := new(mockRepo)
+	svc := service.NewSubscriptionService(repo)
+
+	id := uuid.New()
+	existing := &models.Subscription{
+		ID:          id,
+		ServiceName: "Test",
+		UserID:      uuid.New(),
+		StartDate:   time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC),
+		Version:     5,
+	}
+	repo.On("FindByID", mock.Anything, id).Return(existing, nil)
+
+	sub, err := svc.Patch(context.Background(), id.String(), models.UpdateSubscriptionRequest{Price: intPtr(600)}, 4)
+	assert.Nil(t, sub)
+	assert.ErrorIs(t, err, service.ErrPreconditionFailed)
+	repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TestPatch_UncachedReads - перед записью версия читается из хранилища без кеша, устаревший кеш не даёт ложный 412
+func TestPatch_UncachedReads(t *testing.T) {
+	cached := new(mockRepo)
+	fresh := new(mockRepo)
+	svc := service.NewSubscriptionService(cached, service.WithUncachedReads(fresh))
+
+	id := uuid.New()
+	current := &models.Subscription{
+		ID:          id,
+		ServiceName: "Test",
+		UserID:      uuid.New(),
+		StartDate:   time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC),
+		Version:     5,
+	}
+	fresh.On("FindByID", mock.Anything, id).Return(current, nil)
+	cached.On("ExistsOverlap", mock.Anything, current.UserID, "Test", mock.Anything, mock.Anything, &id).Return(false, nil)
+	cached.On("Update", mock.Anything, id, 5, mock.Anything).Return(&models.Subscription{ID: id, Version: 6}, nil)
+	cached.On("Delete", mock.Anything, id, 5).Return(nil)
+
+	sub, err := svc.Patch(context.Background(), id.String(), models.UpdateSubscriptionRequest{Price: intPtr(600)}, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, sub.Version)
+
+	assert.NoError(t, svc.Delete(context.Background(), id.String(), 5))
+	cached.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+	cached.AssertExpectations(t)
+}
+
+// TestReplace_Upsert - PUT несуществующей подписки создаёт её с переданным ID
+func TestReplace_Upsert(t *testing.T) {
+	repo := new(mockRepo)
+	svc := service.NewSubscriptionService(repo)
+
+	id := uuid.New()
+	repo.On("FindByID", mock.Anything, id).Return(nil, gorm.ErrRecordNotFound)
+	repo.On("ExistsOverlap", mock.Anything, mock.Anything, "Netflix", mock.Anything, (*time.Time)(nil), (*uuid.UUID)(nil)).Return(false, nil)
+	repo.On("Create", mock.Anything, mock.MatchedBy(func(s *models.Subscription) bool { return s.ID == id })).Return(nil)
+
+	sub, created, err := svc.Replace(context.Background(), id.String(), models.CreateSubscriptionRequest{
+		ServiceName: "Netflix",
+		Price:       500,
+		UserID:      uuid.NewString(),
+		StartDate:   "07-2025",
+	}, 0)
+	assert.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, id, sub.ID)
+
+	// с If-Match подписка не создаётся
+	_, _, err = svc.Replace(context.Background(), id.String(), models.CreateSubscriptionRequest{
+		ServiceName: "Netflix",
+		Price:       500,
+		UserID:      uuid.NewString(),
+		StartDate:   "07-2025",
+	}, 1)
+	assert.ErrorIs(t, err, service.ErrPreconditionFailed)
+	repo.AssertNumberOfCalls(t, "Create", 1)
+}
+
+// TestReplace_NotOwnedOrTaken - чужая подписка не раскрывается, а ID, занятый в другой организации, даёт конфликт
+func TestReplace_NotOwnedOrTaken(t *testing.T) {
+	repo := new(mockRepo)
+	svc := service.NewSubscriptionService(repo)
+
+	alice := uuid.New()
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: alice, Role: models.RoleUser})
+	req := models.CreateSubscriptionRequest{ServiceName: "Netflix", Price: 500, StartDate: "07-2025"}
+
+	foreign := uuid.New()
+	repo.On("FindByID", mock.Anything, foreign).Return(&models.Subscription{ID: foreign, UserID: uuid.New(), Version: 1}, nil)
+	_, _, err := svc.Replace(ctx, foreign.String(), req, 0)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	taken := uuid.New()
+	repo.On("FindByID", mock.Anything, taken).Return(nil, gorm.ErrRecordNotFound)
+	repo.On("ExistsOverlap", mock.Anything, alice, "Netflix", mock.Anything, (*time.Time)(nil), (*uuid.UUID)(nil)).Return(false, nil)
+	repo.On("Create", mock.Anything, mock.Anything).Return(gorm.ErrDuplicatedKey)
+	_, created, err := svc.Replace(ctx, taken.String(), req, 0)
+	assert.ErrorIs(t, err, service.ErrIDConflict)
+	assert.False(t, created)
+	repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TestReplace_ClearsOptionalFields - PUT очищает end_date и category, которых нет в запросе
+func TestReplace_ClearsOptionalFields(t *testing.T) {
+	repo := new(mockRepo)
+	svc := service.NewSubscriptionService(repo)
+
+	id, userID := uuid.New(), uuid.New()
+	end := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+	existing := &models.Subscription{
+		ID:          id,
+		ServiceName: "Netflix",
+		Price:       500,
+		UserID:      userID,
+		StartDate:   time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:     &end,
+		Category:    "video",
+		Version:     3,
+	}
+	repo.On("FindByID", mock.Anything, id).Return(existing, nil)
+	repo.On("ExistsOverlap", mock.Anything, userID, "Netflix", mock.Anything, (*time.Time)(nil), &id).Return(false, nil)
+	repo.On("Update", mock.Anything, id, 3, mock.MatchedBy(func(f map[string]any) bool {
+		return f["end_date"] == nil && f["category"] == "" && f["price"] == 600
+	})).Return(&models.Subscription{ID: id, Version: 4}, nil)
+
+	sub, created, err := svc.Replace(context.Background(), id.String(), models.CreateSubscriptionRequest{
+		ServiceName: "Netflix",
+		Price:       600,
+		UserID:      userID.String(),
+		StartDate:   "07-2025",
+	}, 3)
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, 4, sub.Version)
+	repo.AssertExpectations(t)
+}
+
+// TestListByUsers - подписки нескольких пользователей загружаются одним запросом; обычный пользователь видит только свои
+func TestListByUsers(t *testing.T) {
+	repo := new(mockRepo)
+	svc := service.NewSubscriptionService(repo)
+
+	alice, bob := uuid.New(), uuid.New()
+	repo.On("List", mock.Anything, mock.MatchedBy(func(f models.ListFilters) bool { return len(f.UserIDs) == 1 && f.UserIDs[0] == alice })).
+		Return([]models.Subscription{{UserID: alice, ServiceName: "Netflix"}}, nil)
+
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: alice, Role: models.RoleUser})
+	res, err := svc.ListByUsers(ctx, []uuid.UUID{alice, bob})
+	assert.NoError(t, err)
+	assert.Len(t, res[alice], 1)
+	assert.Empty(t, res[bob])
+	repo.AssertNumberOfCalls(t, "List", 1)
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const instrumentationName = "github.com/olesia8novoselova/Subscriptions/internal/service"
+
+// startSpan — спан метода сервиса; закрывается endSpan
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name)
+}
+
+// endSpan — записывает ошибку и закрывает спан. Ожидаемые доменные ошибки (валидация, доступ,
+// конфликты) не помечают спан как ошибочный: это ответ клиенту, а не сбой сервиса.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		if !isDomainError(err) {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
+
+func isDomainError(err error) bool {
+	for _, target := range []error{
+		ErrValidation, ErrForbidden, ErrOverlap, ErrPreconditionFailed, ErrBudgetExceeded, ErrIDConflict, ErrUnknownUser, gorm.ErrRecordNotFound,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
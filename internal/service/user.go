@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"gorm.io/gorm"
+)
+
+var ErrUserHasSubscriptions = errors.New("user has subscriptions")
+
+type UserRepository interface {
+	Create(ctx context.Context, u *models.User) error
+	// Provision — создает пользователя без имени, если его ещё нет
+	Provision(ctx context.Context, id uuid.UUID) error
+	FindByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+	List(ctx context.Context, limit, offset int) ([]models.User, error)
+	Update(ctx context.Context, id uuid.UUID, fields map[string]any) (*models.User, error)
+	// Delete — удаляет пользователя и его бюджеты. Подписки удаляются (cascade)
+	// или передаются пользователю reassignTo (reassign) в той же транзакции.
+	Delete(ctx context.Context, id uuid.UUID, policy string, reassignTo uuid.UUID) error
+}
+
+// WithUsers — включает проверку существования пользователя при создании подписки.
+// Обычный пользователь, впервые создающий подписку, заводится автоматически
+func WithUsers(users UserRepository) Option {
+	return func(s *SubscriptionService) {
+		s.users = users
+	}
+}
+
+type UserService struct {
+	users   UserRepository
+	subs    SubscriptionRepository
+	members MemberRepository
+	metrics Metrics
+	pages   PaginationSource
+	log     *slog.Logger
+	now     func() time.Time
+}
+
+// NewUserService — сервис пользователей; members и metrics могут быть nil.
+// Размер страниц List и Subscriptions берётся из pages (обычно SubscriptionService, чтобы SIGHUP менял его везде),
+// при nil — DefaultPagination
+func NewUserService(users UserRepository, subs SubscriptionRepository, members MemberRepository, metrics Metrics, pages PaginationSource, log *slog.Logger) *UserService {
+	if pages == nil {
+		pages = DefaultPagination
+	}
+	return &UserService{users: users, subs: subs, members: members, metrics: metrics, pages: pages, log: log, now: time.Now}
+}
+
+// Create — создает пользователя. Доступно только администратору
+func (s *UserService) Create(ctx context.Context, req models.CreateUserRequest) (*models.User, error) {
+	if _, ok := restricted(ctx); ok {
+		return nil, fmt.Errorf("%w: only admin can create users", ErrForbidden)
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", errValid)
+	}
+	email, err := normalizeEmail(req.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	id := uuid.New()
+	if req.ID != "" {
+		if id, err = uuid.Parse(req.ID); err != nil {
+			return nil, fmt.Errorf("%w: id must be UUID", errValid)
+		}
+	}
+
+	u := &models.User{ID: id, Name: name, Email: email}
+	if err := s.users.Create(ctx, u); err != nil {
+		return nil, fmt.Errorf("db error: %w", err)
+	}
+	return u, nil
+}
+
+// GetByID — получает пользователя по ID
+func (s *UserService) GetByID(ctx context.Context, idStr string) (*models.User, error) {
+	id, err := s.parseUserID(ctx, idStr)
+	if err != nil {
+		return nil, err
+	}
+	return s.find(ctx, id)
+}
+
+// List — список пользователей с пагинацией. Обычный пользователь видит только себя
+func (s *UserService) List(ctx context.Context, limit, offset int) ([]models.User, error) {
+	if p, ok := restricted(ctx); ok {
+		u, err := s.find(ctx, p.UserID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return []models.User{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []models.User{*u}, nil
+	}
+
+	limit, offset = s.pages.Pagination().clamp(limit, offset)
+	list, err := s.users.List(ctx, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("db error: %w", err)
+	}
+	return list, nil
+}
+
+// Patch — частично обновляет имя и email пользователя
+func (s *UserService) Patch(ctx context.Context, idStr string, req models.UpdateUserRequest) (*models.User, error) {
+	id, err := s.parseUserID(ctx, idStr)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]any{}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, fmt.Errorf("%w: name cannot be empty", errValid)
+		}
+		fields["name"] = name
+	}
+	if req.Email != nil {
+		email, err := normalizeEmail(req.Email)
+		if err != nil {
+			return nil, err
+		}
+		if email == nil {
+			fields["email"] = nil
+		} else {
+			fields["email"] = *email
+		}
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: nothing to update", errValid)
+	}
+
+	u, err := s.users.Update(ctx, id, fields)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return nil, fmt.Errorf("db error: %w", err)
+	}
+	return u, nil
+}
+
+// Delete — удаляет пользователя. Доступно только администратору.
+// policy определяет судьбу оставшихся подписок: block (по умолчанию) — удаление запрещено,
+// cascade — подписки удаляются, reassign — подписки передаются пользователю reassignToStr.
+func (s *UserService) Delete(ctx context.Context, idStr, policy, reassignToStr string) error {
+	if _, ok := restricted(ctx); ok {
+		return fmt.Errorf("%w: only admin can delete users", ErrForbidden)
+	}
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return fmt.Errorf("%w: id must be UUID", errValid)
+	}
+	if policy == "" {
+		policy = models.UserDeleteBlock
+	}
+
+	var reassignTo uuid.UUID
+	switch policy {
+	case models.UserDeleteBlock, models.UserDeleteCascade:
+		if reassignToStr != "" {
+			return fmt.Errorf("%w: reassign_to is allowed only with policy reassign", errValid)
+		}
+	case models.UserDeleteReassign:
+		if reassignTo, err = uuid.Parse(reassignToStr); err != nil {
+			return fmt.Errorf("%w: reassign_to must be UUID", errValid)
+		}
+		if reassignTo == id {
+			return fmt.Errorf("%w: reassign_to must differ from id", errValid)
+		}
+	default:
+		return fmt.Errorf("%w: policy must be block, cascade or reassign", errValid)
+	}
+
+	if _, err := s.find(ctx, id); err != nil {
+		return err
+	}
+	// Limit -1 — без ограничения
+	subs, err := s.subs.List(ctx, models.ListFilters{UserID: &id, Limit: -1})
+	if err != nil {
+		return fmt.Errorf("db error: %w", err)
+	}
+
+	switch {
+	case len(subs) > 0 && policy == models.UserDeleteBlock:
+		return fmt.Errorf("%w: %d subscription(s), use policy cascade or reassign", ErrUserHasSubscriptions, len(subs))
+	case policy == models.UserDeleteReassign:
+		if _, err := s.find(ctx, reassignTo); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: reassign_to user not found", errValid)
+			}
+			return err
+		}
+		// подписки получателя не должны пересекаться с передаваемыми
+		for _, sub := range subs {
+			overlap, err := s.subs.ExistsOverlap(ctx, reassignTo, sub.ServiceName, sub.StartDate, sub.EndDate, &sub.ID)
+			if err != nil {
+				return fmt.Errorf("db error: %w", err)
+			}
+			if overlap {
+				return fmt.Errorf("%w: %s", rejectOverlap(ctx, s.metrics, reassignTo, sub.ServiceName), sub.ServiceName)
+			}
+		}
+	}
+
+	if err := s.users.Delete(ctx, id, policy, reassignTo); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return gorm.ErrRecordNotFound
+		case errors.Is(err, ErrOverlap):
+			// получателю параллельно добавили пересекающуюся подписку
+			return rejectOverlap(ctx, s.metrics, reassignTo, "")
+		}
+		return fmt.Errorf("db error: %w", err)
+	}
+	return nil
+}
+
+// Subscriptions — подписки пользователя с пагинацией
+func (s *UserService) Subscriptions(ctx context.Context, idStr string, limit, offset int) ([]models.Subscription, error) {
+	id, err := s.parseUserID(ctx, idStr)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.find(ctx, id); err != nil {
+		return nil, err
+	}
+
+	limit, offset = s.pages.Pagination().clamp(limit, offset)
+	list, err := s.subs.List(ctx, models.ListFilters{UserID: &id, Limit: limit, Offset: offset})
+	if err != nil {
+		return nil, fmt.Errorf("db error: %w", err)
+	}
+	return list, nil
+}
+
+// Summary — число активных подписок пользователя (вместе с совместными, где он участник)
+// и его расходы в текущем месяце, посчитанные так же, как TotalCost
+func (s *UserService) Summary(ctx context.Context, idStr string) (*models.UserSummary, error) {
+	id, err := s.parseUserID(ctx, idStr)
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	subs, err := s.subs.FindActiveInPeriod(ctx, month, month, models.ListFilters{UserID: &id, IncludeShared: s.members != nil})
+	if err != nil {
+		return nil, fmt.Errorf("db error: %w", err)
+	}
+	prices, err := userPrices(ctx, s.members, subs, id)
+	if err != nil {
+		return nil, fmt.Errorf("db error: %w", err)
+	}
+
+	return &models.UserSummary{
+		UserID:              u.ID,
+		Name:                u.Name,
+		Month:               month.Format("01-2006"),
+		ActiveSubscriptions: len(subs),
+		MonthlySpend:        sumPriced(subs, prices, month, month),
+	}, nil
+}
+
+// parseUserID — ID пользователя из пути; обычному пользователю доступен только он сам
+func (s *UserService) parseUserID(ctx context.Context, idStr string) (uuid.UUID, error) {
+	idStr, err := scopeUserID(ctx, idStr)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: id must be UUID", errValid)
+	}
+	return id, nil
+}
+
+func (s *UserService) find(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	u, err := s.users.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return nil, fmt.Errorf("db error: %w", err)
+	}
+	return u, nil
+}
+
+// normalizeEmail — пустой email означает его отсутствие
+func normalizeEmail(email *string) (*string, error) {
+	if email == nil {
+		return nil, nil
+	}
+	e := strings.TrimSpace(*email)
+	if e == "" {
+		return nil, nil
+	}
+	if at := strings.Index(e, "@"); at <= 0 || at == len(e)-1 {
+		return nil, fmt.Errorf("%w: email is invalid", errValid)
+	}
+	return &e, nil
+}
@@ -0,0 +1,217 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/auth"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/olesia8novoselova/Subscriptions/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+type mockUserRepo struct {
+	mock.Mock
+}
+
+func (m *mockUserRepo) Create(ctx context.Context, u *models.User) error {
+	args := m.Called(ctx, u)
+	return args.Error(0)
+}
+
+func (m *mockUserRepo) Provision(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *mockUserRepo) FindByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	args := m.Called(ctx, id)
+	u, _ := args.Get(0).(*models.User)
+	return u, args.Error(1)
+}
+
+func (m *mockUserRepo) List(ctx context.Context, limit, offset int) ([]models.User, error) {
+	args := m.Called(ctx, limit, offset)
+	return args.Get(0).([]models.User), args.Error(1)
+}
+
+func (m *mockUserRepo) Update(ctx context.Context, id uuid.UUID, fields map[string]any) (*models.User, error) {
+	args := m.Called(ctx, id, fields)
+	u, _ := args.Get(0).(*models.User)
+	return u, args.Error(1)
+}
+
+func (m *mockUserRepo) Delete(ctx context.Context, id uuid.UUID, policy string, reassignTo uuid.UUID) error {
+	args := m.Called(ctx, id, policy, reassignTo)
+	return args.Error(0)
+}
+
+// TestCreate_UnknownUser - подписку нельзя создать для несуществующего пользователя
+func TestCreate_UnknownUser(t *testing.T) {
+	users := new(mockUserRepo)
+	svc := service.NewSubscriptionService(new(mockRepo), service.WithUsers(users))
+
+	userID := uuid.New()
+	users.On("FindByID", mock.Anything, userID).Return(nil, gorm.ErrRecordNotFound)
+
+	sub, err := svc.Create(context.Background(), models.CreateSubscriptionRequest{
+		ServiceName: "Netflix",
+		Price:       500,
+		UserID:      userID.String(),
+		StartDate:   "07-2025",
+	})
+	assert.Nil(t, sub)
+	assert.ErrorContains(t, err, "user not found")
+}
+
+// TestCreate_ProvisionsSelf - обычный пользователь, впервые создающий подписку, заводится автоматически
+func TestCreate_ProvisionsSelf(t *testing.T) {
+	users := new(mockUserRepo)
+	repo := new(mockRepo)
+	svc := service.NewSubscriptionService(repo, service.WithUsers(users))
+
+	userID := uuid.New()
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: userID, Role: models.RoleUser})
+	users.On("Provision", mock.Anything, userID).Return(nil)
+	repo.On("ExistsOverlap", mock.Anything, userID, "Netflix", mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+	repo.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+	sub, err := svc.Create(ctx, models.CreateSubscriptionRequest{
+		ServiceName: "Netflix",
+		Price:       500,
+		StartDate:   "07-2025",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, userID, sub.UserID)
+	users.AssertExpectations(t)
+	users.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+}
+
+// TestUserDelete_Block - пользователя с подписками нельзя удалить без политики cascade или reassign
+func TestUserDelete_Block(t *testing.T) {
+	users := new(mockUserRepo)
+	subs := new(mockRepo)
+	svc := service.NewUserService(users, subs, nil, nil, nil, nil)
+
+	id := uuid.New()
+	users.On("FindByID", mock.Anything, id).Return(&models.User{ID: id}, nil)
+	subs.On("List", mock.Anything, mock.Anything).Return([]models.Subscription{{ID: uuid.New(), UserID: id}}, nil)
+
+	err := svc.Delete(context.Background(), id.String(), "", "")
+	assert.True(t, errors.Is(err, service.ErrUserHasSubscriptions))
+	users.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TestUserDelete_Cascade - при политике cascade пользователь удаляется вместе с подписками
+func TestUserDelete_Cascade(t *testing.T) {
+	users := new(mockUserRepo)
+	subs := new(mockRepo)
+	svc := service.NewUserService(users, subs, nil, nil, nil, nil)
+
+	id := uuid.New()
+	users.On("FindByID", mock.Anything, id).Return(&models.User{ID: id}, nil)
+	subs.On("List", mock.Anything, mock.Anything).Return([]models.Subscription{{ID: uuid.New(), UserID: id}}, nil)
+	users.On("Delete", mock.Anything, id, models.UserDeleteCascade, uuid.Nil).Return(nil)
+
+	assert.NoError(t, svc.Delete(context.Background(), id.String(), models.UserDeleteCascade, ""))
+	users.AssertExpectations(t)
+}
+
+// TestUserDelete_ReassignOverlap - подписки не передаются, если они пересекаются с подписками получателя;
+// отказ учитывается в метриках
+func TestUserDelete_ReassignOverlap(t *testing.T) {
+	users := new(mockUserRepo)
+	subs := new(mockRepo)
+	counter := &overlapCounter{}
+	svc := service.NewUserService(users, subs, nil, counter, nil, nil)
+
+	id, target := uuid.New(), uuid.New()
+	start := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	users.On("FindByID", mock.Anything, id).Return(&models.User{ID: id}, nil)
+	users.On("FindByID", mock.Anything, target).Return(&models.User{ID: target}, nil)
+	subID := uuid.New()
+	subs.On("List", mock.Anything, mock.Anything).Return([]models.Subscription{
+		{ID: subID, UserID: id, ServiceName: "Netflix", StartDate: start},
+	}, nil)
+	subs.On("ExistsOverlap", mock.Anything, target, "Netflix", start, (*time.Time)(nil), &subID).Return(true, nil)
+
+	err := svc.Delete(context.Background(), id.String(), models.UserDeleteReassign, target.String())
+	assert.True(t, errors.Is(err, service.ErrOverlap))
+	users.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	assert.Equal(t, 1, counter.n)
+}
+
+// TestUserSummary - сводка считает активные подписки и расходы текущего месяца
+func TestUserSummary(t *testing.T) {
+	users := new(mockUserRepo)
+	subs := new(mockRepo)
+	svc := service.NewUserService(users, subs, nil, nil, nil, nil)
+
+	id := uuid.New()
+	users.On("FindByID", mock.Anything, id).Return(&models.User{ID: id, Name: "Alice"}, nil)
+	subs.On("FindActiveInPeriod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]models.Subscription{
+		{ServiceName: "Netflix", Price: 500, UserID: id, StartDate: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{ServiceName: "Spotify", Price: 300, UserID: id, StartDate: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}, nil)
+
+	sum, err := svc.Summary(context.Background(), id.String())
+	assert.NoError(t, err)
+	assert.Equal(t, "Alice", sum.Name)
+	assert.Equal(t, 2, sum.ActiveSubscriptions)
+	assert.Equal(t, 800, sum.MonthlySpend)
+}
+
+// TestUserSummary_Shared - сводка учитывает совместные подписки и считает долю пользователя, как TotalCost
+func TestUserSummary_Shared(t *testing.T) {
+	users := new(mockUserRepo)
+	subs := new(mockRepo)
+	members := new(mockMemberRepo)
+	svc := service.NewUserService(users, subs, members, nil, nil, nil)
+
+	id, owner := uuid.New(), uuid.New()
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	own := models.Subscription{ID: uuid.New(), ServiceName: "Okko", Price: 300, UserID: id, StartDate: start}
+	family := models.Subscription{ID: uuid.New(), ServiceName: "Spotify Family", Price: 400, UserID: owner, StartDate: start}
+	users.On("FindByID", mock.Anything, id).Return(&models.User{ID: id, Name: "Alice"}, nil)
+	subs.On("FindActiveInPeriod", mock.Anything, mock.Anything, mock.Anything,
+		mock.MatchedBy(func(f models.ListFilters) bool { return f.IncludeShared && *f.UserID == id })).
+		Return([]models.Subscription{own, family}, nil)
+	members.On("ListBySubscriptions", mock.Anything, []uuid.UUID{own.ID, family.ID}).Return([]models.SubscriptionMember{
+		{SubscriptionID: family.ID, UserID: id, ShareType: models.ShareTypeFixed, ShareValue: 100},
+	}, nil)
+
+	sum, err := svc.Summary(context.Background(), id.String())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, sum.ActiveSubscriptions)
+	assert.Equal(t, 400, sum.MonthlySpend) // 300 + доля 100
+}
+
+// TestUserList_Pagination - списки пользователей и их подписок берут размер страницы из SubscriptionService, в том числе после SetPagination
+func TestUserList_Pagination(t *testing.T) {
+	users := new(mockUserRepo)
+	subs := new(mockRepo)
+	pages := service.NewSubscriptionService(subs, service.WithPagination(service.Pagination{DefaultLimit: 5, MaxLimit: 10}))
+	svc := service.NewUserService(users, subs, nil, nil, pages, nil)
+
+	id := uuid.New()
+	users.On("List", mock.Anything, 5, 0).Return([]models.User{}, nil).Once()
+	users.On("List", mock.Anything, 10, 0).Return([]models.User{}, nil).Once()
+	users.On("FindByID", mock.Anything, id).Return(&models.User{ID: id}, nil)
+	subs.On("List", mock.Anything, models.ListFilters{UserID: &id, Limit: 30}).Return([]models.Subscription{}, nil).Once()
+
+	_, err := svc.List(context.Background(), 0, 0)
+	assert.NoError(t, err)
+	_, err = svc.List(context.Background(), 50, 0)
+	assert.NoError(t, err)
+
+	pages.SetPagination(service.Pagination{DefaultLimit: 30, MaxLimit: 40})
+	_, err = svc.Subscriptions(context.Background(), id.String(), 0, 0)
+	assert.NoError(t, err)
+	users.AssertExpectations(t)
+	subs.AssertExpectations(t)
+}
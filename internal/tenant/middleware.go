@@ -0,0 +1,83 @@
+package tenant
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/auth"
+	"github.com/olesia8novoselova/Subscriptions/pkg/logging"
+)
+
+// Header — заголовок для явного выбора арендатора
+const Header = "X-Tenant-ID"
+
+var (
+	ErrInvalidID = errors.New(Header + " must be UUID")
+	// ErrMismatch — запрошен арендатор, отличный от того, к которому привязан principal
+	ErrMismatch = errors.New("access to another tenant")
+	// ErrNotAllowed — principal без привязки и без прав администратора выбирает арендатора
+	ErrNotAllowed = errors.New("caller is not allowed to choose a tenant")
+)
+
+// Resolve — арендатор запроса по principal из контекста и запрошенному значению (пусто — не задан).
+// Арендатор, к которому привязан principal (claim tenant_id или API-ключ), имеет приоритет,
+// и запрошенный может только совпадать с ним. Выбирать арендатора могут администраторы
+// без привязки и вызовы без аутентификации (AUTH_DISABLED). Иначе — DefaultID.
+func Resolve(ctx context.Context, requested string) (uuid.UUID, error) {
+	var req *uuid.UUID
+	if requested != "" {
+		id, err := uuid.Parse(requested)
+		if err != nil {
+			return uuid.Nil, ErrInvalidID
+		}
+		req = &id
+	}
+
+	p, authenticated := auth.FromContext(ctx)
+	switch {
+	case authenticated && p.TenantID != uuid.Nil:
+		if req != nil && *req != p.TenantID {
+			return uuid.Nil, ErrMismatch
+		}
+		return p.TenantID, nil
+	case req != nil:
+		if authenticated && !p.IsAdmin() {
+			return uuid.Nil, ErrNotAllowed
+		}
+		return *req, nil
+	}
+	return DefaultID, nil
+}
+
+// Middleware — определяет арендатора запроса по заголовку X-Tenant-ID (см. Resolve)
+func Middleware(log *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested := r.Header.Get(Header)
+		id, err := Resolve(r.Context(), requested)
+		switch {
+		case errors.Is(err, ErrInvalidID):
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		case errors.Is(err, ErrMismatch):
+			p, _ := auth.FromContext(r.Context())
+			log.WarnContext(r.Context(), "tenant mismatch", "subject", p.Subject, "tenant_id", p.TenantID, "requested", requested)
+			writeError(w, http.StatusForbidden, err.Error())
+			return
+		case err != nil:
+			writeError(w, http.StatusForbidden, err.Error())
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithTenant(r.Context(), id)))
+	})
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(logging.ErrorBody(w, msg))
+}
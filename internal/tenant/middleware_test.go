@@ -0,0 +1,64 @@
+package tenant_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/olesia8novoselova/Subscriptions/internal/auth"
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/olesia8novoselova/Subscriptions/internal/tenant"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMiddleware - тестирует определение арендатора по principal и заголовку X-Tenant-ID
+func TestMiddleware(t *testing.T) {
+	orgA := uuid.New()
+	orgB := uuid.New()
+
+	var got uuid.UUID
+	h := tenant.Middleware(slog.New(slog.NewTextHandler(io.Discard, nil)), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = tenant.FromContext(r.Context())
+	}))
+
+	tests := []struct {
+		name       string
+		principal  *auth.Principal
+		header     string
+		wantStatus int
+		wantTenant uuid.UUID
+	}{
+		{"no auth, no header", nil, "", http.StatusOK, tenant.DefaultID},
+		{"no auth, header", nil, orgA.String(), http.StatusOK, orgA},
+		{"bound principal", &auth.Principal{TenantID: orgA, Role: models.RoleUser}, "", http.StatusOK, orgA},
+		{"bound principal, same header", &auth.Principal{TenantID: orgA, Role: models.RoleUser}, orgA.String(), http.StatusOK, orgA},
+		{"bound principal, other header", &auth.Principal{TenantID: orgA, Role: models.RoleAdmin}, orgB.String(), http.StatusForbidden, uuid.Nil},
+		{"unbound admin, header", &auth.Principal{Role: models.RoleAdmin}, orgB.String(), http.StatusOK, orgB},
+		{"unbound user, header", &auth.Principal{Role: models.RoleUser}, orgB.String(), http.StatusForbidden, uuid.Nil},
+		{"unbound user, no header", &auth.Principal{Role: models.RoleUser}, "", http.StatusOK, tenant.DefaultID},
+		{"invalid header", nil, "acme", http.StatusBadRequest, uuid.Nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = uuid.New()
+			req := httptest.NewRequest(http.MethodGet, "/api/subscriptions", nil)
+			if tt.header != "" {
+				req.Header.Set(tenant.Header, tt.header)
+			}
+			if tt.principal != nil {
+				req = req.WithContext(auth.WithPrincipal(req.Context(), tt.principal))
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, tt.wantTenant, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+// Package tenant — организация (арендатор), к которой относятся данные запроса.
+package tenant
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// DefaultID — арендатор по умолчанию для однотенантных установок и данных,
+// созданных до появления арендаторов
+var DefaultID = uuid.Nil
+
+type tenantKey struct{}
+
+// WithTenant — кладёт арендатора в контекст запроса
+func WithTenant(ctx context.Context, id uuid.UUID) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// FromContext — арендатор запроса или DefaultID, если он не задан
+func FromContext(ctx context.Context) uuid.UUID {
+	if id, ok := ctx.Value(tenantKey{}).(uuid.UUID); ok {
+		return id
+	}
+	return DefaultID
+}
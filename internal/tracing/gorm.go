@@ -0,0 +1,90 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const gormSpanKey = "otel:span"
+
+// GORM — плагин GORM: спан на каждый запрос с текстом SQL без значений параметров.
+// system — значение db.system: "postgresql" или "sqlite".
+func GORM(system string) gorm.Plugin {
+	return gormPlugin{system: semconv.DBSystemKey.String(system)}
+}
+
+type gormPlugin struct {
+	system attribute.KeyValue
+}
+
+// gormSpan — спан запроса и контекст, который был у запроса до него
+type gormSpan struct {
+	span   trace.Span
+	parent context.Context
+}
+
+func (gormPlugin) Name() string { return "otel-tracing" }
+
+func (p gormPlugin) Initialize(db *gorm.DB) error {
+	start := func(op string) func(*gorm.DB) {
+		return func(tx *gorm.DB) { p.start(tx, op) }
+	}
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("otel:before_create", start("create")),
+		cb.Create().After("gorm:create").Register("otel:after_create", p.end),
+		cb.Query().Before("gorm:query").Register("otel:before_query", start("select")),
+		cb.Query().After("gorm:query").Register("otel:after_query", p.end),
+		cb.Update().Before("gorm:update").Register("otel:before_update", start("update")),
+		cb.Update().After("gorm:update").Register("otel:after_update", p.end),
+		cb.Delete().Before("gorm:delete").Register("otel:before_delete", start("delete")),
+		cb.Delete().After("gorm:delete").Register("otel:after_delete", p.end),
+		cb.Row().Before("gorm:row").Register("otel:before_row", start("row")),
+		cb.Row().After("gorm:row").Register("otel:after_row", p.end),
+		cb.Raw().Before("gorm:raw").Register("otel:before_raw", start("raw")),
+		cb.Raw().After("gorm:raw").Register("otel:after_raw", p.end),
+	)
+}
+
+func (p gormPlugin) start(tx *gorm.DB, op string) {
+	parent := tx.Statement.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, span := otel.Tracer(instrumentationName).Start(parent, "gorm."+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(p.system, semconv.DBOperationName(op)),
+	)
+	tx.Statement.Context = ctx
+	tx.InstanceSet(gormSpanKey, gormSpan{span: span, parent: parent})
+}
+
+func (p gormPlugin) end(tx *gorm.DB) {
+	v, ok := tx.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	s := v.(gormSpan)
+	// statement может переиспользоваться цепочкой: следующий запрос не должен стать дочерним
+	tx.Statement.Context = s.parent
+
+	s.span.SetAttributes(
+		semconv.DBQueryText(tx.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", tx.Statement.RowsAffected),
+	)
+	if tx.Statement.Table != "" {
+		s.span.SetAttributes(semconv.DBCollectionName(tx.Statement.Table))
+	}
+	if err := tx.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
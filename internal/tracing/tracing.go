@@ -0,0 +1,80 @@
+// Package tracing — трассировка OpenTelemetry: провайдер с экспортом по OTLP, HTTP-middleware
+// с контекстом W3C Trace Context и спаны запросов GORM.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Экспортёры спанов (OTEL_TRACES_EXPORTER)
+const (
+	ExporterNone = "none"
+	ExporterOTLP = "otlp"
+)
+
+const serviceName = "subscriptions"
+
+const instrumentationName = "github.com/olesia8novoselova/Subscriptions/internal/tracing"
+
+// Setup — устанавливает глобальные провайдер трассировки и propagator (traceparent, baggage).
+// С ExporterOTLP спаны отправляются по OTLP/HTTP; адрес, заголовки и TLS экспортёр берёт
+// из стандартных переменных OTEL_EXPORTER_OTLP_*. С ExporterNone спаны не экспортируются,
+// но trace_id всё равно передаётся дальше и попадает в логи.
+// Возвращает функцию, которая отправляет оставшиеся спаны и останавливает провайдер.
+func Setup(ctx context.Context, exporter string) (func(context.Context) error, error) {
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+		resource.WithFromEnv(), // OTEL_SERVICE_NAME, OTEL_RESOURCE_ATTRIBUTES
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("tracing resource: %w", err)
+	}
+
+	opts := []sdktrace.TracerProviderOption{sdktrace.WithResource(res)}
+	switch exporter {
+	case ExporterOTLP:
+		exp, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("otlp exporter: %w", err)
+		}
+		opts = append(opts, sdktrace.WithBatcher(exp))
+	case ExporterNone:
+	default:
+		return nil, fmt.Errorf("unknown traces exporter %q", exporter)
+	}
+
+	tp := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return tp.Shutdown, nil
+}
+
+// Middleware — серверный спан на каждый запрос; родительский контекст берётся из traceparent.
+// Спан называется шаблоном маршрута mux (например, "GET /api/users/{id}").
+func Middleware(mux *http.ServeMux, next http.Handler) http.Handler {
+	route := func(r *http.Request) string {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			return pattern
+		}
+		return r.Method
+	}
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		trace.SpanFromContext(r.Context()).SetAttributes(semconv.HTTPRoute(route(r)))
+		next.ServeHTTP(w, r)
+	})
+	return otelhttp.NewHandler(inner, "http.server",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string { return route(r) }),
+	)
+}
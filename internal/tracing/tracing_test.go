@@ -0,0 +1,90 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/olesia8novoselova/Subscriptions/internal/models"
+	"github.com/olesia8novoselova/Subscriptions/internal/repository/sqlite"
+	"github.com/olesia8novoselova/Subscriptions/internal/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newRecorder(t *testing.T) *tracetest.SpanRecorder {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+	return rec
+}
+
+func attr(span sdktrace.ReadOnlySpan, key attribute.Key) string {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
+
+// TestMiddleware - тестирует продолжение трассы из traceparent и имя спана по шаблону маршрута
+func TestMiddleware(t *testing.T) {
+	rec := newRecorder(t)
+	mux := http.NewServeMux()
+	var inner trace.SpanContext
+	mux.HandleFunc("GET /api/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		inner = trace.SpanContextFromContext(r.Context())
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	tracing.Middleware(mux, mux).ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := rec.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "GET /api/users/{id}", span.Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+	assert.Equal(t, "GET /api/users/{id}", attr(span, "http.route"))
+	assert.Equal(t, span.SpanContext().SpanID(), inner.SpanID(), "handler must see the server span")
+}
+
+// TestGORM - тестирует спаны запросов GORM как дочерние к спану из контекста
+func TestGORM(t *testing.T) {
+	rec := newRecorder(t)
+	db, err := sqlite.Open(":memory:")
+	require.NoError(t, err)
+	require.NoError(t, db.Use(tracing.GORM("sqlite")))
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	var n int64
+	require.NoError(t, db.WithContext(ctx).Model(&models.Subscription{}).Where("price > ?", 100).Count(&n).Error)
+	require.NoError(t, db.WithContext(ctx).Exec("DELETE FROM subscriptions WHERE price < ?", 0).Error)
+	parent.End()
+
+	var names []string
+	for _, span := range rec.Ended() {
+		if span.Name() == "parent" {
+			continue
+		}
+		names = append(names, span.Name())
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID(), span.Name())
+		assert.Equal(t, "sqlite", attr(span, "db.system"))
+		query := attr(span, "db.query.text")
+		assert.True(t, strings.Contains(query, "subscriptions"), query)
+		assert.NotContains(t, query, "100", "query parameters must not be recorded")
+	}
+	assert.Equal(t, []string{"gorm.select", "gorm.raw"}, names)
+}
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS trg_set_updated_at ON budgets;
+DROP TABLE IF EXISTS budgets;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS category;
@@ -0,0 +1,21 @@
+-- Категория подписки (музыка, видео, облако и т.п.)
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS category TEXT NOT NULL DEFAULT '';
+
+-- Месячные бюджеты пользователей
+CREATE TABLE IF NOT EXISTS budgets (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    monthly_limit INTEGER NOT NULL CHECK (monthly_limit > 0),
+    service_name TEXT NULL,
+    category TEXT NULL,
+    mode TEXT NOT NULL DEFAULT 'warn' CHECK (mode IN ('warn', 'reject')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_budgets_user_id ON budgets (user_id);
+
+DROP TRIGGER IF EXISTS trg_set_updated_at ON budgets;
+CREATE TRIGGER trg_set_updated_at
+BEFORE UPDATE ON budgets
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
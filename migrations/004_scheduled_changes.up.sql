@@ -0,0 +1,12 @@
+-- Запланированные изменения подписок: смена цены или отмена с указанного месяца
+CREATE TABLE IF NOT EXISTS scheduled_changes (
+    id UUID PRIMARY KEY,
+    subscription_id UUID NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE,
+    effective_date DATE NOT NULL,
+    new_price INTEGER NULL CHECK (new_price > 0),
+    cancel BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK ((new_price IS NOT NULL) <> cancel)
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_changes_subscription_id ON scheduled_changes (subscription_id);
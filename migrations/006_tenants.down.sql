@@ -0,0 +1,22 @@
+DROP POLICY IF EXISTS tenant_isolation ON scheduled_changes;
+ALTER TABLE scheduled_changes DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON budgets;
+ALTER TABLE budgets DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON subscriptions;
+ALTER TABLE subscriptions DISABLE ROW LEVEL SECURITY;
+
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS uniq_user_service_period;
+ALTER TABLE subscriptions
+  ADD CONSTRAINT uniq_user_service_period
+  EXCLUDE USING gist (
+    user_id WITH =,
+    (lower(service_name)) WITH =,
+    period WITH &&
+  );
+
+DROP INDEX IF EXISTS idx_budgets_tenant_user;
+DROP INDEX IF EXISTS idx_subscriptions_tenant_user;
+
+ALTER TABLE api_keys DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE budgets DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS tenant_id;
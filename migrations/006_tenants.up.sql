@@ -0,0 +1,39 @@
+-- Арендаторы (организации). Существующие данные относятся к арендатору по умолчанию (нулевой UUID)
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+ALTER TABLE budgets ADD COLUMN IF NOT EXISTS tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS tenant_id UUID NULL;
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_tenant_user ON subscriptions (tenant_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_budgets_tenant_user ON budgets (tenant_id, user_id);
+
+-- Запрет пересечений действует в пределах арендатора
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS uniq_user_service_period;
+ALTER TABLE subscriptions
+  ADD CONSTRAINT uniq_user_service_period
+  EXCLUDE USING gist (
+    tenant_id WITH =,
+    user_id WITH =,
+    (lower(service_name)) WITH =,
+    period WITH &&
+  );
+
+-- Row-level security: приложение устанавливает app.tenant_id в каждой транзакции.
+-- Политики действуют для ролей, не являющихся владельцем таблиц и суперпользователем.
+ALTER TABLE subscriptions ENABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON subscriptions;
+CREATE POLICY tenant_isolation ON subscriptions
+  USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid)
+  WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
+
+ALTER TABLE budgets ENABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON budgets;
+CREATE POLICY tenant_isolation ON budgets
+  USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid)
+  WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
+
+-- Изменения видны, только если видна их подписка (подзапрос сам ограничен политикой subscriptions)
+ALTER TABLE scheduled_changes ENABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON scheduled_changes;
+CREATE POLICY tenant_isolation ON scheduled_changes
+  USING (EXISTS (SELECT 1 FROM subscriptions s WHERE s.id = subscription_id))
+  WITH CHECK (EXISTS (SELECT 1 FROM subscriptions s WHERE s.id = subscription_id));
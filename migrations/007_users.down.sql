@@ -0,0 +1,6 @@
+ALTER TABLE budgets DROP CONSTRAINT IF EXISTS fk_budgets_user;
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS fk_subscriptions_user;
+
+DROP POLICY IF EXISTS tenant_isolation ON users;
+DROP TRIGGER IF EXISTS trg_set_updated_at ON users;
+DROP TABLE IF EXISTS users;
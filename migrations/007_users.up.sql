@@ -0,0 +1,45 @@
+-- Пользователи: владельцы подписок и бюджетов. ID уникален в пределах арендатора:
+-- один и тот же UUID в разных организациях — разные пользователи
+CREATE TABLE IF NOT EXISTS users (
+    tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000',
+    id UUID NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    email TEXT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, id)
+);
+
+DROP TRIGGER IF EXISTS trg_set_updated_at ON users;
+CREATE TRIGGER trg_set_updated_at
+BEFORE UPDATE ON users
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- Пользователи, уже упомянутые в подписках и бюджетах, создаются без имени
+-- (в каждой организации, где они встречаются)
+INSERT INTO users (tenant_id, id)
+SELECT tenant_id, user_id
+FROM (
+    SELECT tenant_id, user_id FROM subscriptions
+    UNION
+    SELECT tenant_id, user_id FROM budgets
+) existing
+ON CONFLICT (tenant_id, id) DO NOTHING;
+
+-- Подписки нельзя оставить без владельца: политика удаления применяется приложением
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS fk_subscriptions_user;
+ALTER TABLE subscriptions
+  ADD CONSTRAINT fk_subscriptions_user
+  FOREIGN KEY (tenant_id, user_id) REFERENCES users (tenant_id, id) ON DELETE RESTRICT;
+
+-- Бюджеты удаляются вместе с пользователем
+ALTER TABLE budgets DROP CONSTRAINT IF EXISTS fk_budgets_user;
+ALTER TABLE budgets
+  ADD CONSTRAINT fk_budgets_user
+  FOREIGN KEY (tenant_id, user_id) REFERENCES users (tenant_id, id) ON DELETE CASCADE;
+
+ALTER TABLE users ENABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON users;
+CREATE POLICY tenant_isolation ON users
+  USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid)
+  WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
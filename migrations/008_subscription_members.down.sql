@@ -0,0 +1,2 @@
+DROP POLICY IF EXISTS tenant_isolation ON subscription_members;
+DROP TABLE IF EXISTS subscription_members;
@@ -0,0 +1,23 @@
+-- Участники совместных (семейных) подписок и их доли в оплате.
+-- tenant_id нужен для ссылки на пользователя той же организации
+CREATE TABLE IF NOT EXISTS subscription_members (
+    id UUID PRIMARY KEY,
+    subscription_id UUID NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    share_type TEXT NOT NULL CHECK (share_type IN ('percent', 'fixed')),
+    share_value INTEGER NOT NULL CHECK (share_value > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (subscription_id, user_id),
+    CHECK (share_type <> 'percent' OR share_value <= 100),
+    FOREIGN KEY (tenant_id, user_id) REFERENCES users (tenant_id, id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscription_members_user_id ON subscription_members (tenant_id, user_id);
+
+-- Участники видны, только если видна их подписка (см. scheduled_changes)
+ALTER TABLE subscription_members ENABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON subscription_members;
+CREATE POLICY tenant_isolation ON subscription_members
+  USING (EXISTS (SELECT 1 FROM subscriptions s WHERE s.id = subscription_id))
+  WITH CHECK (EXISTS (SELECT 1 FROM subscriptions s WHERE s.id = subscription_id));
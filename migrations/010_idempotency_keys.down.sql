@@ -0,0 +1,2 @@
+DROP POLICY IF EXISTS tenant_isolation ON idempotency_keys;
+DROP TABLE IF EXISTS idempotency_keys;
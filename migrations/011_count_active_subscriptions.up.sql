@@ -0,0 +1,8 @@
+-- Число подписок, активных в месяце month_start, по всем арендаторам (для метрик).
+-- SECURITY DEFINER выполняет запрос с правами владельца таблицы в обход row-level security,
+-- поэтому функция возвращает только агрегат.
+CREATE OR REPLACE FUNCTION count_active_subscriptions(month_start DATE) RETURNS BIGINT
+LANGUAGE sql STABLE SECURITY DEFINER SET search_path = public AS $$
+  SELECT count(*) FROM subscriptions
+  WHERE start_date <= month_start AND (end_date IS NULL OR end_date >= month_start)
+$$;
@@ -0,0 +1,9 @@
+// Package migrations — SQL-миграции базы данных, встроенные в бинарные файлы.
+package migrations
+
+import "embed"
+
+// FS — файлы NNN_name.up.sql и NNN_name.down.sql
+//
+//go:embed *.sql
+var FS embed.FS
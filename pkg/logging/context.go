@@ -0,0 +1,78 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader — заголовок с ID запроса; входящее значение сохраняется, если оно допустимо
+const RequestIDHeader = "X-Request-ID"
+
+const maxRequestIDLen = 128
+
+type ctxKey int
+
+const (
+	loggerKey ctxKey = iota
+	requestIDKey
+)
+
+// WithLogger — контекст с логгером запроса
+func WithLogger(ctx context.Context, log *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, log)
+}
+
+// FromContext — логгер запроса; вне запроса — slog.Default().
+// request_id и trace_id в записи добавляет ContextHandler, поэтому логировать стоит методами *Context.
+func FromContext(ctx context.Context) *slog.Logger {
+	if log, ok := ctx.Value(loggerKey).(*slog.Logger); ok && log != nil {
+		return log
+	}
+	return slog.Default()
+}
+
+// WithRequestID — контекст с ID запроса
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID — ID запроса из контекста; пусто, если его нет
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// ErrorBody — тело ответа об ошибке {"error": msg, "request_id": ...}.
+// ID берётся из заголовка ответа, который HTTPMiddleware выставляет до вызова обработчиков.
+func ErrorBody(w http.ResponseWriter, msg string) map[string]string {
+	body := map[string]string{"error": msg}
+	if id := w.Header().Get(RequestIDHeader); id != "" {
+		body["request_id"] = id
+	}
+	return body
+}
+
+// NewRequestID — входящий ID, если он допустим, иначе новый UUID
+func NewRequestID(incoming string) string {
+	if validRequestID(incoming) {
+		return incoming
+	}
+	return uuid.NewString()
+}
+
+// validRequestID — непустой, не длиннее maxRequestIDLen и только из видимых ASCII-символов,
+// чтобы клиент не мог подделать записи лога или заголовки
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
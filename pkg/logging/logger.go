@@ -1,23 +1,66 @@
 package logging
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
 	"os"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
-func New() *slog.Logger {
-	h := slog.NewJSONHandler(os.Stdout, nil)
-	return slog.New(h)
+// New — JSON-логгер в stdout; level можно менять во время работы, если это *slog.LevelVar
+func New(level slog.Leveler) *slog.Logger {
+	h := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})
+	return slog.New(ContextHandler(h))
+}
+
+// ContextHandler — добавляет к записям request_id запроса и trace_id/span_id активного спана
+// из контекста (для вызовов *Context: InfoContext, ErrorContext и т. д.)
+func ContextHandler(h slog.Handler) slog.Handler {
+	return contextHandler{h}
 }
 
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// HTTPMiddleware — присваивает запросу ID (из заголовка X-Request-ID или новый), возвращает его
+// в ответе, кладёт в контекст ID и логгер запроса и логирует завершение запроса
 func HTTPMiddleware(log *slog.Logger, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
+		id := NewRequestID(r.Header.Get(RequestIDHeader))
+		w.Header().Set(RequestIDHeader, id)
+		ctx := WithRequestID(r.Context(), id)
+		ctx = WithLogger(ctx, log.With("method", r.Method, "path", r.URL.Path))
+		r = r.WithContext(ctx)
+
 		ww := &wrapWriter{ResponseWriter: w, status: http.StatusOK}
 		next.ServeHTTP(ww, r)
-		log.Info("http_request",
+		log.InfoContext(r.Context(), "http_request",
 			"method", r.Method,
 			"path", r.URL.Path,
 			"status", ww.status,
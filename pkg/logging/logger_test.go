@@ -0,0 +1,75 @@
+package logging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/olesia8novoselova/Subscriptions/pkg/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHTTPMiddleware_RequestID - тестирует приём, генерацию и возврат X-Request-ID
+func TestHTTPMiddleware_RequestID(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(logging.ContextHandler(slog.NewJSONHandler(&buf, nil)))
+
+	h := logging.HTTPMiddleware(log, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logging.FromContext(r.Context()).InfoContext(r.Context(), "inside")
+		w.WriteHeader(http.StatusTeapot)
+		_ = json.NewEncoder(w).Encode(logging.ErrorBody(w, "boom"))
+	}))
+
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{"accepted", "req-123", true},
+		{"generated", "", false},
+		{"too long", strings.Repeat("a", 200), false},
+		{"control characters", "bad\nid", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			req := httptest.NewRequest(http.MethodGet, "/api/subscriptions", nil)
+			if tt.incoming != "" {
+				req.Header.Set(logging.RequestIDHeader, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(logging.RequestIDHeader)
+			require.NotEmpty(t, id)
+			if tt.keep {
+				assert.Equal(t, tt.incoming, id)
+			} else {
+				assert.NotEqual(t, tt.incoming, id)
+			}
+
+			var body map[string]string
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			assert.Equal(t, map[string]string{"error": "boom", "request_id": id}, body)
+
+			// запись обработчика и запись о запросе связаны одним request_id
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			require.Len(t, lines, 2)
+			for _, line := range lines {
+				var rec map[string]any
+				require.NoError(t, json.Unmarshal([]byte(line), &rec))
+				assert.Equal(t, id, rec["request_id"], line)
+			}
+		})
+	}
+}
+
+// TestFromContext_Default - тестирует логгер по умолчанию вне запроса
+func TestFromContext_Default(t *testing.T) {
+	assert.Same(t, slog.Default(), logging.FromContext(httptest.NewRequest(http.MethodGet, "/", nil).Context()))
+}